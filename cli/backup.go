@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// backupStore writes every product, soft-deleted ones included, to path as a
+// JSON array the file store can load. Under --store-encryption-key the
+// backup is encrypted with the store's key, so it is no easier to read than
+// the store. The file is replaced atomically.
+func backupStore(ctx context.Context, path string) (int, error) {
+	key, err := parseEncryptionKey(viper.GetString("store-encryption-key"))
+	if err != nil {
+		return 0, err
+	}
+	products, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		return 0, err
+	}
+	b, err := json.MarshalIndent(products, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if len(key) > 0 {
+		if b, err = store.Encrypt(key, b); err != nil {
+			return 0, err
+		}
+	}
+	if err := replaceFile(path, b); err != nil {
+		return 0, err
+	}
+	return len(products), nil
+}
+
+// withSnapshot runs a bulk destructive operation. When --backup-dir is set
+// the whole store is first written there to a timestamped file, and an error
+// from run names that file so the previous state can be restored.
+func withSnapshot(ctx context.Context, op string, run func() error) error {
+	dir := viper.GetString("backup-dir")
+	if dir == "" {
+		return run()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("safety snapshot: %w", err)
+	}
+	name := fmt.Sprintf("inventory-%s-%s.json", op, time.Now().UTC().Format("20060102T150405.000Z"))
+	path := filepath.Join(dir, name)
+	n, err := backupStore(ctx, path)
+	if err != nil {
+		return fmt.Errorf("safety snapshot: %w", err)
+	}
+	slog.Info("safety snapshot written", "op", op, "path", path, "count", n)
+	if err := run(); err != nil {
+		return fmt.Errorf("%w (the store before %s was saved to %s; restore it with: restore --from %s --replace)", err, op, path, path)
+	}
+	return nil
+}
+
+// restoreBackup loads the backup at path into the store. With replace the
+// store is emptied first, but only once the whole backup has been checked,
+// so an unusable backup leaves the store untouched.
+func restoreBackup(ctx context.Context, path string, replace bool) (int, error) {
+	products, err := loadCatalog(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkBackup(products); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	load := func() error {
+		return productStore.BulkImport(ctx, products)
+	}
+	if replace {
+		load = func() error {
+			return withSnapshot(ctx, "restore", func() error {
+				if _, err := productStore.Clear(ctx); err != nil {
+					return err
+				}
+				return productStore.BulkImport(ctx, products)
+			})
+		}
+	}
+	if err := load(); err != nil {
+		return 0, err
+	}
+	return len(products), nil
+}
+
+// checkBackup reports every product in a backup that BulkImport would
+// reject on its own, regardless of what the store already holds.
+func checkBackup(products []domain.Product) error {
+	var errs domain.MultiError
+	seen := make(map[string]bool, len(products))
+	for i, p := range products {
+		switch {
+		case p.ID == "":
+			errs.Append(fmt.Errorf("record %d: %w", i, domain.NewInvalidProductError("id", "cannot be empty", p.ID)))
+		case seen[p.ID]:
+			errs.Append(fmt.Errorf("id=%s: %w", p.ID, domain.NewDuplicateProductError(p.ID)))
+		default:
+			if err := domain.ValidateProduct(p); err != nil {
+				errs.Append(fmt.Errorf("id=%s: %w", p.ID, err))
+			}
+		}
+		seen[p.ID] = true
+	}
+	return errs.ErrOrNil()
+}
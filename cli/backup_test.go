@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	defer resetCLI()
+	ctx := context.Background()
+	src := store.NewInMemoryStoreWithOptions(store.Options{SoftDelete: true})
+	_ = src.Create(ctx, domain.Product{ID: "b1", Name: "One", Price: 1, Quantity: 1})
+	_ = src.Create(ctx, domain.Product{ID: "b2", Name: "Two", Price: 2, Quantity: 2})
+	_ = src.Delete(ctx, "b2")
+	path := filepath.Join(t.TempDir(), "backup.json")
+
+	productStore = src
+	rootCmd.SetArgs([]string{"backup", "--to", path})
+	out, err := captureOutput(Execute)
+	if err != nil || !strings.Contains(out, "backed up 2 product(s)") {
+		t.Fatalf("backup failed: %q, %v", out, err)
+	}
+
+	// restoring into another backend keeps the soft-deleted product hidden
+	dst := store.NewInMemoryStore()
+	_ = dst.Create(ctx, domain.Product{ID: "old", Name: "Old", Price: 1, Quantity: 1})
+	resetCLI()
+	productStore = dst
+	rootCmd.SetArgs([]string{"restore", "--from", path, "--replace"})
+	if out, err := captureOutput(Execute); err != nil || !strings.Contains(out, "restored 2 product(s)") {
+		t.Fatalf("restore failed: %q, %v", out, err)
+	}
+	live, _ := dst.List(ctx, domain.ListFilter{})
+	if len(live) != 1 || live[0].ID != "b1" {
+		t.Fatalf("expected only b1 live after replacing restore, got %+v", live)
+	}
+	all, _ := dst.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if len(all) != 2 {
+		t.Fatalf("expected the soft-deleted product to be restored too, got %+v", all)
+	}
+
+	// without --replace the existing products collide and nothing changes
+	resetCLI()
+	productStore = dst
+	rootCmd.SetArgs([]string{"restore", "--from", path})
+	if _, err := captureOutput(Execute); !domain.IsDuplicateProductError(err) {
+		t.Fatalf("expected duplicates without --replace, got %v", err)
+	}
+}
+
+func TestRestoreRejectsBadBackupBeforeClearing(t *testing.T) {
+	defer resetCLI()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte(`[{"id":"x1","name":"","price":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewInMemoryStore()
+	_ = st.Create(ctx, domain.Product{ID: "keep", Name: "Keep", Price: 1, Quantity: 1})
+
+	productStore = st
+	rootCmd.SetArgs([]string{"restore", "--from", path, "--replace"})
+	if _, err := captureOutput(Execute); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected invalid backup to be rejected, got %v", err)
+	}
+	if _, err := st.Get(ctx, "keep"); err != nil {
+		t.Fatalf("store must be untouched after a rejected backup: %v", err)
+	}
+}
+
+// failingDeleteStore rejects every BatchDelete
+type failingDeleteStore struct {
+	domain.ProductStore
+}
+
+func (failingDeleteStore) BatchDelete(ctx context.Context, ids []string) error {
+	return errors.New("disk full")
+}
+
+func TestBackupDirSnapshotsBeforeBulkDelete(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("backup-dir", "")
+	ctx := context.Background()
+	dir := t.TempDir()
+	st := store.NewInMemoryStore()
+	_ = st.Create(ctx, domain.Product{ID: "d1", Name: "A", Price: 1, Quantity: 1, Category: "Old"})
+	_ = st.Create(ctx, domain.Product{ID: "d2", Name: "B", Price: 1, Quantity: 1, Category: "Keep"})
+
+	productStore = st
+	rootCmd.SetArgs([]string{"--backup-dir", dir, "delete", "--category", "Old", "--force"})
+	if _, err := captureOutput(Execute); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	snaps, _ := filepath.Glob(filepath.Join(dir, "inventory-delete-*.json"))
+	if len(snaps) != 1 {
+		t.Fatalf("expected one snapshot, got %v", snaps)
+	}
+	saved, err := loadCatalog(snaps[0])
+	if err != nil || len(saved) != 2 {
+		t.Fatalf("expected the snapshot to hold the store before the delete, got %+v, %v", saved, err)
+	}
+
+	// a failed operation points at its snapshot
+	resetCLI()
+	productStore = failingDeleteStore{st}
+	rootCmd.SetArgs([]string{"--backup-dir", dir, "delete", "--category", "Keep", "--force"})
+	_, err = captureOutput(Execute)
+	if err == nil || !strings.Contains(err.Error(), "restore --from "+dir) {
+		t.Fatalf("expected the error to name the snapshot, got %v", err)
+	}
+}
+
+func TestBackupDirSnapshotIsEncrypted(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("backup-dir", "")
+	defer rootCmd.PersistentFlags().Set("store-encryption-key", "")
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := strings.Repeat("ab", 32)
+	st := store.NewInMemoryStore()
+	_ = st.Create(ctx, domain.Product{ID: "e1", Name: "Secret Widget", Price: 1, Quantity: 1, Category: "Old"})
+	_ = st.Create(ctx, domain.Product{ID: "e2", Name: "Other", Price: 1, Quantity: 1, Category: "Keep"})
+
+	productStore = st
+	rootCmd.SetArgs([]string{"--store-encryption-key", key, "--backup-dir", dir, "delete", "--category", "Old", "--force"})
+	if _, err := captureOutput(Execute); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	snaps, _ := filepath.Glob(filepath.Join(dir, "inventory-delete-*.json"))
+	if len(snaps) != 1 {
+		t.Fatalf("expected one snapshot, got %v", snaps)
+	}
+	b, _ := os.ReadFile(snaps[0])
+	if strings.Contains(string(b), "Secret Widget") {
+		t.Fatalf("expected the snapshot to be encrypted, got %s", b)
+	}
+
+	// restore opens it with the same key, and not without one
+	resetCLI()
+	productStore = st
+	rootCmd.SetArgs([]string{"--store-encryption-key", key, "restore", "--from", snaps[0], "--replace"})
+	if out, err := captureOutput(Execute); err != nil || !strings.Contains(out, "restored 2 product(s)") {
+		t.Fatalf("restore failed: %q, %v", out, err)
+	}
+	rootCmd.PersistentFlags().Set("store-encryption-key", "")
+	if _, err := loadCatalog(snaps[0]); !errors.Is(err, store.ErrEncrypted) {
+		t.Fatalf("expected the snapshot not to open without the key, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// ANSI escapes used by text output
+const (
+	ansiRed   = "\x1b[31m"
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// colorMode is the --color flag: always, auto or never
+type colorMode string
+
+func (c *colorMode) String() string { return string(*c) }
+
+func (c *colorMode) Set(s string) error {
+	switch s {
+	case "always", "auto", "never":
+		*c = colorMode(s)
+		return nil
+	}
+	return fmt.Errorf("invalid color mode %q: want always, auto or never", s)
+}
+
+func (c *colorMode) Type() string { return "when" }
+
+// useColor reports whether text written to w should be colored. In auto
+// mode that needs w to be a terminal and NO_COLOR (https://no-color.org)
+// to be unset.
+func useColor(w io.Writer) bool {
+	switch viper.GetString("color") {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the ANSI code when on
+func paint(on bool, code, s string) string {
+	if !on {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// isLowStock reports whether p is out of stock or below its reorder level
+func isLowStock(p domain.Product) bool {
+	return p.Quantity == 0 || p.Quantity < p.ReorderLevel
+}
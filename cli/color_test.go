@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintProducts_Color(t *testing.T) {
+	defer rootCmd.PersistentFlags().Set("color", "auto")
+	out := []domain.Product{
+		{ID: "c1", Name: "Empty", Price: 1, Quantity: 0},
+		{ID: "c2", Name: "Full", Price: 1, Quantity: 9},
+	}
+	render := func(mode, format string) string {
+		t.Helper()
+		if err := rootCmd.PersistentFlags().Set("color", mode); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		printProducts(&buf, out, format)
+		return buf.String()
+	}
+
+	got := render("always", "")
+	if !strings.Contains(got, "c1 | Empty | $0.01 | "+ansiRed+"0"+ansiReset+" |") {
+		t.Fatalf("expected a red low-stock quantity:\n%q", got)
+	}
+	if strings.Contains(got, ansiRed+"9") {
+		t.Fatalf("expected a stocked quantity uncolored:\n%q", got)
+	}
+	if got := render("always", "json"); strings.Contains(got, "\x1b[") {
+		t.Fatalf("JSON output must not contain escape codes:\n%q", got)
+	}
+	// auto never colors a writer that is not a terminal
+	if got := render("auto", ""); strings.Contains(got, "\x1b[") {
+		t.Fatalf("expected no color in auto mode off a terminal:\n%q", got)
+	}
+	if err := rootCmd.PersistentFlags().Set("color", "sometimes"); err == nil {
+		t.Fatalf("expected an invalid color mode to be rejected")
+	}
+}
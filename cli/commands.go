@@ -3,17 +3,25 @@ package cli
 
 import (
 	"aexp_assesment/domain"
+	"aexp_assesment/server"
 	"aexp_assesment/store"
 	"aexp_assesment/util"
 	"bufio"
-	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -24,39 +32,23 @@ var (
 	rootCmd = &cobra.Command{
 		Use:   "inventory-cli",
 		Short: "A product inventory management system",
-		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// IMPORTANT: allow tests to inject store
-			if productStore != nil {
-				return nil
-			}
-
-			if cfg := viper.GetString("config"); cfg != "" {
-				viper.SetConfigFile(cfg)
-				if err := viper.ReadInConfig(); err != nil {
-					return err
-				}
-			}
-
-			lvlStr := strings.ToLower(viper.GetString("log-level"))
-			lvl := slog.LevelInfo
-			switch lvlStr {
-			case "debug":
-				lvl = slog.LevelDebug
-			case "warn", "warning":
-				lvl = slog.LevelWarn
-			case "error":
-				lvl = slog.LevelError
-			}
-			slog.SetDefault(slog.New(
-				slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl}),
-			))
+		Long: `A product inventory management system.
 
-			var err error
-			productStore, err = store.NewStore(
-				viper.GetString("store"),
-				viper.GetString("store-file"),
-			)
-			return err
+Exit codes:
+  0    success
+  1    any other error
+  2    product not found
+  3    invalid product
+  4    duplicate product
+  5    product limit reached (--max-products)
+  130  interrupted with Ctrl-C`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setup()
+		},
+		// flush after every command so shell sessions persist as they go;
+		// Execute closes the store once the process is done with it
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return domain.FlushStore(cmd.Context(), productStore)
 		},
 	}
 
@@ -69,10 +61,12 @@ func init() {
 		Use:   "shell",
 		Short: "Interactive shell mode",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			r := bufio.NewReader(os.Stdin)
+			// Ctrl-C cancels the running command, if any, and never ends the shell
+			defer onInterrupt(func() {})()
+			session, stdout := cmd.Context(), cmd.OutOrStdout()
+			r := newShellReader(stdout)
 			for {
-				fmt.Print("inventory> ")
-				line, err := r.ReadString('\n')
+				line, err := r.ReadLine(shellPrompt)
 				if err != nil {
 					return nil
 				}
@@ -83,91 +77,256 @@ func init() {
 				if line == "exit" || line == "quit" {
 					return nil
 				}
-				rootCmd.SetArgs(strings.Fields(line))
-				if err := rootCmd.Execute(); err != nil {
+				sl, err := parseShellLine(line)
+				if err == nil {
+					err = runShellLine(session, sl, stdout)
+				}
+				if err != nil {
 					fmt.Fprintln(os.Stderr, err)
 				}
-				rootCmd.SetArgs(nil)
 			}
 		},
 	}
 	rootCmd.AddCommand(shellCmd)
 
-	rootCmd.PersistentFlags().String("store", "memory", "store backend: memory|file")
+	// run
+	var keepGoing bool
+	runCmd := &cobra.Command{
+		Use:   "run <script>",
+		Short: "Run the commands in a script file against one store",
+		Long: `Run the commands in a script file, one per line, in order and against the
+same store. Lines use the shell syntax, including quotes, redirection and
+pipes; blank lines and lines starting with # are skipped. The first failing
+command stops the script unless --keep-going is set.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// read the flag now: running a line resets command flags
+			keepGoing := keepGoing
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return runScript(cmd.Context(), f, args[0], keepGoing, cmd.OutOrStdout())
+		},
+	}
+	runCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "run every command even after one fails")
+	rootCmd.AddCommand(runCmd)
+
+	rootCmd.PersistentFlags().String("store", "memory", "store backend: memory|file, or a DSN such as file://data/products.json")
 	rootCmd.PersistentFlags().String("store-file", "data/products.json", "file store path")
+	rootCmd.PersistentFlags().String("store-file-mode", "0644", "octal permissions for the store file, e.g. 0600")
 	rootCmd.PersistentFlags().String("config", "", "config file")
+	rootCmd.PersistentFlags().String("env-file", "", "load KEY=VALUE environment variables from this file, e.g. .env; set variables win")
 	rootCmd.PersistentFlags().String("log-level", "info", "log level")
+	rootCmd.PersistentFlags().String("log-file", "", "append logs to this file instead of stderr")
+	rootCmd.PersistentFlags().Bool("log-also-stderr", false, "with --log-file, also log to stderr")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "validate and print changes without writing to the store")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "deadline for store operations, e.g. 30s (0 = none)")
+	color := colorMode("auto")
+	rootCmd.PersistentFlags().Var(&color, "color", "color text output: always, auto (terminal and no NO_COLOR) or never")
+	rootCmd.PersistentFlags().Bool("soft-delete", false, "mark deleted products instead of removing them (see restore and purge)")
+	rootCmd.PersistentFlags().Bool("strict-load", true, "fail when the store file is corrupt; with =false move it aside and recover")
+	rootCmd.PersistentFlags().Bool("durable", false, "fsync the store file on every save so it survives a power loss")
+	rootCmd.PersistentFlags().Bool("track-price-history", false, "record every price change for the price-history command")
+	rootCmd.PersistentFlags().Bool("journal", false, "append each change to <store-file>.journal instead of rewriting the whole file")
+	rootCmd.PersistentFlags().String("store-encryption-key", "", "encrypt the store file with AES-GCM under this hex key (32, 48 or 64 digits); prefer $INVENTORY_STORE_ENCRYPTION_KEY")
+	rootCmd.PersistentFlags().Bool("watch-file", false, "reload the store file when another process changes it (useful with shell)")
+	rootCmd.PersistentFlags().Bool("strict", false, "treat an unknown id as an error (exit code 2) in every command, get included")
+	rootCmd.PersistentFlags().Int("max-products", 0, "refuse creates, imports and restores past this many live products (0 = no limit)")
+	rootCmd.PersistentFlags().String("backup-dir", "", "write a timestamped snapshot of the store here before bulk deletes, purges and replacing restores")
 
 	viper.BindPFlag("store", rootCmd.PersistentFlags().Lookup("store"))
 	viper.BindPFlag("store-file", rootCmd.PersistentFlags().Lookup("store-file"))
+	viper.BindPFlag("store-file-mode", rootCmd.PersistentFlags().Lookup("store-file-mode"))
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	viper.BindPFlag("env-file", rootCmd.PersistentFlags().Lookup("env-file"))
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("log-file", rootCmd.PersistentFlags().Lookup("log-file"))
+	viper.BindPFlag("log-also-stderr", rootCmd.PersistentFlags().Lookup("log-also-stderr"))
+	viper.BindPFlag("dry-run", rootCmd.PersistentFlags().Lookup("dry-run"))
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("color", rootCmd.PersistentFlags().Lookup("color"))
+	viper.BindPFlag("soft-delete", rootCmd.PersistentFlags().Lookup("soft-delete"))
+	viper.BindPFlag("strict-load", rootCmd.PersistentFlags().Lookup("strict-load"))
+	viper.BindPFlag("durable", rootCmd.PersistentFlags().Lookup("durable"))
+	viper.BindPFlag("track-price-history", rootCmd.PersistentFlags().Lookup("track-price-history"))
+	viper.BindPFlag("journal", rootCmd.PersistentFlags().Lookup("journal"))
+	viper.BindPFlag("watch-file", rootCmd.PersistentFlags().Lookup("watch-file"))
+	viper.BindPFlag("strict", rootCmd.PersistentFlags().Lookup("strict"))
+	viper.BindPFlag("max-products", rootCmd.PersistentFlags().Lookup("max-products"))
+	viper.BindPFlag("store-encryption-key", rootCmd.PersistentFlags().Lookup("store-encryption-key"))
+	viper.BindPFlag("backup-dir", rootCmd.PersistentFlags().Lookup("backup-dir"))
 	viper.SetEnvPrefix("INVENTORY")
+	// INVENTORY_STORE_FILE for store-file: shells cannot set names with dashes
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 
 	// create
-	var name, category string
-	var price float64
-	var quantity int
+	var name, category, currency, explicitID, idStrategy, idFormat string
+	var price domain.Money
+	var quantity, reorderLevel int
+	var tags, attrs []string
+	var cQuiet bool
 	createCmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a product",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
 			if name == "" {
 				return errors.New("name required")
 			}
-			id := util.GenerateUUID()
-			p := domain.Product{ID: id, Name: name, Price: price, Quantity: quantity, Category: category}
+			attributes, err := parseAttributes(attrs)
+			if err != nil {
+				return err
+			}
+			id := explicitID
+			if cmd.Flags().Changed("id") {
+				if strings.TrimSpace(id) == "" {
+					return domain.NewInvalidProductError("id", "cannot be empty", id)
+				}
+			} else {
+				strategy := idStrategy
+				if cmd.Flags().Changed("id-format") {
+					strategy = idFormat
+				}
+				if id, err = newID(ctx, strategy, !viper.GetBool("dry-run")); err != nil {
+					return err
+				}
+			}
+			p := domain.Product{ID: id, Name: name, Price: price, Quantity: quantity, Category: category, ReorderLevel: reorderLevel, Tags: tags, Currency: strings.ToUpper(currency), Attributes: attributes}
+			if err := validateProduct(p); err != nil {
+				return err
+			}
+			if viper.GetBool("dry-run") {
+				if err := checkIDFree(ctx, id); err != nil {
+					return err
+				}
+				printDryRun(cmd.OutOrStdout(), "create", p)
+				return nil
+			}
 			start := time.Now()
-			if err := productStore.Create(context.Background(), p); err != nil {
+			if err := productStore.Create(ctx, p); err != nil {
 				slog.Error("create failed", "product_id", id, "error", err)
 				return err
 			}
 			slog.Info("product created", "product_id", id, "duration_ms", time.Since(start).Milliseconds())
+			if cQuiet {
+				fmt.Fprintln(cmd.OutOrStdout(), id)
+				return nil
+			}
 			b, _ := json.MarshalIndent(p, "", "  ")
-			fmt.Println(string(b))
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
 			return nil
 		},
 	}
 	createCmd.Flags().StringVar(&name, "name", "", "name")
-	createCmd.Flags().Float64Var(&price, "price", 0, "price")
+	createCmd.Flags().Var(&price, "price", "price")
 	createCmd.Flags().IntVar(&quantity, "quantity", 0, "quantity")
 	createCmd.Flags().StringVar(&category, "category", "", "category")
+	createCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	createCmd.Flags().IntVar(&reorderLevel, "reorder-level", 0, "minimum desired stock")
+	createCmd.Flags().StringSliceVar(&tags, "tag", nil, "tag (repeatable)")
+	createCmd.Flags().StringArrayVar(&attrs, "attr", nil, "attribute as key=value (repeatable)")
+	createCmd.Flags().StringVar(&currency, "currency", domain.DefaultCurrency, "ISO 4217 currency code")
+	createCmd.Flags().StringVar(&explicitID, "id", "", "use this id instead of generating one; it must not be taken")
+	createCmd.Flags().StringVar(&idStrategy, "id-strategy", "v4", "how to generate the id: v4 (random), v7 (time-ordered) or sequence (P-0001, P-0002, ...)")
+	createCmd.Flags().StringVar(&idFormat, "id-format", "v4", "generated id format: v4 or v7")
+	createCmd.Flags().MarkDeprecated("id-format", "use --id-strategy")
+	createCmd.Flags().BoolVarP(&cQuiet, "quiet", "q", false, "print only the new product's id")
 	rootCmd.AddCommand(createCmd)
 
 	// get
+	var gOutputFile string
+	var gFields []string
 	getCmd := &cobra.Command{
 		Use:   "get <id>",
 		Short: "Get product by id",
-		Args:  cobra.ExactArgs(1),
+		Long: `Print a product as JSON. An unknown id is reported on stderr, but get
+still exits 0 unless --strict is set, when it fails with exit code 2 like
+update, delete, restore and price-history always do.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProductIDs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p, err := productStore.Get(context.Background(), args[0])
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if err := checkFields(gFields); err != nil {
+				return err
+			}
+			p, err := productStore.Get(ctx, args[0])
 			if err != nil {
-				if domain.IsProductNotFoundError(err) {
+				// lenient by default: the message, but exit status 0
+				if domain.IsProductNotFoundError(err) && !viper.GetBool("strict") {
 					fmt.Fprintln(os.Stderr, err)
 					return nil
 				}
 				return err
 			}
-			b, _ := json.MarshalIndent(p, "", "  ")
-			fmt.Println(string(b))
-			return nil
+			var b []byte
+			if len(gFields) > 0 {
+				b, _ = json.MarshalIndent(project(p, gFields), "", "  ")
+			} else {
+				b, _ = json.MarshalIndent(p, "", "  ")
+			}
+			return writeOutput(cmd.OutOrStdout(), gOutputFile, func(w io.Writer) {
+				fmt.Fprintln(w, string(b))
+			})
 		},
 	}
+	getCmd.Flags().StringVar(&gOutputFile, "output-file", "", "write the product JSON to this file instead of stdout")
+	getCmd.Flags().StringSliceVar(&gFields, "fields", nil, "only output these fields, e.g. id,price")
 	rootCmd.AddCommand(getCmd)
 
 	// update
-	var uName, uCategory string
-	var uPrice float64
-	var uQuantity int
+	var uName, uCategory, uCurrency string
+	var uPrice domain.Money
+	var uQuantity, uReorderLevel int
+	var uTags, uAttrs []string
+	var setCategory string
+	var setPrice domain.Money
+	var setQuantity int
 	updateCmd := &cobra.Command{
-		Use:   "update <id>",
-		Short: "Update a product",
-		Args:  cobra.ExactArgs(1),
+		Use:               "update [<id>]",
+		Short:             "Update a product, or every product matching --category/--tag with --set-* flags",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeProductIDs,
+		// reject bad amounts before the store is read
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkNonNegative(cmd, "price", int64(uPrice)); err != nil {
+				return err
+			}
+			if err := checkNonNegative(cmd, "set-price", int64(setPrice)); err != nil {
+				return err
+			}
+			if err := checkNonNegative(cmd, "quantity", int64(uQuantity)); err != nil {
+				return err
+			}
+			return checkNonNegative(cmd, "set-quantity", int64(setQuantity))
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if len(args) == 0 {
+				var patch domain.ProductPatch
+				if cmd.Flags().Changed("set-category") {
+					patch.Category = &setCategory
+				}
+				if cmd.Flags().Changed("set-price") {
+					patch.Price = &setPrice
+				}
+				if cmd.Flags().Changed("set-quantity") {
+					patch.Quantity = &setQuantity
+				}
+				return updateWhere(ctx, cmd.OutOrStdout(), updateFilter(cmd, uCategory, uTags), patch)
+			}
+
 			id := args[0]
 
-			p, err := productStore.Get(context.Background(), id)
+			p, err := productStore.Get(ctx, id)
 			if err != nil {
 				return err
 			}
@@ -184,13 +343,31 @@ func init() {
 			if cmd.Flags().Changed("category") {
 				p.Category = uCategory
 			}
+			if cmd.Flags().Changed("reorder-level") {
+				p.ReorderLevel = uReorderLevel
+			}
+			if cmd.Flags().Changed("tag") {
+				p.Tags = uTags
+			}
+			if cmd.Flags().Changed("attr") {
+				if p.Attributes, err = parseAttributes(uAttrs); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("currency") {
+				p.Currency = strings.ToUpper(uCurrency)
+			}
 
-			if err := domain.ValidateProduct(p); err != nil {
+			if err := validateProduct(p); err != nil {
 				return err
 			}
+			if viper.GetBool("dry-run") {
+				printDryRun(cmd.OutOrStdout(), "update", p)
+				return nil
+			}
 
 			start := time.Now()
-			if err := productStore.Update(context.Background(), id, p); err != nil {
+			if err := productStore.Update(ctx, id, p); err != nil {
 				slog.Error("update failed", "product_id", id, "error", err)
 				return err
 			}
@@ -202,161 +379,1605 @@ func init() {
 			)
 
 			b, _ := json.MarshalIndent(p, "", "  ")
-			fmt.Println(string(b))
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
 			return nil
 		},
 	}
 	updateCmd.Flags().StringVar(&uName, "name", "", "name")
-	updateCmd.Flags().Float64Var(&uPrice, "price", 0, "price")
+	updateCmd.Flags().Var(&uPrice, "price", "price")
 	updateCmd.Flags().IntVar(&uQuantity, "quantity", 0, "quantity")
 	updateCmd.Flags().StringVar(&uCategory, "category", "", "category")
+	updateCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	updateCmd.Flags().IntVar(&uReorderLevel, "reorder-level", 0, "minimum desired stock")
+	updateCmd.Flags().StringSliceVar(&uTags, "tag", nil, "tag (repeatable, replaces existing tags)")
+	updateCmd.Flags().StringArrayVar(&uAttrs, "attr", nil, "attribute as key=value (repeatable, replaces existing attributes)")
+	updateCmd.Flags().StringVar(&uCurrency, "currency", "", "ISO 4217 currency code")
+	updateCmd.Flags().StringVar(&setCategory, "set-category", "", "new category for every match (no id)")
+	updateCmd.RegisterFlagCompletionFunc("set-category", completeCategories)
+	updateCmd.Flags().Var(&setPrice, "set-price", "new price for every match (no id)")
+	updateCmd.Flags().IntVar(&setQuantity, "set-quantity", 0, "new quantity for every match (no id)")
 	rootCmd.AddCommand(updateCmd)
 
 	// list
-	var lCategory, lSort, lOrder, lOutput string
-	var lMin, lMax float64
+	var lSort, lOrder, lOutput, lOutputFile, lCurrency, lSince string
+	var lCategories, lTags, lAttrs, lFields []string
+	var lAllTags, lIgnoreCase, lIncludeDeleted, lQuiet, lInStock, lOutOfStock bool
+	var lMin, lMax domain.Money
+	var lMinQty, lMaxQty, lLimit, lOffset int
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List products",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var minPtr, maxPtr *float64
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if lLimit < 0 || lOffset < 0 {
+				return errors.New("--limit and --offset must be non-negative")
+			}
+			var minPtr, maxPtr *domain.Money
 			if cmd.Flags().Changed("min-price") {
 				minPtr = &lMin
 			}
 			if cmd.Flags().Changed("max-price") {
 				maxPtr = &lMax
 			}
-			out, err := productStore.List(context.Background(), domain.ListFilter{
-				Category: lCategory,
-				MinPrice: minPtr,
-				MaxPrice: maxPtr,
-				SortBy:   lSort,
-				Order:    lOrder,
+			var minQtyPtr, maxQtyPtr *int
+			if cmd.Flags().Changed("min-qty") {
+				minQtyPtr = &lMinQty
+			}
+			if cmd.Flags().Changed("max-qty") {
+				maxQtyPtr = &lMaxQty
+			}
+			filter := domain.ListFilter{
+				Categories:      lCategories,
+				Currency:        strings.ToUpper(lCurrency),
+				MinPrice:        minPtr,
+				MaxPrice:        maxPtr,
+				MinQuantity:     minQtyPtr,
+				MaxQuantity:     maxQtyPtr,
+				Offset:          lOffset,
+				Limit:           lLimit,
+				SortBy:          lSort,
+				Order:           lOrder,
+				CaseInsensitive: lIgnoreCase,
+				IncludeDeleted:  lIncludeDeleted,
+			}
+			if lAllTags {
+				filter.TagsAll = lTags
+			} else {
+				filter.TagsAny = lTags
+			}
+			attrFilter, err := parseAttributes(lAttrs)
+			if err != nil {
+				return err
+			}
+			filter.AttributeEquals = attrFilter
+			if lInStock || lOutOfStock {
+				filter.InStock = &lInStock
+			}
+			if lSince != "" {
+				since, err := time.Parse(time.RFC3339, lSince)
+				if err != nil {
+					return fmt.Errorf("--since must be an RFC 3339 time, e.g. 2024-01-01T00:00:00Z: %w", err)
+				}
+				filter.UpdatedAfter = &since
+			}
+			if err := checkFields(lFields); err != nil {
+				return err
+			}
+			out, err := productStore.List(ctx, filter)
+			if err != nil {
+				return err
+			}
+			return writeOutput(cmd.OutOrStdout(), lOutputFile, func(w io.Writer) {
+				if lQuiet {
+					for _, p := range out {
+						fmt.Fprintln(w, p.ID)
+					}
+					return
+				}
+				if lOutput == "json-meta" {
+					printListMeta(w, out, filter, lFields)
+					return
+				}
+				if len(lFields) > 0 {
+					printProjected(w, out, lFields, lOutput)
+					return
+				}
+				printProducts(w, out, lOutput)
+			})
+		},
+	}
+	listCmd.Flags().StringSliceVar(&lCategories, "category", nil, "category (repeatable or comma-separated)")
+	listCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	listCmd.Flags().StringSliceVar(&lTags, "tag", nil, "tag (repeatable); matches any tag unless --all-tags")
+	listCmd.Flags().BoolVar(&lAllTags, "all-tags", false, "require every --tag to match")
+	listCmd.Flags().BoolVar(&lIncludeDeleted, "include-deleted", false, "also list soft-deleted products")
+	listCmd.Flags().StringArrayVar(&lAttrs, "attr", nil, "keep products with attribute key=value (repeatable; all must match)")
+	listCmd.Flags().StringVar(&lOutputFile, "output-file", "", "write output to this file instead of stdout")
+	listCmd.Flags().StringVar(&lCurrency, "currency", "", "ISO 4217 currency code")
+	listCmd.Flags().Var(&lMin, "min-price", "min price")
+	listCmd.Flags().Var(&lMax, "max-price", "max price")
+	listCmd.Flags().IntVar(&lMinQty, "min-qty", 0, "min quantity")
+	listCmd.Flags().IntVar(&lMaxQty, "max-qty", 0, "max quantity")
+	listCmd.Flags().BoolVar(&lInStock, "in-stock", false, "only products with quantity above zero")
+	listCmd.Flags().BoolVar(&lOutOfStock, "out-of-stock", false, "only products with zero quantity")
+	listCmd.Flags().StringVar(&lSince, "since", "", "only products created or changed at or after this RFC 3339 time, e.g. 2024-01-01T00:00:00Z")
+	listCmd.Flags().StringVar(&lSort, "sort-by", "", "sort field: name, price, quantity or value (price * quantity)")
+	listCmd.Flags().StringVar(&lOrder, "order", "asc", "sort order")
+	listCmd.Flags().BoolVar(&lIgnoreCase, "ignore-case", false, "sort names case-insensitively")
+	listCmd.Flags().IntVar(&lLimit, "limit", 0, "return at most this many products (0 = all)")
+	listCmd.Flags().IntVar(&lOffset, "offset", 0, "skip this many products of the sorted result")
+	listCmd.Flags().StringVar(&lOutput, "output", "", "output format: text (default), json, or json-meta for the products wrapped with the filter, count and time")
+	listCmd.Flags().StringSliceVar(&lFields, "fields", nil, "only output these fields, e.g. id,price")
+	listCmd.Flags().BoolVarP(&lQuiet, "quiet", "q", false, "print only product ids, one per line")
+	listCmd.MarkFlagsMutuallyExclusive("quiet", "output")
+	listCmd.MarkFlagsMutuallyExclusive("quiet", "fields")
+	listCmd.MarkFlagsMutuallyExclusive("in-stock", "out-of-stock")
+	rootCmd.AddCommand(listCmd)
+
+	// low-stock
+	var lsThreshold int
+	var lsSort, lsOrder, lsOutput string
+	lowStockCmd := &cobra.Command{
+		Use:   "low-stock --threshold <n>",
+		Short: "List products at or below a stock threshold",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if lsThreshold < 0 {
+				return errors.New("--threshold must be non-negative")
+			}
+			out, err := productStore.List(ctx, domain.ListFilter{
+				MaxQuantity: &lsThreshold,
+				SortBy:      lsSort,
+				Order:       lsOrder,
 			})
 			if err != nil {
 				return err
 			}
-			if lOutput == "json" {
-				b, _ := json.MarshalIndent(out, "", "  ")
-				fmt.Println(string(b))
+			printProducts(cmd.OutOrStdout(), out, lsOutput)
+			return nil
+		},
+	}
+	lowStockCmd.Flags().IntVar(&lsThreshold, "threshold", 0, "maximum quantity to report")
+	lowStockCmd.Flags().StringVar(&lsSort, "sort-by", "quantity", "sort field")
+	lowStockCmd.Flags().StringVar(&lsOrder, "order", "asc", "sort order")
+	lowStockCmd.Flags().StringVar(&lsOutput, "output", "", "output format")
+	rootCmd.AddCommand(lowStockCmd)
+
+	// reorder-report
+	var rrOutput string
+	reorderReportCmd := &cobra.Command{
+		Use:   "reorder-report",
+		Short: "List products below their reorder level",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			out, err := productStore.NeedsReorder(ctx)
+			if err != nil {
+				return err
+			}
+			if rrOutput == "json" {
+				printProducts(cmd.OutOrStdout(), out, rrOutput)
 				return nil
 			}
 			for _, p := range out {
-				fmt.Printf("%s | %s | %.2f | %d | %s\n",
-					p.ID, p.Name, p.Price, p.Quantity, p.Category)
+				fmt.Fprintf(cmd.OutOrStdout(), "%s | %s | %d | %d | %d\n",
+					p.ID, p.Name, p.Quantity, p.ReorderLevel, p.ReorderLevel-p.Quantity)
 			}
 			return nil
 		},
 	}
-	listCmd.Flags().StringVar(&lCategory, "category", "", "category")
-	listCmd.Flags().Float64Var(&lMin, "min-price", 0, "min price")
-	listCmd.Flags().Float64Var(&lMax, "max-price", 0, "max price")
-	listCmd.Flags().StringVar(&lSort, "sort-by", "", "sort field")
-	listCmd.Flags().StringVar(&lOrder, "order", "asc", "sort order")
-	listCmd.Flags().StringVar(&lOutput, "output", "", "output format")
-	rootCmd.AddCommand(listCmd)
+	reorderReportCmd.Flags().StringVar(&rrOutput, "output", "", "output format")
+	rootCmd.AddCommand(reorderReportCmd)
+
+	// value-alert
+	var vaAbove, vaBelow domain.Money
+	var vaCategories []string
+	valueAlertCmd := &cobra.Command{
+		Use:   "value-alert --above <amount> | --below <amount>",
+		Short: "Fail when total inventory value crosses a threshold",
+		Long: `Compute the total inventory value (price * quantity) of the matching
+products and exit with status 1 when it is above --above or below --below.
+Meant for monitoring jobs.`,
+		// a crossed threshold is a result, not a usage mistake
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			above, below := cmd.Flags().Changed("above"), cmd.Flags().Changed("below")
+			if !above && !below {
+				return errors.New("pass --above, --below or both")
+			}
+			if above && below && vaBelow > vaAbove {
+				return errors.New("--below must not be greater than --above")
+			}
+			st, err := productStore.Stats(ctx, domain.ListFilter{Categories: vaCategories})
+			if err != nil {
+				return err
+			}
+			switch {
+			case above && st.Value > vaAbove:
+				return fmt.Errorf("ALERT: total inventory value %s is above %s (%d product(s))", st.Value, vaAbove, st.Count)
+			case below && st.Value < vaBelow:
+				return fmt.Errorf("ALERT: total inventory value %s is below %s (%d product(s))", st.Value, vaBelow, st.Count)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "OK: total inventory value %s (%d product(s))\n", st.Value, st.Count)
+			return nil
+		},
+	}
+	valueAlertCmd.Flags().Var(&vaAbove, "above", "alert when the total value is greater than this")
+	valueAlertCmd.Flags().Var(&vaBelow, "below", "alert when the total value is less than this")
+	valueAlertCmd.Flags().StringSliceVar(&vaCategories, "category", nil, "only count these categories (repeatable or comma-separated)")
+	valueAlertCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	rootCmd.AddCommand(valueAlertCmd)
 
 	// delete
-	var force bool
+	var force, confirmName bool
+	var dCategories, dTags []string
+	var dMin, dMax domain.Money
 	deleteCmd := &cobra.Command{
-		Use:   "delete <id>",
-		Short: "Delete a product",
-		Args:  cobra.ExactArgs(1),
+		Use:               "delete [<id>]",
+		Short:             "Delete a product, or every product matching the filter flags",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeProductIDs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !force {
-				fmt.Printf("Delete %s? (y/N): ", args[0])
+			if len(args) == 0 {
+				filter, ok := deleteFilter(cmd, dCategories, dTags, &dMin, &dMax)
+				if !ok && !force {
+					return errors.New("refusing to delete every product: pass a filter (--category, --tag, --min-price, --max-price) or --force")
+				}
+				return deleteWhere(cmd, filter, force)
+			}
+			if viper.GetBool("dry-run") {
+				ctx, cancel := commandContext(cmd)
+				defer cancel()
+				p, err := productStore.Get(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				printDryRun(cmd.OutOrStdout(), "delete", p)
+				return nil
+			}
+			if !force && confirmName {
+				ok, err := confirmByName(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Fprintln(cmd.OutOrStdout(), "aborted")
+					return nil
+				}
+			} else if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Delete %s? (y/N): ", args[0])
 				var resp string
 				if _, err := fmt.Scanln(&resp); err != nil || (resp != "y" && resp != "Y") {
-					fmt.Println("aborted")
+					fmt.Fprintln(cmd.OutOrStdout(), "aborted")
 					return nil
 				}
 			}
-			if err := productStore.Delete(context.Background(), args[0]); err != nil {
+			// start the deadline after the prompt so typing is not timed
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			if err := productStore.Delete(ctx, args[0]); err != nil {
 				return err
 			}
-			fmt.Println("deleted")
+			fmt.Fprintln(cmd.OutOrStdout(), "deleted")
 			return nil
 		},
 	}
 	deleteCmd.Flags().BoolVar(&force, "force", false, "skip confirmation")
+	deleteCmd.Flags().BoolVar(&confirmName, "confirm-name", false, "require typing the product name to confirm")
+	deleteCmd.Flags().StringSliceVar(&dCategories, "category", nil, "delete products in these categories (no id)")
+	deleteCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	deleteCmd.Flags().StringSliceVar(&dTags, "tag", nil, "delete products carrying any of these tags (no id)")
+	deleteCmd.Flags().Var(&dMin, "min-price", "delete products priced at least this (no id)")
+	deleteCmd.Flags().Var(&dMax, "max-price", "delete products priced at most this (no id)")
 	rootCmd.AddCommand(deleteCmd)
 
-	// import (FIXED: supports NDJSON)
-	var importFile string
-	importCmd := &cobra.Command{
-		Use:   "import --file <file>",
-		Short: "Import products from JSON",
+	// restore
+	var restoreFrom string
+	var restoreReplace bool
+	restoreCmd := &cobra.Command{
+		Use:   "restore <id> | --from <backup.json> [--replace]",
+		Short: "Restore a soft-deleted product, or load a backup",
+		Long: `With an id, restore a soft-deleted product. With --from, import every
+product of a backup written by "backup"; --replace empties the store first,
+once the backup has been checked.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if restoreFrom != "" {
+				if len(args) > 0 {
+					return errors.New("pass either an id or --from, not both")
+				}
+				if viper.GetBool("dry-run") {
+					products, err := loadCatalog(restoreFrom)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would restore %d product(s) from %s\n", len(products), restoreFrom)
+					return nil
+				}
+				ctx, cancel := commandContext(cmd)
+				defer cancel()
+				n, err := restoreBackup(ctx, restoreFrom, restoreReplace)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "restored %d product(s) from %s\n", n, restoreFrom)
+				return nil
+			}
+			if len(args) == 0 {
+				return errors.New("restore needs an id or --from")
+			}
+
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			if viper.GetBool("dry-run") {
+				fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would restore %s\n", args[0])
+				return nil
+			}
+			if err := productStore.Restore(ctx, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "restored")
+			return nil
+		},
+	}
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "backup file to load")
+	restoreCmd.Flags().BoolVar(&restoreReplace, "replace", false, "with --from, remove every existing product first")
+	rootCmd.AddCommand(restoreCmd)
+
+	// backup
+	var backupTo string
+	backupCmd := &cobra.Command{
+		Use:   "backup --to <file>",
+		Short: "Snapshot every product to a JSON file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if importFile == "" {
-				return errors.New("--file required")
+			if backupTo == "" {
+				return errors.New("--to required")
+			}
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			n, err := backupStore(ctx, backupTo)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "backed up %d product(s) to %s\n", n, backupTo)
+			return nil
+		},
+	}
+	backupCmd.Flags().StringVar(&backupTo, "to", "", "backup file to write")
+	rootCmd.AddCommand(backupCmd)
+
+	// undo
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the last create, update or delete of this session",
+		Long: `Revert the most recent create, update or delete made in this process,
+typically an interactive shell session. Only that one operation can be undone;
+bulk updates, batch deletes and imports cannot.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			u, ok := productStore.(interface {
+				Undo(ctx context.Context) (string, error)
+			})
+			if !ok {
+				return store.ErrNothingToUndo
+			}
+			msg, err := u.Undo(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), msg)
+			return nil
+		},
+	}
+	rootCmd.AddCommand(undoCmd)
+
+	// purge
+	var purgeForce bool
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently remove soft-deleted products",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if viper.GetBool("dry-run") || !purgeForce {
+				ctx, cancel := commandContext(cmd)
+				defer cancel()
+				n, err := countDeleted(ctx)
+				if err != nil {
+					return err
+				}
+				if viper.GetBool("dry-run") {
+					fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would purge %d product(s)\n", n)
+					return nil
+				}
+				if n == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "nothing to purge")
+					return nil
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Permanently remove %d soft-deleted product(s)? (y/N): ", n)
+				var resp string
+				if _, err := fmt.Scanln(&resp); err != nil || (resp != "y" && resp != "Y") {
+					fmt.Fprintln(cmd.OutOrStdout(), "aborted")
+					return nil
+				}
+			}
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			var n int
+			err := withSnapshot(ctx, "purge", func() (err error) {
+				n, err = productStore.Purge(ctx)
+				return err
+			})
+			if err != nil {
+				return err
 			}
+			fmt.Fprintf(cmd.OutOrStdout(), "purged %d product(s)\n", n)
+			return nil
+		},
+	}
+	purgeCmd.Flags().BoolVar(&purgeForce, "force", false, "skip confirmation")
+	rootCmd.AddCommand(purgeCmd)
 
-			b, err := os.ReadFile(importFile)
+	// clear
+	var clearForce bool
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every product from the store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if viper.GetBool("dry-run") || !clearForce {
+				ctx, cancel := commandContext(cmd)
+				all, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+				cancel()
+				if err != nil {
+					return err
+				}
+				if viper.GetBool("dry-run") {
+					fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would remove %d product(s)\n", len(all))
+					return nil
+				}
+				if len(all) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "store is already empty")
+					return nil
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Permanently remove all %d product(s)? (y/N): ", len(all))
+				var resp string
+				if _, err := fmt.Scanln(&resp); err != nil || (resp != "y" && resp != "Y") {
+					fmt.Fprintln(cmd.OutOrStdout(), "aborted")
+					return nil
+				}
+			}
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+			var n int
+			err := withSnapshot(ctx, "clear", func() (err error) {
+				n, err = productStore.Clear(ctx)
+				return err
+			})
 			if err != nil {
 				return err
 			}
+			slog.Info("store cleared", "count", n)
+			fmt.Fprintf(cmd.OutOrStdout(), "removed %d product(s)\n", n)
+			return nil
+		},
+	}
+	clearCmd.Flags().BoolVar(&clearForce, "force", false, "skip confirmation")
+	rootCmd.AddCommand(clearCmd)
 
-			btrim := bytes.TrimSpace(b)
-			if len(btrim) == 0 {
-				return errors.New("empty file")
+	// import (FIXED: supports NDJSON)
+	var importFile, importSchema, importFormat string
+	var importQuiet, importBestEffort, importValidateOnly bool
+	var importRateLimit, importMaxRetries, importBatchSize int
+	importCmd := &cobra.Command{
+		Use:   "import [--file <file>|-] [--format json|ndjson|csv|yaml]",
+		Short: "Import products from JSON, NDJSON, CSV or YAML",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if importRateLimit < 0 || importMaxRetries < 0 || importBatchSize < 0 {
+				return errors.New("--rate-limit, --max-retries and --batch-size must not be negative")
+			}
+			if (importRateLimit > 0 || importMaxRetries > 0) && !importBestEffort {
+				return errors.New("--rate-limit and --max-retries require --best-effort")
+			}
+			if importFormat != "" && !slices.Contains(importFormats, importFormat) {
+				return fmt.Errorf("unknown --format %q (want %s)", importFormat, strings.Join(importFormats, ", "))
 			}
 
-			var products []domain.Product
+			sch, err := loadSchema(importSchema)
+			if err != nil {
+				return err
+			}
+			in, err := openImportInput(ctx, cmd.InOrStdin(), importFile)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
 
-			// JSON array
-			if btrim[0] == '[' {
-				if err := json.Unmarshal(btrim, &products); err != nil {
+			if importValidateOnly {
+				var records []json.RawMessage
+				err := eachRecord(in, importFormat, func(rec json.RawMessage) error {
+					records = append(records, rec)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				report, err := checkImport(ctx, sch, records)
+				if err != nil {
 					return err
 				}
+				report.print(cmd.OutOrStdout())
+				cmd.SilenceUsage = true
+				return report.err()
+			}
+			if !importQuiet && isTerminal(os.Stderr) {
+				ctx = domain.WithProgress(ctx, progressPrinter(os.Stderr))
+			}
+			opts := domain.BulkImportOptions{
+				Atomic:     !importBestEffort,
+				RateLimit:  importRateLimit,
+				MaxRetries: importMaxRetries,
+			}
+			if importBatchSize > 0 && !viper.GetBool("dry-run") {
+				err = importBatches(ctx, in, importFormat, sch, importBatchSize, opts)
 			} else {
-				// NDJSON or single JSON object
-				scanner := bufio.NewScanner(bytes.NewReader(btrim))
-				for scanner.Scan() {
-					line := bytes.TrimSpace(scanner.Bytes())
-					if len(line) == 0 {
-						continue
-					}
-					var p domain.Product
-					if err := json.Unmarshal(line, &p); err != nil {
+				var products []domain.Product
+				if products, err = decodeProducts(in, importFormat, sch); err != nil {
+					return err
+				}
+				if viper.GetBool("dry-run") {
+					plan, err := planImport(ctx, products)
+					if err != nil {
 						return err
 					}
-					products = append(products, p)
-				}
-				if err := scanner.Err(); err != nil {
-					return err
+					plan.print(cmd.OutOrStdout())
+					return nil
 				}
+				err = productStore.BulkImportWithOptions(ctx, products, opts)
 			}
-
-			return productStore.BulkImport(context.Background(), products)
+			var bie *domain.BulkImportError
+			if errors.As(err, &bie) && len(bie.Applied) > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "imported %d product(s): %s\n", len(bie.Applied), strings.Join(bie.Applied, ", "))
+			}
+			return err
 		},
 	}
-	importCmd.Flags().StringVar(&importFile, "file", "", "input file")
+	importCmd.Flags().StringVar(&importFile, "file", "", `input file, http(s) URL, or "-" for stdin (default: stdin when piped)`)
+	importCmd.Flags().StringVar(&importFormat, "format", "", "input format: json, ndjson, csv or yaml (default: detect from the content)")
+	importCmd.Flags().StringVar(&importSchema, "schema", "", "JSON Schema file to validate records against (default: built-in product schema)")
+	importCmd.Flags().BoolVar(&importQuiet, "quiet", false, "do not show import progress on stderr")
+	importCmd.Flags().IntVar(&importBatchSize, "batch-size", 0, "stream the input into the store this many products at a time, holding only about two batches in memory; each batch is imported on its own (default: the whole input as one batch)")
+	importCmd.Flags().BoolVar(&importValidateOnly, "validate-only", false, "check every record, report each problem and import nothing; fails if any record would fail")
+	importCmd.Flags().BoolVar(&importBestEffort, "best-effort", false, "store the valid products even when others fail (default: import nothing on any failure)")
+	importCmd.Flags().IntVar(&importRateLimit, "rate-limit", 0, "with --best-effort, create at most this many products a second (default: unlimited)")
+	importCmd.Flags().IntVar(&importMaxRetries, "max-retries", 0, "with --best-effort, retry a product this many times after a transient store error, backing off exponentially")
 	rootCmd.AddCommand(importCmd)
 
 	// export
-	var exportFile, exportCategory string
+	var exportFile, exportCategory, exportZip, exportSplitBy string
+	var exportMin, exportMax domain.Money
+	var exportGzip bool
 	exportCmd := &cobra.Command{
-		Use:   "export --file <file>",
+		Use:   "export --file <file> | --zip <file.zip> [--split-by category]",
 		Short: "Export products to JSON",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if exportFile == "" {
-				return errors.New("--file required")
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			if exportFile == "" && exportZip == "" {
+				return errors.New("--file or --zip required")
 			}
-			out, err := productStore.List(context.Background(), domain.ListFilter{
-				Category: exportCategory,
-			})
+			if exportSplitBy != "" {
+				if exportZip == "" {
+					return errors.New("--split-by requires --zip")
+				}
+				if exportSplitBy != splitByCategory {
+					return fmt.Errorf("unknown --split-by %q (want %q)", exportSplitBy, splitByCategory)
+				}
+			}
+			filter := domain.ListFilter{Category: exportCategory}
+			if cmd.Flags().Changed("min-price") {
+				filter.MinPrice = &exportMin
+			}
+			if cmd.Flags().Changed("max-price") {
+				filter.MaxPrice = &exportMax
+			}
+			out, err := productStore.List(ctx, filter)
 			if err != nil {
 				return err
 			}
+			if exportZip != "" {
+				return writeZipExport(exportZip, out, exportSplitBy)
+			}
 			b, _ := json.MarshalIndent(out, "", "  ")
-			return os.WriteFile(exportFile, b, 0o644)
+			if exportGzip {
+				if b, err = util.Gzip(b); err != nil {
+					return err
+				}
+			}
+			return os.WriteFile(exportFile, b, 0o644)
 		},
 	}
 	exportCmd.Flags().StringVar(&exportFile, "file", "", "output file")
+	exportCmd.Flags().StringVar(&exportZip, "zip", "", "write a ZIP archive of JSON files instead")
+	exportCmd.Flags().StringVar(&exportSplitBy, "split-by", "", "with --zip, write one file per category (\"category\")")
+	exportCmd.Flags().BoolVar(&exportGzip, "gzip", false, "gzip-compress the --file output")
+	exportCmd.MarkFlagsMutuallyExclusive("file", "zip")
+	exportCmd.MarkFlagsMutuallyExclusive("gzip", "zip")
 	exportCmd.Flags().StringVar(&exportCategory, "category", "", "category")
+	exportCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	exportCmd.Flags().Var(&exportMin, "min-price", "min price")
+	exportCmd.Flags().Var(&exportMax, "max-price", "max price")
 	rootCmd.AddCommand(exportCmd)
+
+	// rename-category
+	renameCategoryCmd := &cobra.Command{
+		Use:               "rename-category <old> <new>",
+		Short:             "Move every product in a category to another category",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeCategories,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			from, to := args[0], args[1]
+			if from == "" {
+				return domain.NewInvalidProductError("category", "category to rename cannot be empty", from)
+			}
+			if viper.GetBool("dry-run") {
+				if err := domain.ValidateCategory(to); err != nil {
+					return err
+				}
+				matches, err := productStore.List(ctx, domain.ListFilter{Category: from})
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would move %d product(s) from %q to %q\n", len(matches), from, to)
+				return nil
+			}
+			n, err := productStore.RenameCategory(ctx, from, to)
+			if err != nil {
+				return err
+			}
+			slog.Info("category renamed", "from", from, "to", to, "count", n)
+			fmt.Fprintf(cmd.OutOrStdout(), "moved %d product(s) from %q to %q\n", n, from, to)
+			return nil
+		},
+	}
+	rootCmd.AddCommand(renameCategoryCmd)
+
+	// adjust-price
+	var apCategories, apTags []string
+	var apPercent float64
+	var apAll bool
+	adjustPriceCmd := &cobra.Command{
+		Use:   "adjust-price (--category <c> | --tag <t> | --all) --by-percent <pct>",
+		Short: "Change the price of every matching product by a percentage",
+		Long: `Change the price of every live product in the given categories or with any
+of the given tags by a percentage, e.g. --by-percent -10 for a 10% sale.
+New prices are rounded to the cent, half to even. An adjustment below -100%
+is rejected, and if any new price is invalid nothing changes. --all adjusts
+the whole catalog; --dry-run previews the new prices.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			filter := domain.ListFilter{Categories: apCategories, TagsAny: apTags}
+			if !apAll && len(apCategories) == 0 && len(apTags) == 0 {
+				return errors.New("adjust-price needs --category or --tag to select products, or --all")
+			}
+			if math.IsNaN(apPercent) || math.IsInf(apPercent, 0) || apPercent < -100 {
+				return domain.NewInvalidProductError("price", "--by-percent must be a finite percentage of at least -100", apPercent)
+			}
+			if viper.GetBool("dry-run") {
+				matches, err := productStore.List(ctx, filter)
+				if err != nil {
+					return err
+				}
+				for _, p := range matches {
+					cur := p.EffectiveCurrency()
+					fmt.Fprintf(cmd.OutOrStdout(), "%s | %s | %s -> %s\n", p.ID, p.Name,
+						domain.FormatMoney(p.Price, cur), domain.FormatMoney(p.Price.AdjustByPercent(apPercent), cur))
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would adjust %d product(s) by %v%%\n", len(matches), apPercent)
+				return nil
+			}
+			n, err := productStore.AdjustPriceWhere(ctx, filter, apPercent)
+			if err != nil {
+				return err
+			}
+			slog.Info("prices adjusted", "percent", apPercent, "count", n)
+			fmt.Fprintf(cmd.OutOrStdout(), "adjusted %d product(s) by %v%%\n", n, apPercent)
+			return nil
+		},
+	}
+	adjustPriceCmd.Flags().StringSliceVar(&apCategories, "category", nil, "adjust products in these categories")
+	adjustPriceCmd.RegisterFlagCompletionFunc("category", completeCategories)
+	adjustPriceCmd.Flags().StringSliceVar(&apTags, "tag", nil, "adjust products carrying any of these tags")
+	adjustPriceCmd.Flags().BoolVar(&apAll, "all", false, "adjust every product")
+	adjustPriceCmd.Flags().Float64Var(&apPercent, "by-percent", 0, "percentage to change prices by, e.g. -10 for 10% off")
+	adjustPriceCmd.MarkFlagRequired("by-percent")
+	adjustPriceCmd.MarkFlagsMutuallyExclusive("all", "category")
+	adjustPriceCmd.MarkFlagsMutuallyExclusive("all", "tag")
+	rootCmd.AddCommand(adjustPriceCmd)
+
+	// categories
+	var catOutput string
+	categoriesCmd := &cobra.Command{
+		Use:   "categories",
+		Short: "List the categories in use with a product count each",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			counts, err := productStore.Categories(ctx)
+			if err != nil {
+				return err
+			}
+			type categoryCount struct {
+				Category string `json:"category"`
+				Count    int    `json:"count"`
+			}
+			out := make([]categoryCount, 0, len(counts))
+			for c, n := range counts {
+				out = append(out, categoryCount{c, n})
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i].Category < out[j].Category })
+			if catOutput == "json" {
+				b, _ := json.MarshalIndent(out, "", "  ")
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}
+			for _, c := range out {
+				name := c.Category
+				if name == "" {
+					name = "(none)"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s | %d\n", name, c.Count)
+			}
+			return nil
+		},
+	}
+	categoriesCmd.Flags().StringVar(&catOutput, "output", "", "output format")
+	rootCmd.AddCommand(categoriesCmd)
+
+	// find-duplicates
+	var fdOutput string
+	findDuplicatesCmd := &cobra.Command{
+		Use:   "find-duplicates",
+		Short: "Report products that share a name and category under different ids",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			groups, err := productStore.FindDuplicates(ctx)
+			if err != nil {
+				return err
+			}
+			if fdOutput == "json" {
+				if groups == nil {
+					groups = []domain.DuplicateGroup{}
+				}
+				b, _ := json.MarshalIndent(groups, "", "  ")
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}
+			if len(groups) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no duplicates found")
+				return nil
+			}
+			for _, g := range groups {
+				category := g.Category
+				if category == "" {
+					category = "(none)"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s | %s | %s\n", g.Name, category, strings.Join(g.IDs, ", "))
+			}
+			return nil
+		},
+	}
+	findDuplicatesCmd.Flags().StringVar(&fdOutput, "output", "", "output format")
+	rootCmd.AddCommand(findDuplicatesCmd)
+
+	// price-history
+	var phOutput string
+	priceHistoryCmd := &cobra.Command{
+		Use:               "price-history <id>",
+		Short:             "Show how a product's price changed (needs --track-price-history)",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProductIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			changes, err := productStore.PriceHistory(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if phOutput == "json" {
+				if changes == nil {
+					changes = []domain.PriceChange{}
+				}
+				b, _ := json.MarshalIndent(changes, "", "  ")
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}
+			if len(changes) == 0 {
+				if !viper.GetBool("track-price-history") {
+					fmt.Fprintln(cmd.OutOrStdout(), "no price changes recorded (enable --track-price-history to record them)")
+				} else {
+					fmt.Fprintln(cmd.OutOrStdout(), "no price changes recorded")
+				}
+				return nil
+			}
+			for _, c := range changes {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s | %s -> %s\n", c.At.Format(time.RFC3339), c.OldPrice, c.NewPrice)
+			}
+			return nil
+		},
+	}
+	priceHistoryCmd.Flags().StringVar(&phOutput, "output", "", "output format")
+	rootCmd.AddCommand(priceHistoryCmd)
+
+	// search
+	var sLimit int
+	var sOutput string
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Find products by name or category, tolerating typos",
+		Long: `Rank products by how closely their name, a word of it or their category
+matches the query. Substring matches rank first; other matches are scored by
+edit distance, and weak ones are left out.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			all, err := productStore.List(ctx, domain.ListFilter{})
+			if err != nil {
+				return err
+			}
+			results := rankProducts(all, args[0], sLimit)
+			if sOutput == "json" {
+				b, _ := json.MarshalIndent(results, "", "  ")
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}
+			products := make([]domain.Product, len(results))
+			for i, r := range results {
+				products[i] = r.Product
+			}
+			printProducts(cmd.OutOrStdout(), products, sOutput)
+			return nil
+		},
+	}
+	searchCmd.Flags().IntVar(&sLimit, "limit", 10, "maximum number of results (0 = all)")
+	searchCmd.Flags().StringVar(&sOutput, "output", "", "output format")
+	rootCmd.AddCommand(searchCmd)
+
+	// merge
+	var onConflict string
+	mergeCmd := &cobra.Command{
+		Use:   "merge <out.json> <in.json>...",
+		Short: "Combine catalog files into one without a store",
+		Long: `Load every input catalog (JSON array, object or NDJSON), validate the
+products and write them to out.json as one JSON array sorted by id, which
+the file store loads as it is. --on-conflict decides what happens when several
+inputs share an id: keep the first, keep the last, or fail.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			products, err := mergeCatalogs(args[1:], onConflict)
+			if err != nil {
+				return err
+			}
+			if viper.GetBool("dry-run") {
+				fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would write %d product(s) to %s\n", len(products), args[0])
+				return nil
+			}
+			if err := writeCatalog(args[0], products); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "merged %d product(s) from %d file(s) into %s\n", len(products), len(args)-1, args[0])
+			return nil
+		},
+	}
+	mergeCmd.Flags().StringVar(&onConflict, "on-conflict", conflictError, "duplicate id policy: first, last or error")
+	rootCmd.AddCommand(mergeCmd)
+
+	// validate
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check every stored product and report all problems",
+		Long: `Check every record in the store, soft-deleted ones included, against the
+product validation rules, and look for missing or repeated ids and for live
+products sharing a SKU (the "sku" attribute). Each problem is printed with the
+record's id. The file store's file is read as written, so hand edits are
+checked before loading hides them. Exits with status 1 if anything is wrong.`,
+		// problems in the data are a result, not a usage mistake
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			products, err := storeRecords(ctx)
+			if err != nil {
+				return err
+			}
+			problems := checkStoreRecords(products)
+			for _, p := range problems {
+				fmt.Fprintln(cmd.OutOrStdout(), p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problem(s) in %d record(s)", len(problems), len(products))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "ok: %d record(s) checked, no problems found\n", len(products))
+			return nil
+		},
+	}
+	rootCmd.AddCommand(validateCmd)
+
+	// migrate
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [<file>]",
+		Short: "Upgrade a store file to the current file format",
+		Long: `Rewrite a store file, --store-file by default, in the current format: a
+versioned envelope {"version": N, "products": [...]}. Older files, such as the
+original bare JSON array, are still read, so migrating is only needed before
+handing the file to tools that expect the new layout. --store-encryption-key
+and a .gz name are honoured as for the store itself.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, path, err := storeLocation()
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				path = args[0]
+			}
+			if path == "" {
+				return errors.New("no file to migrate: pass one or set --store-file")
+			}
+			opts, err := storeOptions()
+			if err != nil {
+				return err
+			}
+			if viper.GetBool("dry-run") {
+				fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would migrate %s to format version %d\n", path, store.FileFormatVersion)
+				return nil
+			}
+			from, err := store.MigrateFile(path, opts)
+			if err != nil {
+				return err
+			}
+			if from == store.FileFormatVersion {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is already at format version %d\n", path, from)
+				return nil
+			}
+			slog.Info("store file migrated", "path", path, "from", from, "to", store.FileFormatVersion)
+			fmt.Fprintf(cmd.OutOrStdout(), "migrated %s from format version %d to %d\n", path, from, store.FileFormatVersion)
+			return nil
+		},
+	}
+	rootCmd.AddCommand(migrateCmd)
+
+	// watch
+	var wOutput string
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print change events as they happen",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// runs until Ctrl-C cancels the command's context
+			ctx, cancel := commandContext(cmd)
+			defer cancel()
+
+			events, err := productStore.Watch(ctx)
+			if err != nil {
+				return err
+			}
+			for ev := range events {
+				if wOutput == "json" {
+					b, _ := json.Marshal(ev)
+					fmt.Fprintln(cmd.OutOrStdout(), string(b))
+					continue
+				}
+				p := ev.Product
+				fmt.Fprintf(cmd.OutOrStdout(), "%s | %s | %s | %s | %d | %s\n",
+					ev.Op, ev.ID, p.Name, p.Price, p.Quantity, p.Category)
+			}
+			return nil
+		},
+	}
+	watchCmd.Flags().StringVar(&wOutput, "output", "", "output format")
+	rootCmd.AddCommand(watchCmd)
+
+	// config
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration file",
+		// no store or config is needed to write a config file
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	var cfgOutput string
+	var cfgForce bool
+	configInitCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a sample config file with all supported keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := writeSampleConfig(cfgOutput, cfgForce); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "wrote", cfgOutput)
+			return nil
+		},
+	}
+	configInitCmd.Flags().StringVar(&cfgOutput, "output", "config.yaml", "output file")
+	configInitCmd.Flags().BoolVar(&cfgForce, "force", false, "overwrite an existing file")
+	configCmd.AddCommand(configInitCmd)
+	rootCmd.AddCommand(configCmd)
+
+	// serve
+	var serveAddr string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the store over a JSON REST API until interrupted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Ctrl-C cancels the command's context and shuts the server down
+			ctx := cmd.Context()
+
+			mux := http.NewServeMux()
+			mux.Handle("/", server.NewHandler(store.NewInstrumentedStore(productStore)))
+			mux.Handle("GET /metrics", store.DefaultMetrics.Handler())
+			srv := &http.Server{Addr: serveAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+			errc := make(chan error, 1)
+			go func() { errc <- srv.ListenAndServe() }()
+			slog.Info("serving", "addr", serveAddr)
+
+			select {
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdownCtx)
+		},
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "listen address")
+	rootCmd.AddCommand(serveCmd)
+
+	// completion
+	completionCmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script, e.g.:
+
+  source <(inventory-cli completion bash)
+  inventory-cli completion zsh > "${fpath[1]}/_inventory-cli"`,
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		DisableFlagsInUseLine: true,
+		PersistentPreRunE:     func(cmd *cobra.Command, args []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeCompletion(cmd.OutOrStdout(), args[0])
+		},
+	}
+	rootCmd.AddCommand(completionCmd)
 }
 
+// setup loads configuration, configures logging and opens the store. It is a
+// no-op once productStore is set.
+func setup() error {
+	// IMPORTANT: allow tests to inject store
+	if productStore != nil {
+		return nil
+	}
+
+	// before anything reads the environment, INVENTORY_CONFIG included
+	if envFile := viper.GetString("env-file"); envFile != "" {
+		if err := loadEnvFile(envFile); err != nil {
+			return err
+		}
+	}
+	cfg := findConfig(viper.GetString("config"))
+	if cfg != "" {
+		viper.SetConfigFile(cfg)
+		if err := viper.ReadInConfig(); err != nil {
+			return err
+		}
+	}
+	if err := applyValidationConfig(viper.GetViper()); err != nil {
+		return err
+	}
+
+	lvlStr := strings.ToLower(viper.GetString("log-level"))
+	lvl := slog.LevelInfo
+	switch lvlStr {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn", "warning":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	}
+	logOut, err := logWriter(viper.GetString("log-file"), viper.GetBool("log-also-stderr"))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(
+		slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: lvl}),
+	))
+	if cfg != "" {
+		slog.Debug("loaded config file", "path", cfg)
+	} else {
+		slog.Debug("no config file found", "searched", defaultConfigPaths())
+	}
+
+	opts, err := storeOptions()
+	if err != nil {
+		return err
+	}
+	kind, path, err := storeLocation()
+	if err != nil {
+		return err
+	}
+	slog.Debug("opening store", "dsn", store.DSN(kind, path))
+	s, err := store.NewStoreWithOptions(kind, path, opts)
+	if err != nil {
+		return err
+	}
+	switch max := viper.GetInt("max-products"); {
+	case max < 0:
+		return errors.New("--max-products must not be negative")
+	case max > 0:
+		s = store.NewCappedStore(s, max)
+	}
+	// the store lives as long as the process, so undo spans a shell session
+	productStore = store.NewUndoStore(s)
+	return nil
+}
+
+// storeLocation returns the store kind and path selected by --store, which
+// may be a DSN such as file://data/products.json, and --store-file
+func storeLocation() (kind, path string, err error) {
+	return store.ResolveStore(viper.GetString("store"), viper.GetString("store-file"))
+}
+
+// storeOptions builds the store options from flags, config and environment
+func storeOptions() (store.Options, error) {
+	fileMode, err := parseFileMode(viper.GetString("store-file-mode"))
+	if err != nil {
+		return store.Options{}, err
+	}
+	encKey, err := parseEncryptionKey(viper.GetString("store-encryption-key"))
+	if err != nil {
+		return store.Options{}, err
+	}
+	return store.Options{
+		SoftDelete:        viper.GetBool("soft-delete"),
+		RecoverCorrupt:    !viper.GetBool("strict-load"),
+		Durable:           viper.GetBool("durable"),
+		FileMode:          fileMode,
+		Journal:           viper.GetBool("journal"),
+		TrackPriceHistory: viper.GetBool("track-price-history"),
+		WatchFile:         viper.GetBool("watch-file"),
+		EncryptionKey:     encKey,
+	}, nil
+}
+
+// updateFilter builds the selector for "update" without an id: --category
+// and --tag choose products instead of setting fields.
+func updateFilter(cmd *cobra.Command, category string, tags []string) *domain.ListFilter {
+	if !cmd.Flags().Changed("category") && len(tags) == 0 {
+		return nil
+	}
+	return &domain.ListFilter{Category: category, TagsAny: tags}
+}
+
+// updateWhere applies patch to the products selected by filter, or previews
+// the matches under --dry-run.
+func updateWhere(ctx context.Context, w io.Writer, filter *domain.ListFilter, patch domain.ProductPatch) error {
+	if filter == nil {
+		return errors.New("update without an id needs --category or --tag to select products")
+	}
+	if patch.IsEmpty() {
+		return errors.New("update without an id needs --set-category, --set-price or --set-quantity")
+	}
+	if viper.GetBool("dry-run") {
+		matches, err := productStore.List(ctx, *filter)
+		if err != nil {
+			return err
+		}
+		printProducts(w, matches, "")
+		fmt.Fprintf(w, "dry-run: would update %d product(s)\n", len(matches))
+		return nil
+	}
+	updated, err := productStore.UpdateWhere(ctx, *filter, patch)
+	if err != nil {
+		return err
+	}
+	slog.Info("products updated", "count", len(updated))
+	fmt.Fprintf(w, "updated %d product(s)\n", len(updated))
+	return nil
+}
+
+// deleteFilter builds the ListFilter for "delete" without an id from its
+// filter flags. ok is false when no filter flag was given.
+func deleteFilter(cmd *cobra.Command, categories, tags []string, min, max *domain.Money) (filter domain.ListFilter, ok bool) {
+	filter = domain.ListFilter{Categories: categories, TagsAny: tags}
+	if cmd.Flags().Changed("min-price") {
+		filter.MinPrice = min
+	}
+	if cmd.Flags().Changed("max-price") {
+		filter.MaxPrice = max
+	}
+	ok = len(categories) > 0 || len(tags) > 0 || filter.MinPrice != nil || filter.MaxPrice != nil
+	return filter, ok
+}
+
+// deleteWhere lists the products matching filter, confirms unless force is
+// set, and removes them with one BatchDelete.
+func deleteWhere(cmd *cobra.Command, filter domain.ListFilter, force bool) error {
+	ctx, cancel := commandContext(cmd)
+	matches, err := productStore.List(ctx, filter)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "no matching products")
+		return nil
+	}
+	printProducts(cmd.OutOrStdout(), matches, "")
+	if viper.GetBool("dry-run") {
+		fmt.Fprintf(cmd.OutOrStdout(), "dry-run: would delete %d product(s)\n", len(matches))
+		return nil
+	}
+	if !force {
+		fmt.Fprintf(cmd.OutOrStdout(), "Delete %d product(s)? (y/N): ", len(matches))
+		var resp string
+		if _, err := fmt.Scanln(&resp); err != nil || (resp != "y" && resp != "Y") {
+			fmt.Fprintln(cmd.OutOrStdout(), "aborted")
+			return nil
+		}
+	}
+
+	ids := make([]string, len(matches))
+	for i, p := range matches {
+		ids[i] = p.ID
+	}
+	ctx, cancel = commandContext(cmd)
+	defer cancel()
+	err = withSnapshot(ctx, "delete", func() error {
+		return productStore.BatchDelete(ctx, ids)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "deleted %d product(s)\n", len(ids))
+	return nil
+}
+
+// confirmByName shows the product's name and reports whether the user typed
+// it back exactly. It reads a whole line so names containing spaces work.
+func confirmByName(cmd *cobra.Command, id string) (bool, error) {
+	ctx, cancel := commandContext(cmd)
+	p, err := productStore.Get(ctx, id)
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Type the product name %q to delete %s: ", p.Name, id)
+	resp, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && resp == "" {
+		return false, nil
+	}
+	return strings.TrimRight(resp, "\r\n") == p.Name, nil
+}
+
+// checkNonNegative rejects a negative value for flag when it was given
+func checkNonNegative(cmd *cobra.Command, flag string, v int64) error {
+	if cmd.Flags().Changed(flag) && v < 0 {
+		return domain.NewInvalidProductError(strings.TrimPrefix(flag, "set-"), "--"+flag+" must be non-negative", cmd.Flags().Lookup(flag).Value.String())
+	}
+	return nil
+}
+
+// printDryRun reports a mutation skipped because of --dry-run.
+func printDryRun(w io.Writer, op string, p domain.Product) {
+	b, _ := json.MarshalIndent(p, "", "  ")
+	fmt.Fprintf(w, "dry-run: would %s %s\n%s\n", op, p.ID, b)
+}
+
+// countDeleted returns how many soft-deleted products the store holds
+func countDeleted(ctx context.Context) (int, error) {
+	all, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range all {
+		if p.IsDeleted() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// validateProduct reports every invalid field of p: a single problem as its
+// *domain.InvalidProductError, several as a *domain.MultiError of them
+func validateProduct(p domain.Product) error {
+	errs := domain.ValidateProductAll(p)
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	var me domain.MultiError
+	for _, err := range errs {
+		me.Append(err)
+	}
+	return &me
+}
+
+// checkIDFree returns a duplicate error when id is taken, including by a
+// soft-deleted product that Get hides but Create still rejects
+func checkIDFree(ctx context.Context, id string) error {
+	all, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	for _, p := range all {
+		if p.ID == id {
+			return domain.NewDuplicateProductError(id)
+		}
+	}
+	return nil
+}
+
+// newID generates a product id with the named util.NewIDGenerator
+// strategy. A sequence for a file store continues after the high-water mark
+// saved next to the store file, which reserve advances, so a number is not
+// handed out twice even once its product is purged.
+func newID(ctx context.Context, strategy string, reserve bool) (string, error) {
+	g, err := util.NewIDGenerator(strategy)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := g.(*util.Sequence); ok {
+		return nextSequenceID(ctx, reserve)
+	}
+	return g.NewID()
+}
+
+// nextSequenceID returns the next sequential id, saving the new high-water
+// mark when reserve is set. Without a saved mark, for a memory store or a
+// file store that has none yet, the sequence starts after the highest
+// matching id in the store, soft-deleted ones included.
+func nextSequenceID(ctx context.Context, reserve bool) (string, error) {
+	kind, path, err := storeLocation()
+	if err != nil {
+		return "", err
+	}
+	seq, saved := util.NewSequence(util.DefaultSequencePrefix, util.DefaultSequenceWidth), false
+	if kind == "file" {
+		if seq, saved, err = util.LoadSequence(sequencePath(path), util.DefaultSequencePrefix, util.DefaultSequenceWidth); err != nil {
+			return "", err
+		}
+	}
+	if !saved {
+		all, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+		if err != nil {
+			return "", err
+		}
+		for _, p := range all {
+			seq.Observe(p.ID)
+		}
+	}
+	id, err := seq.NewID()
+	if err != nil || !reserve || kind != "file" {
+		return id, err
+	}
+	opts, err := storeOptions()
+	if err != nil {
+		return "", err
+	}
+	perm := opts.FileMode
+	if perm == 0 {
+		perm = store.DefaultFileMode
+	}
+	if err := seq.Save(sequencePath(path), perm); err != nil {
+		return "", fmt.Errorf("save id sequence: %w", err)
+	}
+	return id, nil
+}
+
+// sequencePath is where the sequence high-water mark of the file store at
+// path is kept
+func sequencePath(path string) string {
+	return path + ".seq"
+}
+
+// parseAttributes turns repeated key=value flag values into a map; it
+// returns nil when none were given.
+func parseAttributes(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	attrs := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid attribute %q: want key=value", kv)
+		}
+		attrs[k] = v
+	}
+	return attrs, nil
+}
+
+// parseFileMode reads octal permission bits such as "0600"
+func parseFileMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("invalid store-file-mode %q: want octal permissions such as 0600", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// parseEncryptionKey decodes the hex store-encryption-key; empty means none
+func parseEncryptionKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil || (len(key) != 16 && len(key) != 24 && len(key) != 32) {
+		return nil, errors.New("invalid store-encryption-key: want 32, 48 or 64 hex digits, e.g. from `openssl rand -hex 32`")
+	}
+	return key, nil
+}
+
+// logWriter returns where logs go: stderr by default, or the file at path
+// (opened for append, created if missing), optionally together with stderr.
+// The file stays open for the life of the process.
+func logWriter(path string, alsoStderr bool) (io.Writer, error) {
+	if path == "" {
+		return os.Stderr, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	if alsoStderr {
+		return io.MultiWriter(f, os.Stderr), nil
+	}
+	return f, nil
+}
+
+// commandContext derives the context for a command's store calls from
+// cmd.Context(), bounded by the configured --timeout when it is positive.
+func commandContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d := viper.GetDuration("timeout"); d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
+
+// exitInterrupted is the conventional status of a process ended by SIGINT
+const exitInterrupted = 130
+
+// Execute runs the CLI. When the failing command was asked for
+// --output json, the error is also printed to stdout as a
+// domain.ErrorEnvelope so scripts can switch on its code.
 func Execute() error {
-	return rootCmd.Execute()
+	// Ctrl-C cancels the command's context, so a long import or bulk update
+	// stops at its next check and the store is still closed below. A second
+	// Ctrl-C exits at once.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var interrupted atomic.Bool
+	defer onInterrupt(func() {
+		if interrupted.Swap(true) {
+			os.Exit(exitInterrupted)
+		}
+		fmt.Fprintln(os.Stderr, "interrupted, stopping; press Ctrl-C again to quit at once")
+		cancel()
+	})()
+
+	cmd, err := executeContext(ctx)
+	if err != nil && interrupted.Load() {
+		err = interruptedError(err)
+	}
+	// PersistentPostRunE is skipped when a command fails, so close here
+	if cerr := domain.CloseStore(context.Background(), productStore); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil && cmd != nil {
+		if f := cmd.Flags().Lookup("output"); f != nil && f.Value.String() == "json" {
+			b, _ := json.MarshalIndent(domain.NewErrorEnvelope(err), "", "  ")
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+		}
+	}
+	return err
+}
+
+// writeOutput runs write against stdout, or against the file at path
+// (created or truncated) when path is non-empty.
+func writeOutput(stdout io.Writer, path string, write func(w io.Writer)) error {
+	if path == "" {
+		write(stdout)
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write(f)
+	return f.Close()
+}
+
+// listEnvelope is the list --output json-meta document: the products (or
+// their --fields projections) with the filter that selected them, their count
+// and when the list was generated
+type listEnvelope struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Filter      domain.ListFilter `json:"filter"`
+	Count       int               `json:"count"`
+	Products    interface{}       `json:"products"`
+}
+
+// printListMeta writes out to w as an indented listEnvelope. With fields,
+// the products are projected as for printProjected.
+func printListMeta(w io.Writer, out []domain.Product, filter domain.ListFilter, fields []string) {
+	if out == nil {
+		out = []domain.Product{}
+	}
+	env := listEnvelope{GeneratedAt: time.Now().UTC(), Filter: filter, Count: len(out), Products: out}
+	if len(fields) > 0 {
+		env.Products = projectAll(out, fields)
+	}
+	b, _ := json.MarshalIndent(env, "", "  ")
+	fmt.Fprintln(w, string(b))
+}
+
+// printProducts writes products to w as an indented JSON array when format is
+// "json", otherwise as one pipe-separated line per product: id, name, price,
+// quantity, value (price * quantity) and category, ending in "deleted" for
+// soft-deleted ones. Text output may be colored (see
+// useColor): low-stock quantities red and deleted markers dim.
+func printProducts(w io.Writer, out []domain.Product, format string) {
+	if format == "json" {
+		b, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Fprintln(w, string(b))
+		return
+	}
+	color := useColor(w)
+	for _, p := range out {
+		deleted := ""
+		if p.IsDeleted() {
+			deleted = " | " + paint(color, ansiDim, "deleted")
+		}
+		qty := strconv.Itoa(p.Quantity)
+		if isLowStock(p) {
+			qty = paint(color, ansiRed, qty)
+		}
+		cur := p.EffectiveCurrency()
+		fmt.Fprintf(w, "%s | %s | %s | %s | %s | %s%s\n",
+			p.ID, p.Name, domain.FormatMoney(p.Price, cur), qty, domain.FormatMoney(p.Value(), cur), p.Category, deleted)
+	}
 }
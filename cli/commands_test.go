@@ -6,8 +6,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // capture stdout during cobra execution
@@ -30,6 +38,21 @@ func captureOutput(f func() error) (string, error) {
 func resetCLI() {
 	rootCmd.SetArgs(nil)
 	productStore = nil
+	resetFlags(rootCmd.Commands())
+}
+
+func resetFlags(cmds []*cobra.Command) {
+	for _, c := range cmds {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				_ = sv.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+		resetFlags(c.Commands())
+	}
 }
 
 func TestCreateGetListUpdateDelete(t *testing.T) {
@@ -88,7 +111,7 @@ func TestCreateGetListUpdateDelete(t *testing.T) {
 
 	var updated domain.Product
 	_ = json.Unmarshal([]byte(out), &updated)
-	if updated.Price != 7.75 {
+	if updated.Price != 775 {
 		t.Fatalf("price not updated")
 	}
 
@@ -106,3 +129,985 @@ func TestCreateGetListUpdateDelete(t *testing.T) {
 		t.Fatalf("expected product to be deleted")
 	}
 }
+
+func TestLowStock(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "l1", Name: "Plenty", Price: 1, Quantity: 50})
+	_ = productStore.Create(ctx, domain.Product{ID: "l2", Name: "Few", Price: 1, Quantity: 3})
+	_ = productStore.Create(ctx, domain.Product{ID: "l3", Name: "None", Price: 1, Quantity: 0})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"low-stock", "--threshold", "5", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("low-stock failed: %v", err)
+	}
+	var got []domain.Product
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid low-stock output: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "l3" || got[1].ID != "l2" {
+		t.Fatalf("unexpected low-stock result: %+v", got)
+	}
+}
+
+func TestGetNotFoundStrict(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("strict", "false")
+	st := store.NewInMemoryStore()
+
+	resetCLI()
+	productStore = st
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"get", "missing"})
+		return rootCmd.Execute()
+	})
+	if err != nil || out != "" {
+		t.Fatalf("expected get to tolerate a missing id by default, got %q, %v", out, err)
+	}
+
+	resetCLI()
+	productStore = st
+	_, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"get", "missing", "--strict"})
+		return rootCmd.Execute()
+	})
+	if !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected a not found error with --strict, got %v", err)
+	}
+}
+
+func TestListSince(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	_ = st.Create(context.Background(), domain.Product{ID: "s1", Name: "A", Price: 1, Quantity: 1})
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	out, err := run("list", "-q", "--since", time.Now().UTC().Add(-time.Minute).Format(time.RFC3339))
+	if err != nil || out != "s1\n" {
+		t.Fatalf("expected s1 changed in the last minute, got %q, %v", out, err)
+	}
+	out, err = run("list", "-q", "--since", "2999-01-01T00:00:00Z")
+	if err != nil || out != "" {
+		t.Fatalf("expected nothing changed since 2999, got %q, %v", out, err)
+	}
+	if _, err := run("list", "--since", "2024-01-01"); err == nil || !strings.Contains(err.Error(), "RFC 3339") {
+		t.Fatalf("expected a format error, got %v", err)
+	}
+}
+
+func TestListMultipleCategories(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "m1", Name: "A", Price: 1, Quantity: 1, Category: "Tools"})
+	_ = productStore.Create(ctx, domain.Product{ID: "m2", Name: "B", Price: 1, Quantity: 1, Category: "Toys"})
+	_ = productStore.Create(ctx, domain.Product{ID: "m3", Name: "C", Price: 1, Quantity: 1, Category: "Food"})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--category", "Tools,Toys", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var got []domain.Product
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid list output: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(got))
+	}
+}
+
+func TestListJSONMeta(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "j1", Name: "A", Price: 150, Quantity: 2, Category: "Tools"})
+	_ = productStore.Create(ctx, domain.Product{ID: "j2", Name: "B", Price: 1, Quantity: 1, Category: "Food"})
+
+	before := time.Now().UTC().Add(-time.Second)
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--category", "Tools", "--min-price", "1", "--output", "json-meta"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var got struct {
+		GeneratedAt time.Time                  `json:"generatedAt"`
+		Filter      map[string]json.RawMessage `json:"filter"`
+		Count       int                        `json:"count"`
+		Products    []domain.Product           `json:"products"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid json-meta output %q: %v", out, err)
+	}
+	if got.GeneratedAt.Before(before) {
+		t.Fatalf("expected a current generatedAt, got %v", got.GeneratedAt)
+	}
+	if got.Count != 1 || len(got.Products) != 1 || got.Products[0].ID != "j1" {
+		t.Fatalf("expected only j1, got count %d and %+v", got.Count, got.Products)
+	}
+	var categories []string
+	_ = json.Unmarshal(got.Filter["categories"], &categories)
+	if len(categories) != 1 || categories[0] != "Tools" || string(got.Filter["minPrice"]) != "1" {
+		t.Fatalf("expected the applied filter, got %s", out)
+	}
+	if _, ok := got.Filter["maxPrice"]; ok {
+		t.Fatalf("expected unset filter fields to be left out, got %s", out)
+	}
+
+	st := productStore
+	resetCLI()
+	productStore = st
+	out, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--category", "None", "--fields", "id", "--output", "json-meta"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if !strings.Contains(out, `"count": 0`) || !strings.Contains(out, `"products": []`) {
+		t.Fatalf("expected an empty envelope, got %s", out)
+	}
+}
+
+func TestCreateAndListByTag(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"create", "--name", "Tagged", "--tag", "sale", "--tag", "new"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	var created domain.Product
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("invalid create output: %v", err)
+	}
+	if len(created.Tags) != 2 {
+		t.Fatalf("expected 2 tags, got %v", created.Tags)
+	}
+
+	out, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--tag", "new", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var got []domain.Product
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid list output: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != created.ID {
+		t.Fatalf("unexpected tag filter result: %+v", got)
+	}
+}
+
+func TestCreateAndListByAttribute(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+
+	for _, args := range [][]string{
+		{"create", "--name", "Red shirt", "--attr", "color=red", "--attr", "size=M"},
+		{"create", "--name", "Blue shirt", "--attr", "color=blue"},
+	} {
+		resetCLI()
+		productStore = st
+		if _, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	resetCLI()
+	productStore = st
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--attr", "color=red", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var got []domain.Product
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid list output: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "Red shirt" || got[0].Attributes["size"] != "M" {
+		t.Fatalf("unexpected attribute filter result: %+v", got)
+	}
+
+	resetCLI()
+	rootCmd.SetArgs([]string{"list", "--attr", "color"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("expected an error for an attribute without a value")
+	}
+}
+
+func TestSoftDeleteRestorePurge(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStoreWithOptions(store.Options{SoftDelete: true})
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "s1", Name: "Gone", Price: 1, Quantity: 1})
+	_ = st.Create(ctx, domain.Product{ID: "s2", Name: "Kept", Price: 1, Quantity: 1})
+
+	run := func(args ...string) string {
+		t.Helper()
+		resetCLI()
+		productStore = st
+		out, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return out
+	}
+
+	run("delete", "s1", "--force")
+	if out := run("list"); strings.Contains(out, "Gone") {
+		t.Fatalf("soft-deleted product listed by default:\n%s", out)
+	}
+	if out := run("list", "--include-deleted"); !strings.Contains(out, "s1 | Gone") || !strings.Contains(out, "| deleted") {
+		t.Fatalf("expected soft-deleted product with --include-deleted:\n%s", out)
+	}
+
+	run("restore", "s1")
+	if out := run("list"); !strings.Contains(out, "Gone") {
+		t.Fatalf("expected restored product to be listed:\n%s", out)
+	}
+
+	run("delete", "s1", "--force")
+	if out := run("purge", "--force"); !strings.Contains(out, "purged 1 product(s)") {
+		t.Fatalf("unexpected purge output: %q", out)
+	}
+	if out := run("list", "--include-deleted"); strings.Contains(out, "Gone") {
+		t.Fatalf("purged product still listed:\n%s", out)
+	}
+}
+
+func TestClearCommand(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "products.json")
+	st, err := store.NewFileStoreWithOptions(path, store.Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "x1", Name: "A", Price: 1, Quantity: 1})
+	_ = st.Create(ctx, domain.Product{ID: "x2", Name: "B", Price: 1, Quantity: 1})
+	_ = st.Delete(ctx, "x2")
+	productStore = st
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"clear", "--force"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "removed 2 product(s)") {
+		t.Fatalf("unexpected clear output %q, %v", out, err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(b), `"products": []`) {
+		t.Fatalf("expected an empty product list on disk, got %q (%v)", b, err)
+	}
+}
+
+func TestMaxPriceZeroFiltersFreeItems(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "f1", Name: "Free", Price: 0, Quantity: 1})
+	_ = st.Create(ctx, domain.Product{ID: "f2", Name: "Paid", Price: 500, Quantity: 1})
+	productStore = st
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--max-price", "0", "-q"})
+		return rootCmd.Execute()
+	})
+	if err != nil || out != "f1\n" {
+		t.Fatalf("expected only the free item, got %q (%v)", out, err)
+	}
+
+	resetCLI()
+	productStore = st
+	path := filepath.Join(t.TempDir(), "export.json")
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"export", "--file", path, "--max-price", "0"})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	var exported []domain.Product
+	b, _ := os.ReadFile(path)
+	if err := json.Unmarshal(b, &exported); err != nil || len(exported) != 1 || exported[0].ID != "f1" {
+		t.Fatalf("expected only the free item exported, got %s (%v)", b, err)
+	}
+}
+
+func TestUpdateRejectsNegativeFlagsBeforeStore(t *testing.T) {
+	for _, args := range [][]string{
+		{"update", "missing", "--price", "-5"},
+		{"update", "missing", "--quantity=-1"},
+		{"update", "--category", "Office", "--set-price", "-1"},
+	} {
+		resetCLI()
+		productStore = store.NewInMemoryStore()
+		_, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+		// a not-found error would mean the store was read first
+		if !domain.IsInvalidProductError(err) {
+			t.Fatalf("%v: expected invalid product error, got %v", args, err)
+		}
+	}
+	resetCLI()
+}
+
+func TestCreateAndUpdateReportEveryInvalidField(t *testing.T) {
+	defer resetCLI()
+	for _, args := range [][]string{
+		{"create", "--name", "bad\x07name", "--price", "1", "--currency", "xxx"},
+		{"update", "v1", "--name", "bad\x07name", "--currency", "xxx"},
+	} {
+		resetCLI()
+		st := store.NewInMemoryStore()
+		_ = st.Create(context.Background(), domain.Product{ID: "v1", Name: "A", Price: 1})
+		productStore = st
+		_, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+		var me *domain.MultiError
+		if !errors.As(err, &me) || len(me.Errors()) != 2 || !domain.IsInvalidProductError(err) {
+			t.Fatalf("%v: expected the name and currency problems together, got %v", args, err)
+		}
+		if !strings.Contains(err.Error(), "field=name") || !strings.Contains(err.Error(), "field=currency") {
+			t.Fatalf("%v: unexpected error %v", args, err)
+		}
+	}
+}
+
+func TestListLimitOffset(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	for i, price := range []domain.Money{300, 100, 200} {
+		_ = st.Create(ctx, domain.Product{ID: "o" + strconv.Itoa(i), Name: "O", Price: price, Quantity: 1})
+	}
+	productStore = st
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"list", "--sort-by", "price", "--offset", "1", "--limit", "1", "-q"})
+		return rootCmd.Execute()
+	})
+	if err != nil || out != "o2\n" {
+		t.Fatalf("expected the second cheapest product, got %q (%v)", out, err)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(path, []byte(`[{"id":"m1","name":"Old","price":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	productStore = store.NewInMemoryStore()
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"migrate", path})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "from format version 0 to 1") {
+		t.Fatalf("unexpected migrate output %q (%v)", out, err)
+	}
+
+	// the migrated store file imports like any catalog
+	resetCLI()
+	dst := store.NewInMemoryStore()
+	productStore = dst
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"import", "--file", path})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("import of a store file failed: %v", err)
+	}
+	if _, err := dst.Get(context.Background(), "m1"); err != nil {
+		t.Fatalf("expected m1 imported, got %v", err)
+	}
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	if key, err := parseEncryptionKey(""); err != nil || key != nil {
+		t.Fatalf("expected no key, got %v (%v)", key, err)
+	}
+	if key, err := parseEncryptionKey(strings.Repeat("ab", 32)); err != nil || len(key) != 32 {
+		t.Fatalf("expected a 32-byte key, got %d bytes (%v)", len(key), err)
+	}
+	for _, bad := range []string{"zz", strings.Repeat("ab", 20)} {
+		if _, err := parseEncryptionKey(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFindDuplicatesCommand(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "f1", Name: "Pen", Price: 100, Quantity: 1, Category: "Office"})
+	_ = st.Create(ctx, domain.Product{ID: "f2", Name: "Pen", Price: 100, Quantity: 1, Category: "Office"})
+	_ = st.Create(ctx, domain.Product{ID: "f3", Name: "Pen", Price: 100, Quantity: 1, Category: "Garden"})
+	productStore = st
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"find-duplicates", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("find-duplicates failed: %v", err)
+	}
+	var groups []domain.DuplicateGroup
+	if err := json.Unmarshal([]byte(out), &groups); err != nil {
+		t.Fatalf("bad json %q: %v", out, err)
+	}
+	if len(groups) != 1 || groups[0].Category != "Office" || strings.Join(groups[0].IDs, ",") != "f1,f2" {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+}
+
+func TestValueAlertCommand(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "v1", Name: "A", Price: 1000, Quantity: 5, Category: "Office"})
+	_ = st.Create(ctx, domain.Product{ID: "v2", Name: "B", Price: 500, Quantity: 2, Category: "Books"})
+
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	out, err := run("value-alert", "--above", "100")
+	if err != nil || !strings.Contains(out, "OK: total inventory value 60.00") {
+		t.Fatalf("expected OK, got %q (%v)", out, err)
+	}
+	if _, err := run("value-alert", "--above", "55"); err == nil || !strings.Contains(err.Error(), "60.00 is above 55.00") {
+		t.Fatalf("expected above alert, got %v", err)
+	}
+	if _, err := run("value-alert", "--below", "20", "--category", "Books"); err == nil || !strings.Contains(err.Error(), "10.00 is below 20.00") {
+		t.Fatalf("expected below alert, got %v", err)
+	}
+	if _, err := run("value-alert"); err == nil {
+		t.Fatal("expected an error without a threshold")
+	}
+}
+
+func TestPriceHistoryCommand(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStoreWithOptions(store.Options{TrackPriceHistory: true})
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "ph1", Name: "A", Price: 999, Quantity: 1})
+	productStore = st
+
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"update", "ph1", "--price", "12.50"})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	resetCLI()
+	productStore = st
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"price-history", "ph1"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "| 9.99 -> 12.50") {
+		t.Fatalf("unexpected price history %q (%v)", out, err)
+	}
+}
+
+func TestCreateIDFormat(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	productStore = st
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"create", "--name", "Ordered", "--price", "1", "--quantity", "1", "--id-format", "v7"})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	out, _ := st.List(context.Background(), domain.ListFilter{})
+	if len(out) != 1 || out[0].ID[14] != '7' {
+		t.Fatalf("expected one product with a v7 id, got %v", out)
+	}
+
+	resetCLI()
+	productStore = st
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"create", "--name", "Bad", "--price", "1", "--quantity", "1", "--id-format", "v9"})
+		return rootCmd.Execute()
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown id strategy") {
+		t.Fatalf("expected an id strategy error, got %v", err)
+	}
+}
+
+func TestCreateIDStrategyAndExplicitID(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	_ = st.Create(context.Background(), domain.Product{ID: "P-0041", Name: "Old", Price: 1, Quantity: 1})
+	run := func(args ...string) error {
+		resetCLI()
+		productStore = st
+		_, err := captureOutput(func() error {
+			rootCmd.SetArgs(append([]string{"create", "--name", "N", "--price", "1", "--quantity", "1"}, args...))
+			return rootCmd.Execute()
+		})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := run("--id-strategy", "sequence"); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	for _, id := range []string{"P-0042", "P-0043"} {
+		if _, err := st.Get(context.Background(), id); err != nil {
+			t.Fatalf("expected sequential id %s, got %v", id, err)
+		}
+	}
+
+	if err := run("--id", "ext-7"); err != nil {
+		t.Fatalf("create with --id failed: %v", err)
+	}
+	if _, err := st.Get(context.Background(), "ext-7"); err != nil {
+		t.Fatalf("expected the explicit id to be used, got %v", err)
+	}
+	if err := run("--id", "ext-7"); !domain.IsDuplicateProductError(err) {
+		t.Fatalf("expected a duplicate error for a taken id, got %v", err)
+	}
+	if err := run("--id", "ext-7", "--dry-run"); !domain.IsDuplicateProductError(err) {
+		t.Fatalf("expected --dry-run to report the taken id, got %v", err)
+	}
+	rootCmd.PersistentFlags().Set("dry-run", "false")
+	if err := run("--id", " "); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected a blank id to be invalid, got %v", err)
+	}
+
+	// a soft-deleted product still holds its id
+	st = store.NewInMemoryStoreWithOptions(store.Options{SoftDelete: true})
+	_ = st.Create(context.Background(), domain.Product{ID: "gone", Name: "Old", Price: 1, Quantity: 1})
+	_ = st.Delete(context.Background(), "gone")
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	if err := run("--id", "gone", "--dry-run"); !domain.IsDuplicateProductError(err) {
+		t.Fatalf("expected --dry-run to report the soft-deleted id as taken, got %v", err)
+	}
+}
+
+func TestQuietPrintsOnlyIDs(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	_ = st.Create(context.Background(), domain.Product{ID: "q1", Name: "A", Price: 1, Quantity: 1, Category: "Old"})
+	_ = st.Create(context.Background(), domain.Product{ID: "q2", Name: "B", Price: 1, Quantity: 1, Category: "New"})
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	out, err := run("list", "-q", "--category", "Old")
+	if err != nil || out != "q1\n" {
+		t.Fatalf("expected only q1, got %q, %v", out, err)
+	}
+	out, err = run("create", "--quiet", "--id", "q3", "--name", "C", "--price", "1", "--quantity", "1")
+	if err != nil || out != "q3\n" {
+		t.Fatalf("expected only the new id, got %q, %v", out, err)
+	}
+	if _, err := run("list", "-q", "--output", "json"); err == nil {
+		t.Fatalf("expected --quiet and --output to be mutually exclusive")
+	}
+}
+
+func TestCategoriesCommand(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "k1", Name: "A", Price: 1, Quantity: 1, Category: "Office"})
+	_ = productStore.Create(ctx, domain.Product{ID: "k2", Name: "B", Price: 1, Quantity: 1, Category: "Books"})
+	_ = productStore.Create(ctx, domain.Product{ID: "k3", Name: "C", Price: 1, Quantity: 1, Category: "Office"})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"categories"})
+		return rootCmd.Execute()
+	})
+	if err != nil || out != "Books | 1\nOffice | 2\n" {
+		t.Fatalf("unexpected categories output %q, %v", out, err)
+	}
+
+	out, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"categories", "--output", "json"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, `"category": "Office",`) || !strings.Contains(out, `"count": 2`) {
+		t.Fatalf("unexpected JSON output %q, %v", out, err)
+	}
+}
+
+func TestRenameCategoryCommand(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "m1", Name: "A", Price: 1, Quantity: 1, Category: "Office"})
+	_ = productStore.Create(ctx, domain.Product{ID: "m2", Name: "B", Price: 1, Quantity: 1, Category: "Office"})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"rename-category", "Office", "Stationery", "--dry-run"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "would move 2 product(s)") {
+		t.Fatalf("unexpected dry-run output %q, %v", out, err)
+	}
+	if p, _ := productStore.Get(ctx, "m1"); p.Category != "Office" {
+		t.Fatalf("dry-run changed category to %q", p.Category)
+	}
+
+	rootCmd.PersistentFlags().Set("dry-run", "false")
+	out, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"rename-category", "Office", "Stationery"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "moved 2 product(s)") {
+		t.Fatalf("unexpected output %q, %v", out, err)
+	}
+	if p, _ := productStore.Get(ctx, "m2"); p.Category != "Stationery" {
+		t.Fatalf("expected category Stationery, got %q", p.Category)
+	}
+}
+
+func TestAdjustPriceCommand(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "a1", Name: "Lamp", Price: 2000, Quantity: 1, Category: "Home"})
+	_ = st.Create(ctx, domain.Product{ID: "a2", Name: "Rug", Price: 125, Quantity: 1, Category: "Home"})
+	_ = st.Create(ctx, domain.Product{ID: "a3", Name: "Pen", Price: 100, Quantity: 1, Category: "Office"})
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	out, err := run("adjust-price", "--category", "Home", "--by-percent", "-10", "--dry-run")
+	if err != nil || !strings.Contains(out, "a1 | Lamp | $20.00 -> $18.00") || !strings.Contains(out, "would adjust 2 product(s) by -10%") {
+		t.Fatalf("unexpected dry-run output %q, %v", out, err)
+	}
+	if p, _ := st.Get(ctx, "a1"); p.Price != 2000 {
+		t.Fatalf("dry-run changed the price to %d", p.Price)
+	}
+
+	rootCmd.PersistentFlags().Set("dry-run", "false")
+	out, err = run("adjust-price", "--category", "Home", "--by-percent", "-10")
+	if err != nil || !strings.Contains(out, "adjusted 2 product(s) by -10%") {
+		t.Fatalf("unexpected output %q, %v", out, err)
+	}
+	for id, want := range map[string]domain.Money{"a1": 1800, "a2": 112, "a3": 100} {
+		if p, _ := st.Get(ctx, id); p.Price != want {
+			t.Fatalf("%s: expected price %d, got %d", id, want, p.Price)
+		}
+	}
+
+	if _, err := run("adjust-price", "--all", "--by-percent", "-150"); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected a negative-price adjustment to be rejected, got %v", err)
+	}
+	if _, err := run("adjust-price", "--by-percent", "5"); err == nil || !strings.Contains(err.Error(), "--all") {
+		t.Fatalf("expected a missing selector to be rejected, got %v", err)
+	}
+	if _, err := run("adjust-price", "--all"); err == nil || !strings.Contains(err.Error(), "by-percent") {
+		t.Fatalf("expected --by-percent to be required, got %v", err)
+	}
+}
+
+func TestCreateSequenceNotReissuedAfterPurge(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("store", "memory")
+	defer rootCmd.PersistentFlags().Set("store-file", "data/products.json")
+	defer rootCmd.PersistentFlags().Set("soft-delete", "false")
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	path := filepath.Join(t.TempDir(), "seq.json")
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		return captureOutput(func() error {
+			rootCmd.SetArgs(append([]string{"--store", "file", "--store-file", path, "--soft-delete"}, args...))
+			return rootCmd.Execute()
+		})
+	}
+	create := func(extra ...string) string {
+		t.Helper()
+		out, err := run(append([]string{"create", "--name", "N", "--price", "1", "--quantity", "1", "--id-strategy", "sequence", "--quiet"}, extra...)...)
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		return strings.TrimSpace(out)
+	}
+
+	if a, b := create(), create(); a != "P-0001" || b != "P-0002" {
+		t.Fatalf("expected P-0001 and P-0002, got %s and %s", a, b)
+	}
+	if _, err := run("delete", "P-0002"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := run("purge", "--force"); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	// a dry run shows the next id without using it up
+	if _, err := run("create", "--name", "N", "--price", "1", "--quantity", "1", "--id-strategy", "sequence", "--dry-run"); err != nil {
+		t.Fatalf("dry-run create failed: %v", err)
+	}
+	rootCmd.PersistentFlags().Set("dry-run", "false")
+	if id := create(); id != "P-0003" {
+		t.Fatalf("expected the purged P-0002 not to be reissued, got %s", id)
+	}
+}
+
+func TestCommandFlushesStore(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "products.json")
+	// a delay long enough that only the post-run flush can write the file
+	st, err := store.NewFileStoreWithOptions(path, store.Options{SaveDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	productStore = st
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"create", "--name", "Flushed", "--price", "1", "--quantity", "1"})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	reloaded, err := store.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	out, _ := reloaded.List(context.Background(), domain.ListFilter{})
+	if len(out) != 1 || out[0].Name != "Flushed" {
+		t.Fatalf("expected the create to be flushed to disk, got %v", out)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	defer resetCLI()
+	st := store.NewUndoStore(store.NewInMemoryStore())
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "u1", Name: "Widget", Price: 100, Quantity: 5})
+
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	if _, err := run("update", "u1", "--quantity", "0"); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	out, err := run("undo")
+	if err != nil || !strings.Contains(out, "undid update of u1") {
+		t.Fatalf("unexpected undo result %q, %v", out, err)
+	}
+	if p, _ := st.Get(ctx, "u1"); p.Quantity != 5 {
+		t.Fatalf("expected quantity 5 after undo, got %d", p.Quantity)
+	}
+	if _, err := run("undo"); !errors.Is(err, store.ErrNothingToUndo) {
+		t.Fatalf("expected nothing left to undo, got %v", err)
+	}
+}
+
+func TestDeleteConfirmName(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.SetIn(nil)
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "c1", Name: "Desk Lamp", Price: 1, Quantity: 1})
+
+	tests := []struct {
+		name    string
+		input   string
+		deleted bool
+	}{
+		{"bare y is not enough", "y\n", false},
+		{"wrong case", "desk lamp\n", false},
+		{"no input", "", false},
+		{"exact name", "Desk Lamp\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd.SetIn(strings.NewReader(tt.input))
+			out, err := captureOutput(func() error {
+				rootCmd.SetArgs([]string{"delete", "--confirm-name", "c1"})
+				return rootCmd.Execute()
+			})
+			if err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if !strings.Contains(out, `"Desk Lamp"`) {
+				t.Fatalf("expected prompt to show the product name, got %q", out)
+			}
+			_, err = productStore.Get(ctx, "c1")
+			if deleted := domain.IsProductNotFoundError(err); deleted != tt.deleted {
+				t.Fatalf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestDeleteByFilter(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "f1", Name: "Old A", Price: 1, Quantity: 1, Category: "Discontinued"})
+	_ = productStore.Create(ctx, domain.Product{ID: "f2", Name: "Old B", Price: 1, Quantity: 1, Category: "Discontinued"})
+	_ = productStore.Create(ctx, domain.Product{ID: "f3", Name: "Keep", Price: 1, Quantity: 1, Category: "Current"})
+	count := func() int {
+		all, _ := productStore.List(ctx, domain.ListFilter{})
+		return len(all)
+	}
+
+	run := func(args ...string) (string, error) {
+		resetFlags(rootCmd.Commands())
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	if _, err := run("delete"); err == nil {
+		t.Fatalf("expected delete without id or filter to be refused")
+	}
+	out, err := run("--dry-run", "delete", "--category", "Discontinued")
+	if err != nil || !strings.Contains(out, "would delete 2 product(s)") || count() != 3 {
+		t.Fatalf("unexpected dry-run result (err=%v, count=%d):\n%s", err, count(), out)
+	}
+	rootCmd.PersistentFlags().Set("dry-run", "false")
+
+	out, err = run("delete", "--category", "Discontinued", "--force")
+	if err != nil || !strings.Contains(out, "deleted 2 product(s)") {
+		t.Fatalf("delete by category failed (err=%v):\n%s", err, out)
+	}
+	if _, err := productStore.Get(ctx, "f3"); err != nil || count() != 1 {
+		t.Fatalf("expected only f3 to remain, count=%d err=%v", count(), err)
+	}
+}
+
+func TestUpdateByFilter(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "p1", Name: "A", Price: 1, Quantity: 1, Category: "Phones"})
+	_ = productStore.Create(ctx, domain.Product{ID: "p2", Name: "B", Price: 1, Quantity: 1, Category: "Laptops"})
+
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"update", "--set-category", "Mobile"})
+		return rootCmd.Execute()
+	})
+	if err == nil {
+		t.Fatalf("expected update without a selector to fail")
+	}
+
+	resetFlags(rootCmd.Commands())
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"update", "--category", "Phones", "--set-category", "Mobile", "--set-price", "2.50"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "updated 1 product(s)") {
+		t.Fatalf("update by filter failed (err=%v):\n%s", err, out)
+	}
+	p, _ := productStore.Get(ctx, "p1")
+	if p.Category != "Mobile" || p.Price != 250 {
+		t.Fatalf("unexpected product after update: %+v", p)
+	}
+	if p2, _ := productStore.Get(ctx, "p2"); p2.Category != "Laptops" {
+		t.Fatalf("non-matching product changed: %+v", p2)
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	_ = productStore.Create(context.Background(), domain.Product{ID: "o1", Name: "Saved", Price: 1, Quantity: 1})
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"get", []string{"get", "o1", "--output-file", filepath.Join(dir, "get.json")}},
+		{"list", []string{"list", "--output", "json", "--output-file", filepath.Join(dir, "list.json")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags(rootCmd.Commands())
+			out, err := captureOutput(func() error {
+				rootCmd.SetArgs(tt.args)
+				return rootCmd.Execute()
+			})
+			if err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+			if out != "" {
+				t.Fatalf("expected nothing on stdout, got %q", out)
+			}
+			b, err := os.ReadFile(tt.args[len(tt.args)-1])
+			if err != nil {
+				t.Fatalf("output file not written: %v", err)
+			}
+			if !strings.Contains(string(b), "\n  ") || !strings.Contains(string(b), `"Saved"`) {
+				t.Fatalf("expected indented JSON, got:\n%s", b)
+			}
+		})
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	if m, err := parseFileMode("0600"); err != nil || m != 0o600 {
+		t.Fatalf("expected 0600, got %v, %v", m, err)
+	}
+	for _, bad := range []string{"", "rw-r--r--", "0800", "01777"} {
+		if _, err := parseFileMode(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
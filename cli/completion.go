@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// writeCompletion writes the completion script for shell to w.
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(w, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(w)
+	case "fish":
+		return rootCmd.GenFishCompletion(w, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell %q", shell)
+}
+
+// completeProductIDs offers ids of stored products starting with toComplete,
+// described by the product name. Cobra does not run PersistentPreRunE for
+// completion requests, so the store is opened here when needed.
+func completeProductIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if err := setup(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	products, err := productStore.List(context.Background(), domain.ListFilter{SortBy: "name"})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var ids []string
+	for _, p := range products {
+		if strings.HasPrefix(p.ID, toComplete) {
+			ids = append(ids, p.ID+"\t"+p.Name)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCategories offers the distinct categories of stored products
+// starting with toComplete, for --category flags.
+func completeCategories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := setup(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	counts, err := productStore.Categories(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var out []string
+	for c := range counts {
+		if c != "" && strings.HasPrefix(c, toComplete) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
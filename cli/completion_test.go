@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	defer resetCLI()
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		out, err := captureOutput(func() error {
+			rootCmd.SetArgs([]string{"completion", shell})
+			return rootCmd.Execute()
+		})
+		if err != nil {
+			t.Fatalf("completion %s failed: %v", shell, err)
+		}
+		if !strings.Contains(out, "inventory-cli") {
+			t.Fatalf("completion %s: script does not mention the command", shell)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"completion", "tcsh"})
+	if _, err := captureOutput(rootCmd.Execute); err == nil {
+		t.Fatalf("expected error for unsupported shell")
+	}
+}
+
+func TestCompleteProductIDs(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "abc-1", Name: "Apple", Price: 1, Quantity: 1})
+	_ = productStore.Create(ctx, domain.Product{ID: "abd-2", Name: "Banana", Price: 1, Quantity: 1})
+	_ = productStore.Create(ctx, domain.Product{ID: "xyz-3", Name: "Cherry", Price: 1, Quantity: 1})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"__complete", "get", "ab"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("__complete failed: %v", err)
+	}
+	if !strings.Contains(out, "abc-1\tApple") || !strings.Contains(out, "abd-2\tBanana") || strings.Contains(out, "xyz-3") {
+		t.Fatalf("unexpected completions:\n%s", out)
+	}
+
+	// only the first argument is an id
+	ids, _ := completeProductIDs(nil, []string{"abc-1"}, "")
+	if len(ids) != 0 {
+		t.Fatalf("expected no completions after the id, got %v", ids)
+	}
+}
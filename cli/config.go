@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// configExts are the config file formats viper reads, in the order the
+// default search tries them
+var configExts = []string{"yaml", "yml", "json", "toml"}
+
+// workDirConfigExts are the formats looked for in the working directory.
+// JSON and TOML are left out there, where ./inventory.json is as likely to
+// be an exported catalog as a config file.
+var workDirConfigExts = []string{"yaml", "yml"}
+
+// defaultConfigPaths lists where findConfig looks when no config file is
+// given: inventory.yaml or .yml in the working directory, then
+// <user config dir>/inventory/config.<ext>, e.g. ~/.config/inventory on Linux.
+func defaultConfigPaths() []string {
+	var paths []string
+	for _, ext := range workDirConfigExts {
+		paths = append(paths, "inventory."+ext)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		for _, ext := range configExts {
+			paths = append(paths, filepath.Join(dir, "inventory", "config."+ext))
+		}
+	}
+	return paths
+}
+
+// findConfig returns the config file to read: path when it is set, else the
+// first of defaultConfigPaths that exists, else "" for none.
+func findConfig(path string) string {
+	if path != "" {
+		return path
+	}
+	for _, p := range defaultConfigPaths() {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// applyValidationConfig copies the "validation" section of the viper config
+// from v onto domain.Validation, keeping defaults for keys that are not set:
+//
+//	validation:
+//	  max-name-length: 120
+//	  price-precision: reject
+//	  allowed-categories: [Electronics, Books, Office]
+//	  max-attributes: 20
+//	  max-attribute-length: 256
+func applyValidationConfig(v *viper.Viper) error {
+	cfg := domain.DefaultValidationConfig()
+
+	if v.IsSet("validation.max-name-length") {
+		cfg.MaxNameLength = v.GetInt("validation.max-name-length")
+	}
+	if v.IsSet("validation.price-precision") {
+		switch p := domain.PricePrecision(v.GetString("validation.price-precision")); p {
+		case domain.PricePrecisionOff, domain.PricePrecisionReject, domain.PricePrecisionRound:
+			cfg.PricePrecision = p
+		default:
+			return fmt.Errorf("invalid validation.price-precision %q: want reject or round", p)
+		}
+	}
+	cfg.AllowedCategories = v.GetStringSlice("validation.allowed-categories")
+	if v.IsSet("validation.max-attributes") {
+		cfg.MaxAttributes = v.GetInt("validation.max-attributes")
+	}
+	if v.IsSet("validation.max-attribute-length") {
+		cfg.MaxAttributeLength = v.GetInt("validation.max-attribute-length")
+	}
+
+	domain.Validation = cfg
+	return nil
+}
+
+// sampleConfig is written by "config init"; keep it in sync with the keys
+// bound in commands.go and read by applyValidationConfig.
+const sampleConfig = `# inventory-cli configuration
+# Pass with --config <file> or INVENTORY_CONFIG, or save as ./inventory.yaml
+# or ~/.config/inventory/config.yaml to have it found. Every key can also be set
+# through an INVENTORY_-prefixed environment variable with dashes replaced by
+# underscores, e.g. INVENTORY_STORE_FILE, or in a file passed with --env-file.
+
+# Store backend: memory or file
+store: memory
+
+# Path of the JSON file used when store is "file"
+store-file: data/products.json
+# Octal permissions the store file is written with, e.g. "0600"
+store-file-mode: "0644"
+
+# Logging level: debug, info, warn or error
+log-level: info
+
+# Append logs to this file instead of stderr; set log-also-stderr to keep both
+log-file: ""
+log-also-stderr: false
+
+# Color text output: always, auto (only on a terminal without NO_COLOR) or never
+color: auto
+
+# Deadline for store operations, e.g. 30s; 0 means no deadline
+timeout: 0s
+
+# Mark deleted products instead of removing them (see restore and purge)
+soft-delete: false
+
+# Fail when the store file cannot be parsed. With false, a corrupt file is
+# renamed to <store-file>.corrupt and the store recovers from the temp file
+# of an interrupted save, or starts empty
+strict-load: true
+
+# fsync the store file and its directory on every save so a completed write
+# survives a power loss; slower
+durable: false
+
+# Record every price change for the price-history command; the file store
+# keeps them in <store-file>.prices.json
+track-price-history: false
+
+# Append each change to <store-file>.journal instead of rewriting the whole
+# file; the journal is folded into the file every 1000 records
+journal: false
+
+# Reload the store file when another process changes it, e.g. to keep a
+# long-running shell in step; unsaved local changes win over a conflicting
+# change on disk
+watch-file: false
+
+# The file store can be encrypted at rest with --store-encryption-key or,
+# better, $INVENTORY_STORE_ENCRYPTION_KEY: a hex AES key such as the output of
+# openssl rand -hex 32. Keep the key out of this file.
+
+# Make get fail (exit code 2) for an unknown id, as every other command does;
+# by default get reports it on stderr and exits 0
+strict: false
+
+# Snapshot the store into this directory before bulk deletes, purges and
+# replacing restores; empty disables snapshots
+backup-dir: ""
+
+validation:
+  # Maximum number of characters in a product name; 0 disables the check
+  max-name-length: 200
+  # Prices with more than two decimals: round (half-to-even) or reject
+  price-precision: round
+  # Accepted categories; an empty list accepts any category
+  allowed-categories: []
+  # Maximum number of attributes per product; 0 disables the check
+  max-attributes: 20
+  # Maximum characters in an attribute name or value; 0 disables the check
+  max-attribute-length: 256
+`
+
+// writeSampleConfig writes sampleConfig to path, refusing to replace an
+// existing file unless force is set.
+func writeSampleConfig(path string, force bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+		return err
+	}
+	if _, err := f.WriteString(sampleConfig); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadEnvFile sets the KEY=VALUE lines of the file at path as environment
+// variables, so they reach viper exactly as real ones would. Variables that
+// are already set win over the file. Blank lines and lines starting with #
+// are skipped, an "export " prefix is allowed, and a value may be wrapped in
+// single quotes (taken literally) or double quotes (Go escapes apply).
+func loadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: want KEY=VALUE", path, n)
+		}
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			if value, err = strconv.Unquote(value); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestApplyValidationConfig(t *testing.T) {
+	defer func(old domain.ValidationConfig) { domain.Validation = old }(domain.Validation)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "validation:\n  max-name-length: 50\n  price-precision: reject\n  allowed-categories: [Electronics, Books]\n  max-attributes: 2\n  max-attribute-length: 10\n"
+	if err := os.WriteFile(cfgPath, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigFile(cfgPath)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyValidationConfig(v); err != nil {
+		t.Fatalf("applyValidationConfig failed: %v", err)
+	}
+	got := domain.Validation
+	if got.MaxNameLength != 50 || got.PricePrecision != domain.PricePrecisionReject ||
+		!slices.Equal(got.AllowedCategories, []string{"Electronics", "Books"}) ||
+		got.MaxAttributes != 2 || got.MaxAttributeLength != 10 {
+		t.Fatalf("unexpected validation config: %+v", got)
+	}
+	if err := domain.ValidateProduct(domain.Product{Name: "x", Category: "Electronis"}); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected category outside whitelist to be rejected, got %v", err)
+	}
+}
+
+func TestApplyValidationConfig_InvalidPrecision(t *testing.T) {
+	defer func(old domain.ValidationConfig) { domain.Validation = old }(domain.Validation)
+
+	v := viper.New()
+	v.Set("validation.price-precision", "truncate")
+	if err := applyValidationConfig(v); err == nil {
+		t.Fatalf("expected error for unknown price precision")
+	}
+}
+
+func TestConfigInit(t *testing.T) {
+	defer resetCLI()
+	defer func(old domain.ValidationConfig) { domain.Validation = old }(domain.Validation)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"config", "init", "--output", path})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("generated config does not parse: %v", err)
+	}
+	if v.GetString("store") != "memory" || v.GetString("store-file") != "data/products.json" || v.GetString("log-level") != "info" {
+		t.Fatalf("unexpected defaults in generated config: %v", v.AllSettings())
+	}
+	if err := applyValidationConfig(v); err != nil {
+		t.Fatalf("generated validation section rejected: %v", err)
+	}
+
+	resetCLI()
+	_, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"config", "init", "--output", path})
+		return rootCmd.Execute()
+	})
+	if err == nil {
+		t.Fatalf("expected refusal to overwrite existing file")
+	}
+
+	resetCLI()
+	_, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"config", "init", "--output", path, "--force"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("config init --force failed: %v", err)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	if w, err := logWriter("", false); err != nil || w != os.Stderr {
+		t.Fatalf("expected stderr by default, got %v (%v)", w, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "inventory.log")
+	for _, line := range []string{"first\n", "second\n"} {
+		w, err := logWriter(path, false)
+		if err != nil {
+			t.Fatalf("logWriter failed: %v", err)
+		}
+		_, _ = io.WriteString(w, line)
+		w.(io.Closer).Close()
+	}
+	b, _ := os.ReadFile(path)
+	if string(b) != "first\nsecond\n" {
+		t.Fatalf("expected appended log lines, got %q", b)
+	}
+
+	if _, err := logWriter(filepath.Join(t.TempDir(), "missing", "x.log"), false); err == nil {
+		t.Fatalf("expected error for unwritable log path")
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	keys := []string{"INVENTORY_T_PLAIN", "INVENTORY_T_EXPORTED", "INVENTORY_T_SINGLE", "INVENTORY_T_DOUBLE", "INVENTORY_T_SET"}
+	for _, k := range keys {
+		t.Cleanup(func() { os.Unsetenv(k) })
+	}
+	t.Setenv("INVENTORY_T_SET", "from env")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# deployment settings
+INVENTORY_T_PLAIN=plain value
+
+export INVENTORY_T_EXPORTED = exported
+INVENTORY_T_SINGLE='a "literal" \n'
+INVENTORY_T_DOUBLE="tab\there"
+INVENTORY_T_SET=from file
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadEnvFile(path); err != nil {
+		t.Fatalf("loadEnvFile failed: %v", err)
+	}
+	want := map[string]string{
+		"INVENTORY_T_PLAIN":    "plain value",
+		"INVENTORY_T_EXPORTED": "exported",
+		"INVENTORY_T_SINGLE":   `a "literal" \n`,
+		"INVENTORY_T_DOUBLE":   "tab\there",
+		"INVENTORY_T_SET":      "from env",
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.env")
+	_ = os.WriteFile(bad, []byte("INVENTORY_T_PLAIN=x\nnot a pair\n"), 0o644)
+	if err := loadEnvFile(bad); err == nil || !strings.Contains(err.Error(), "bad.env:2") {
+		t.Fatalf("expected an error naming line 2, got %v", err)
+	}
+	if err := loadEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestEnvFilePrecedence(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("env-file", "")
+	defer rootCmd.PersistentFlags().Set("store", "memory")
+	t.Cleanup(func() { os.Unsetenv("INVENTORY_STORE") })
+	path := filepath.Join(t.TempDir(), ".env")
+	_ = os.WriteFile(path, []byte("INVENTORY_STORE=bogus\n"), 0o644)
+
+	resetCLI()
+	rootCmd.SetArgs([]string{"list", "--env-file", path})
+	if _, err := captureOutput(rootCmd.Execute); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Fatalf("expected the env file to select the store, got %v", err)
+	}
+
+	resetCLI()
+	rootCmd.SetArgs([]string{"list", "--env-file", path, "--store", "memory"})
+	if _, err := captureOutput(rootCmd.Execute); err != nil {
+		t.Fatalf("expected --store to win over the env file, got %v", err)
+	}
+
+	// dashed keys are read from underscored names
+	os.Unsetenv("INVENTORY_STORE")
+	t.Cleanup(func() { os.Unsetenv("INVENTORY_MAX_PRODUCTS") })
+	_ = os.WriteFile(path, []byte("INVENTORY_MAX_PRODUCTS=-1\n"), 0o644)
+	resetCLI()
+	rootCmd.SetArgs([]string{"list", "--env-file", path})
+	if _, err := captureOutput(rootCmd.Execute); err == nil || !strings.Contains(err.Error(), "max-products") {
+		t.Fatalf("expected INVENTORY_MAX_PRODUCTS to reach --max-products, got %v", err)
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestFindConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	chdir(t, t.TempDir())
+
+	if got := findConfig(""); got != "" {
+		t.Fatalf("expected no config, got %q", got)
+	}
+	if got := findConfig("explicit.json"); got != "explicit.json" {
+		t.Fatalf("expected an explicit path to be used as is, got %q", got)
+	}
+
+	userCfg := filepath.Join(home, ".config", "inventory", "config.toml")
+	_ = os.MkdirAll(filepath.Dir(userCfg), 0o755)
+	_ = os.WriteFile(userCfg, []byte("store = \"memory\"\n"), 0o644)
+	if got := findConfig(""); got != userCfg {
+		t.Fatalf("expected the user config %s, got %q", userCfg, got)
+	}
+
+	// a catalog saved in the working directory is not config
+	_ = os.WriteFile("inventory.json", []byte(`[{"id": "p1"}]`), 0o644)
+	_ = os.WriteFile("inventory.toml", []byte("store = \"memory\"\n"), 0o644)
+	if got := findConfig(""); got != userCfg {
+		t.Fatalf("expected ./inventory.json and .toml to be skipped, got %q", got)
+	}
+	_ = os.WriteFile("inventory.yml", []byte("store: memory\n"), 0o644)
+	if got := findConfig(""); got != "inventory.yml" {
+		t.Fatalf("expected the working directory to win, got %q", got)
+	}
+	_ = os.WriteFile("inventory.yaml", []byte("store: memory\n"), 0o644)
+	if got := findConfig(""); got != "inventory.yaml" {
+		t.Fatalf("expected .yaml to be tried before .yml, got %q", got)
+	}
+}
+
+func TestSetupFindsTOMLConfig(t *testing.T) {
+	defer resetCLI()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	chdir(t, t.TempDir())
+	userCfg := filepath.Join(home, ".config", "inventory", "config.toml")
+	_ = os.MkdirAll(filepath.Dir(userCfg), 0o755)
+
+	_ = os.WriteFile(userCfg, []byte("store = \"bogus\"\n"), 0o644)
+	resetCLI()
+	rootCmd.SetArgs([]string{"list"})
+	if _, err := captureOutput(rootCmd.Execute); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Fatalf("expected config.toml to select the store, got %v", err)
+	}
+
+	// leave the global viper with a harmless config for later tests
+	_ = os.WriteFile(userCfg, []byte("store = \"memory\"\n"), 0o644)
+	resetCLI()
+	rootCmd.SetArgs([]string{"list"})
+	if _, err := captureOutput(rootCmd.Execute); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+}
+
+func TestStoreDSN(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("store", "memory")
+	path := filepath.Join(t.TempDir(), "dsn.json")
+
+	resetCLI()
+	rootCmd.SetArgs([]string{"create", "--name", "Via DSN", "--store", "file://" + path, "--store-file", "ignored.json"})
+	if _, err := captureOutput(rootCmd.Execute); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	_ = domain.CloseStore(context.Background(), productStore)
+	b, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(b), "Via DSN") {
+		t.Fatalf("expected the product in the DSN's file, got %q (%v)", b, err)
+	}
+	if _, err := os.Stat("ignored.json"); err == nil {
+		os.Remove("ignored.json")
+		t.Fatalf("expected --store-file to be ignored when --store is a DSN")
+	}
+
+	resetCLI()
+	rootCmd.SetArgs([]string{"list", "--store", "redis://localhost:6379/0"})
+	if _, err := captureOutput(rootCmd.Execute); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Fatalf("expected redis to be reported as unavailable, got %v", err)
+	}
+}
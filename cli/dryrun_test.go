@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRunSkipsWrites(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "d1", Name: "Keep", Price: 100, Quantity: 1})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"create", []string{"--dry-run", "create", "--name", "New"}, "dry-run: would create"},
+		{"update", []string{"--dry-run", "update", "d1", "--quantity", "9"}, "dry-run: would update d1"},
+		{"delete", []string{"--dry-run", "delete", "d1"}, "dry-run: would delete d1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureOutput(func() error {
+				rootCmd.SetArgs(tt.args)
+				return rootCmd.Execute()
+			})
+			if err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Fatalf("expected %q in output, got:\n%s", tt.want, out)
+			}
+		})
+	}
+
+	all, _ := productStore.List(ctx, domain.ListFilter{})
+	if len(all) != 1 || all[0].Quantity != 1 {
+		t.Fatalf("dry-run modified the store: %+v", all)
+	}
+}
+
+func TestDryRunCreateValidates(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	productStore = store.NewInMemoryStore()
+
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"--dry-run", "create", "--name", "Bad", "--quantity", "-1"})
+		return rootCmd.Execute()
+	})
+	if !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestDryRunImport(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("dry-run", "false")
+	productStore = store.NewInMemoryStore()
+	_ = productStore.Create(context.Background(), domain.Product{ID: "i1", Name: "Existing", Price: 100, Quantity: 1})
+
+	path := filepath.Join(t.TempDir(), "import.json")
+	data := `[
+		{"id": "i1", "name": "Existing", "price": 1, "quantity": 1},
+		{"id": "i2", "name": "New", "price": 1, "quantity": 1},
+		{"id": "i2", "name": "New again", "price": 1, "quantity": 1},
+		{"id": "i3", "name": "New too", "price": 1, "quantity": 1}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"--dry-run", "import", "--file", path})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "would import 2 product(s)") || !strings.Contains(out, "2 duplicate(s): i1, i2") {
+		t.Fatalf("unexpected dry-run report:\n%s", out)
+	}
+	all, _ := productStore.List(context.Background(), domain.ListFilter{})
+	if len(all) != 1 {
+		t.Fatalf("dry-run import wrote to the store: %+v", all)
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// splitByCategory is the only --split-by value export understands
+const splitByCategory = "category"
+
+// writeZipExport writes products into a ZIP archive at path: one
+// products.json, or with splitBy "category" one JSON file per category.
+// The archive is built next to path and renamed into place.
+func writeZipExport(path string, products []domain.Product, splitBy string) error {
+	files := map[string][]domain.Product{"products.json": products}
+	if splitBy == splitByCategory {
+		files = splitCategoryFiles(products)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		b, _ := json.MarshalIndent(files[name], "", "  ")
+		if _, err := w.Write(b); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// splitCategoryFiles groups products into one file name per category.
+// Names are made safe for an archive entry; products without a category go
+// to uncategorized.json, and names that still collide get a numeric suffix.
+func splitCategoryFiles(products []domain.Product) map[string][]domain.Product {
+	byCategory := make(map[string][]domain.Product)
+	for _, p := range products {
+		byCategory[p.Category] = append(byCategory[p.Category], p)
+	}
+	categories := make([]string, 0, len(byCategory))
+	for c := range byCategory {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+
+	files := make(map[string][]domain.Product, len(byCategory))
+	for _, c := range categories {
+		base := categoryFileBase(c)
+		name := base + ".json"
+		for n := 2; files[name] != nil; n++ {
+			name = fmt.Sprintf("%s-%d.json", base, n)
+		}
+		files[name] = byCategory[c]
+	}
+	return files
+}
+
+// categoryFileBase turns a category into a file name without extension
+func categoryFileBase(category string) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_', r == '.':
+			return r
+		case r == ' ':
+			return '_'
+		}
+		return -1
+	}, strings.TrimSpace(category))
+	base = strings.Trim(base, ".")
+	if base == "" {
+		return "uncategorized"
+	}
+	return base
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"aexp_assesment/util"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// readZipExport returns the products in each file of the archive at path
+func readZipExport(t *testing.T, path string) map[string][]domain.Product {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer r.Close()
+	out := make(map[string][]domain.Product)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		var ps []domain.Product
+		if err := json.Unmarshal(b, &ps); err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		out[f.Name] = ps
+	}
+	return out
+}
+
+func TestExportZip(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "z1", Name: "Pen", Price: 100, Quantity: 1, Category: "Office Supplies"})
+	_ = st.Create(ctx, domain.Product{ID: "z2", Name: "Desk", Price: 9000, Quantity: 1, Category: "Office Supplies"})
+	_ = st.Create(ctx, domain.Product{ID: "z3", Name: "Rake", Price: 2000, Quantity: 1, Category: "Garden"})
+	_ = st.Create(ctx, domain.Product{ID: "z4", Name: "Misc", Price: 50, Quantity: 1})
+	dir := t.TempDir()
+
+	run := func(args ...string) error {
+		resetCLI()
+		productStore = st
+		_, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+		return err
+	}
+
+	single := filepath.Join(dir, "all.zip")
+	if err := run("export", "--zip", single, "--max-price", "0.50"); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	files := readZipExport(t, single)
+	if len(files) != 1 || len(files["products.json"]) != 1 || files["products.json"][0].ID != "z4" {
+		t.Fatalf("unexpected archive %+v", files)
+	}
+
+	split := filepath.Join(dir, "split.zip")
+	if err := run("export", "--zip", split, "--split-by", "category"); err != nil {
+		t.Fatalf("split export failed: %v", err)
+	}
+	files = readZipExport(t, split)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"Garden.json", "Office_Supplies.json", "uncategorized.json"}; !slices.Equal(names, want) {
+		t.Fatalf("expected files %v, got %v", want, names)
+	}
+	if len(files["Office_Supplies.json"]) != 2 {
+		t.Fatalf("expected both office products together, got %+v", files["Office_Supplies.json"])
+	}
+
+	if err := run("export", "--split-by", "category", "--file", filepath.Join(dir, "x.json")); err == nil {
+		t.Fatal("expected --split-by without --zip to fail")
+	}
+	if err := run("export", "--zip", split, "--split-by", "tag"); err == nil {
+		t.Fatal("expected an unknown --split-by to fail")
+	}
+}
+
+func TestExportGzipImportsBack(t *testing.T) {
+	defer resetCLI()
+	src := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = src.Create(ctx, domain.Product{ID: "g1", Name: "Pen", Price: 100, Quantity: 2})
+	_ = src.Create(ctx, domain.Product{ID: "g2", Name: "Desk", Price: 9000, Quantity: 1})
+	path := filepath.Join(t.TempDir(), "products.json.gz")
+
+	productStore = src
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"export", "--file", path, "--gzip"})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if b, _ := os.ReadFile(path); !util.IsGzip(b) {
+		t.Fatalf("expected gzip output, got %q", b)
+	}
+
+	resetCLI()
+	dst := store.NewInMemoryStore()
+	productStore = dst
+	if _, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"import", "--file", path})
+		return rootCmd.Execute()
+	}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if out, _ := dst.List(ctx, domain.ListFilter{}); len(out) != 2 {
+		t.Fatalf("expected both products imported, got %v", out)
+	}
+}
+
+func TestSplitCategoryFiles_Collisions(t *testing.T) {
+	files := splitCategoryFiles([]domain.Product{
+		{ID: "a", Category: "A/B"},
+		{ID: "b", Category: "AB"},
+		{ID: "c", Category: "../"},
+		{ID: "d", Category: ""},
+	})
+	for _, name := range []string{"AB.json", "AB-2.json", "uncategorized.json", "uncategorized-2.json"} {
+		if len(files[name]) != 1 {
+			t.Fatalf("expected %s to hold one product, got %v", name, files)
+		}
+	}
+}
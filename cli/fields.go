@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// productFields lists the JSON names of domain.Product's fields in
+// declaration order, so --fields follows the struct tags automatically,
+// followed by the derived "value" (price * quantity).
+func productFields() []string {
+	t := reflect.TypeOf(domain.Product{})
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return append(names, "value")
+}
+
+// checkFields rejects names that are not product fields
+func checkFields(fields []string) error {
+	valid := productFields()
+	for _, f := range fields {
+		if !slices.Contains(valid, f) {
+			return fmt.Errorf("unknown field %q; valid fields: %s", f, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// project keeps only fields of p's JSON form, plus the derived value. Fields
+// omitted as empty stay absent.
+func project(p domain.Product, fields []string) map[string]json.RawMessage {
+	b, _ := json.Marshal(p)
+	var all map[string]json.RawMessage
+	_ = json.Unmarshal(b, &all)
+	out := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if f == "value" {
+			out[f], _ = json.Marshal(p.Value())
+		} else if v, ok := all[f]; ok {
+			out[f] = v
+		}
+	}
+	return out
+}
+
+// projectAll projects every product in out
+func projectAll(out []domain.Product, fields []string) []map[string]json.RawMessage {
+	rows := make([]map[string]json.RawMessage, len(out))
+	for i, p := range out {
+		rows[i] = project(p, fields)
+	}
+	return rows
+}
+
+// printProjected is printProducts limited to fields: a JSON array of
+// objects for "json", otherwise the values pipe-separated in field order.
+func printProjected(w io.Writer, out []domain.Product, fields []string, format string) {
+	rows := projectAll(out, fields)
+	if format == "json" {
+		b, _ := json.MarshalIndent(rows, "", "  ")
+		fmt.Fprintln(w, string(b))
+		return
+	}
+	for _, row := range rows {
+		vals := make([]string, len(fields))
+		for i, f := range fields {
+			vals[i] = fieldText(row[f])
+		}
+		fmt.Fprintln(w, strings.Join(vals, " | "))
+	}
+}
+
+// fieldText renders one JSON value for text output: strings unquoted,
+// missing values empty and everything else as JSON.
+func fieldText(v json.RawMessage) string {
+	if v == nil {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
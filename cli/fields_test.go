@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFieldsProjection(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = st.Create(ctx, domain.Product{ID: "f1", Name: "Pen", Price: 150, Quantity: 3, Category: "Office"})
+	run := func(args ...string) (string, error) {
+		resetCLI()
+		productStore = st
+		return captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+	}
+
+	out, err := run("get", "f1", "--fields", "id,price")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if len(got) != 2 || got["id"] != "f1" || got["price"] == nil {
+		t.Fatalf("expected only id and price, got %v", got)
+	}
+
+	out, err = run("list", "--fields", "name,category,quantity")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if strings.TrimSpace(out) != "Pen | Office | 3" {
+		t.Fatalf("unexpected projected list: %q", out)
+	}
+
+	out, err = run("list", "--fields", "id,value")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if strings.TrimSpace(out) != "f1 | 4.5" {
+		t.Fatalf("expected the derived value, got %q", out)
+	}
+
+	out, err = run("list")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if strings.TrimSpace(out) != "f1 | Pen | $1.50 | 3 | $4.50 | Office" {
+		t.Fatalf("expected a value column, got %q", out)
+	}
+
+	out, err = run("list", "--fields", "id", "--output", "json")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil || len(rows) != 1 || len(rows[0]) != 1 {
+		t.Fatalf("expected one object with only id, got %q (%v)", out, err)
+	}
+
+	_, err = run("list", "--fields", "id,colour")
+	if err == nil || !strings.Contains(err.Error(), `"colour"`) || !strings.Contains(err.Error(), "reorder_level") {
+		t.Fatalf("expected an unknown field error listing valid fields, got %v", err)
+	}
+}
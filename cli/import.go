@@ -0,0 +1,744 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/schema"
+	"aexp_assesment/util"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Import formats accepted by import --format
+const (
+	formatJSON   = "json"   // a JSON array, a single object or a store file
+	formatNDJSON = "ndjson" // a stream of JSON objects, usually one per line
+	formatCSV    = "csv"    // a header row naming product fields
+	formatYAML   = "yaml"   // a list of products or a single product
+)
+
+// importFormats lists the import formats in the order help text gives them
+var importFormats = []string{formatJSON, formatNDJSON, formatCSV, formatYAML}
+
+// csvListSep separates the tags, and the key=value attributes, of one CSV cell
+const csvListSep = ";"
+
+// decodeRecords splits import data in format into raw JSON records, one per
+// product. With format "" the format is detected from the content. Data of
+// any format may be gzip-compressed.
+func decodeRecords(b []byte, format string) ([]json.RawMessage, error) {
+	b, err := util.MaybeGunzip(b)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, errors.New("empty file")
+	}
+	if format == "" {
+		if format, err = detectFormat(b); err != nil {
+			return nil, err
+		}
+	}
+	switch format {
+	case formatJSON:
+		return decodeJSONRecords(b)
+	case formatNDJSON:
+		return decodeNDJSONRecords(b)
+	case formatCSV:
+		return decodeCSVRecords(b)
+	case formatYAML:
+		return decodeYAMLRecords(b)
+	}
+	return nil, fmt.Errorf("unknown import format %q (want %s)", format, strings.Join(importFormats, ", "))
+}
+
+// detectFormat guesses the format of b, already decompressed. Content that
+// is one JSON value is JSON, so a single object spread over several lines
+// is read whole; other content starting with '{' is NDJSON. A
+// first line starting a YAML document or list, or holding "key:", is YAML,
+// and one holding a comma is a CSV header.
+func detectFormat(b []byte) (string, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return "", errors.New("empty file")
+	}
+	switch b[0] {
+	case '[':
+		return formatJSON, nil
+	case '{':
+		if json.Valid(b) {
+			return formatJSON, nil
+		}
+		return formatNDJSON, nil
+	}
+	first, _, _ := bytes.Cut(b, []byte("\n"))
+	first = bytes.TrimSpace(first)
+	switch {
+	case bytes.HasPrefix(first, []byte("---")) || bytes.HasPrefix(first, []byte("- ")) || bytes.Equal(first, []byte("-")):
+		return formatYAML, nil
+	case bytes.Contains(first, []byte(":")):
+		return formatYAML, nil
+	case bytes.Contains(first, []byte(",")):
+		return formatCSV, nil
+	}
+	return "", errors.New("cannot detect the import format; pass --format")
+}
+
+// decodeJSONRecords reads a JSON array, a single JSON object, or a file
+// store envelope
+func decodeJSONRecords(b []byte) ([]json.RawMessage, error) {
+	if b[0] == '{' {
+		if products, ok := storeEnvelope(b); ok {
+			return products, nil
+		}
+		var rec json.RawMessage
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, err
+		}
+		return []json.RawMessage{rec}, nil
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// storeEnvelope returns the products of b when it is a file store envelope
+// as written by the file store
+func storeEnvelope(b []byte) ([]json.RawMessage, bool) {
+	var env struct {
+		Version  *int              `json:"version"`
+		Products []json.RawMessage `json:"products"`
+	}
+	if json.Unmarshal(b, &env) != nil || env.Version == nil || env.Products == nil {
+		return nil, false
+	}
+	return env.Products, true
+}
+
+// decodeNDJSONRecords reads a stream of JSON values. They are decoded one
+// after another rather than line by line, so a record may span several
+// lines, as pretty-printed objects do.
+func decodeNDJSONRecords(b []byte) ([]json.RawMessage, error) {
+	var records []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var rec json.RawMessage
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records), err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// decodeCSVRecords reads a header row of product JSON field names followed
+// by one product per row. Empty cells are left out; tags and attributes
+// (key=value) are separated by csvListSep. Numbers that do not parse are
+// passed on as strings for schema validation to report.
+func decodeCSVRecords(b []byte) ([]json.RawMessage, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	header := rows[0]
+	for i, col := range header {
+		header[i] = strings.TrimSpace(col)
+		switch header[i] {
+		case "id", "name", "price", "quantity", "category", "reorder_level", "tags", "currency", "attributes":
+		default:
+			return nil, fmt.Errorf("csv: unknown column %q", header[i])
+		}
+	}
+
+	records := make([]json.RawMessage, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		rec := make(map[string]interface{}, len(header))
+		for i, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			switch col := header[i]; col {
+			case "price":
+				rec[col] = csvNumber(cell, func(s string) error { _, err := strconv.ParseFloat(s, 64); return err })
+			case "quantity", "reorder_level":
+				rec[col] = csvNumber(cell, func(s string) error { _, err := strconv.Atoi(s); return err })
+			case "tags":
+				rec[col] = splitList(cell)
+			case "attributes":
+				attrs := make(map[string]string)
+				for _, kv := range splitList(cell) {
+					k, v, _ := strings.Cut(kv, "=")
+					attrs[strings.TrimSpace(k)] = strings.TrimSpace(v)
+				}
+				rec[col] = attrs
+			default:
+				rec[col] = cell
+			}
+		}
+		out, err := json.Marshal(rec)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, out)
+	}
+	return records, nil
+}
+
+// csvNumber returns cell as a JSON number when it is written as one and
+// parse accepts it, else as is, so the schema reports the bad cell
+func csvNumber(cell string, parse func(string) error) interface{} {
+	if !isJSONNumber(cell) || parse(cell) != nil {
+		return cell
+	}
+	return json.Number(cell)
+}
+
+// isJSONNumber reports whether s is a number in JSON syntax. ParseFloat and
+// Atoi also take forms such as "NaN", "0x1p4" and "+3" that json.Marshal
+// rejects, and json.Valid alone allows surrounding spaces.
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) || s[len(s)-1] < '0' || s[len(s)-1] > '9' {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
+
+// splitList splits a CSV cell on csvListSep, dropping empty entries
+func splitList(cell string) []string {
+	var out []string
+	for _, s := range strings.Split(cell, csvListSep) {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// decodeYAMLRecords reads a YAML list of products or a single product
+func decodeYAMLRecords(b []byte) ([]json.RawMessage, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+	items, ok := doc.([]interface{})
+	if !ok {
+		items = []interface{}{doc}
+	}
+	records := make([]json.RawMessage, 0, len(items))
+	for i, item := range items {
+		out, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: record %d: %w", i, err)
+		}
+		records = append(records, out)
+	}
+	return records, nil
+}
+
+// loadSchema returns the schema at path, or the embedded product schema when
+// path is empty.
+func loadSchema(path string) (*schema.Schema, error) {
+	if path == "" {
+		return schema.DefaultProduct(), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Parse(b)
+}
+
+// eachRecord calls fn for every record of the import data read from r, in
+// format, or detected from the content when format is "". A JSON array and
+// a stream of JSON objects (NDJSON) are decoded one record at a time, so
+// neither the input nor its records are ever held in memory at once; other
+// content is read whole and goes through decodeRecords. Input of any format
+// may be gzip-compressed.
+func eachRecord(r io.Reader, format string, fn func(json.RawMessage) error) error {
+	zr, err := util.MaybeGunzipReader(r)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return errors.New("empty file")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case format == formatNDJSON, (format == "" || format == formatJSON) && first == '{':
+		return eachJSONValue(br, fn)
+	case (format == "" || format == formatJSON) && first == '[':
+		return eachArrayElement(br, fn)
+	}
+	b, err := io.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	records, err := decodeRecords(b, format)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstNonSpace returns the first byte of br that is not white space and
+// leaves it unread
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return c, br.UnreadByte()
+		}
+	}
+}
+
+// eachArrayElement calls fn for every element of the JSON array read from r
+func eachArrayElement(r io.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for i := 0; dec.More(); i++ {
+		var rec json.RawMessage
+		if err := dec.Decode(&rec); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON array")
+	}
+	return nil
+}
+
+// eachJSONValue calls fn for every JSON value read from r, one after
+// another. A first value that is a file store envelope stands for its
+// products.
+func eachJSONValue(r io.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	for i := 0; ; i++ {
+		var rec json.RawMessage
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		if i == 0 && !dec.More() {
+			if products, ok := storeEnvelope(rec); ok {
+				for _, p := range products {
+					if err := fn(p); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeProducts reads import data from r with eachRecord, checks every
+// record against the schema s and decodes it. All schema violations are
+// reported together, prefixed with the zero-based index of the offending
+// record; once one is found, later records are only checked.
+func decodeProducts(r io.Reader, format string, s *schema.Schema) ([]domain.Product, error) {
+	var products []domain.Product
+	var problems []string
+	i := 0
+	err := eachRecord(r, format, func(rec json.RawMessage) error {
+		defer func() { i++ }()
+		for _, fe := range s.ValidateJSON(rec) {
+			problems = append(problems, fmt.Sprintf("record %d: %s", i, fe.Error()))
+		}
+		if len(problems) > 0 {
+			return nil
+		}
+		var p domain.Product
+		if err := json.Unmarshal(rec, &p); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+		products = append(products, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("schema validation failed: %s", strings.Join(problems, "; "))
+	}
+	return products, nil
+}
+
+// recordsToProducts decodes validated records into products
+func recordsToProducts(records []json.RawMessage) ([]domain.Product, error) {
+	products := make([]domain.Product, 0, len(records))
+	for i, rec := range records {
+		var p domain.Product
+		if err := json.Unmarshal(rec, &p); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+// openImportInput opens what import reads: the body of an http(s) URL, the
+// named file, or stdin when file is "-" or is empty and stdin is not a
+// terminal. Closing the result does not close stdin.
+func openImportInput(ctx context.Context, stdin io.Reader, file string) (io.ReadCloser, error) {
+	switch {
+	case strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://"):
+		return openURL(ctx, file)
+	case file == "-":
+		return io.NopCloser(stdin), nil
+	case file != "":
+		return os.Open(file)
+	case isPiped(stdin):
+		return io.NopCloser(stdin), nil
+	}
+	return nil, errors.New("--file required (or pipe products on stdin)")
+}
+
+// openURL GETs url, honouring ctx's deadline, and returns the body of a 2xx
+// response for the caller to read and close.
+func openURL(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// isPiped reports whether r is something other than an interactive
+// terminal; readers that are not files (e.g. in tests) count as piped.
+func isPiped(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return !ok || !isTerminal(f)
+}
+
+// isTerminal reports whether f is a character device such as a TTY.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progressPrinter renders "imported done/total" on a single line of w,
+// redrawing about once per percent and ending the line when done == total.
+func progressPrinter(w io.Writer) domain.ProgressFunc {
+	return func(done, total int) {
+		step := total / 100
+		if step == 0 {
+			step = 1
+		}
+		if done%step != 0 && done != total {
+			return
+		}
+		fmt.Fprintf(w, "\rimporting %d/%d", done, total)
+		if done == total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// importPlan summarises what BulkImport would do with a batch, without
+// touching the store.
+type importPlan struct {
+	Add        []string
+	Duplicates []string
+	Invalid    map[string]error
+}
+
+// planImport classifies products as new, duplicates of stored products or of
+// earlier records in the batch, or invalid.
+func planImport(ctx context.Context, products []domain.Product) (importPlan, error) {
+	plan := importPlan{Invalid: map[string]error{}}
+	seen := make(map[string]bool, len(products))
+	for i, p := range products {
+		if p.ID == "" {
+			plan.Invalid[fmt.Sprintf("record %d", i)] = domain.NewInvalidProductError("id", "id cannot be empty", p.ID)
+			continue
+		}
+		if err := domain.ValidateProduct(p); err != nil {
+			plan.Invalid[p.ID] = err
+			continue
+		}
+		if seen[p.ID] {
+			plan.Duplicates = append(plan.Duplicates, p.ID)
+			continue
+		}
+		seen[p.ID] = true
+		_, err := productStore.Get(ctx, p.ID)
+		switch {
+		case err == nil:
+			plan.Duplicates = append(plan.Duplicates, p.ID)
+		case domain.IsProductNotFoundError(err):
+			plan.Add = append(plan.Add, p.ID)
+		default:
+			return importPlan{}, err
+		}
+	}
+	return plan, nil
+}
+
+// print writes the plan to w in the dry-run report format.
+func (plan importPlan) print(w io.Writer) {
+	fmt.Fprintf(w, "dry-run: would import %d product(s)\n", len(plan.Add))
+	if len(plan.Duplicates) > 0 {
+		fmt.Fprintf(w, "dry-run: %d duplicate(s): %s\n", len(plan.Duplicates), strings.Join(plan.Duplicates, ", "))
+	}
+	keys := make([]string, 0, len(plan.Invalid))
+	for k := range plan.Invalid {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "dry-run: invalid %s: %v\n", k, plan.Invalid[k])
+	}
+}
+
+// importProblem is one reason a record would fail to import
+type importProblem struct {
+	Record int
+	ID     string
+	Err    error
+}
+
+// String names the record by index, and by id when it has one
+func (p importProblem) String() string {
+	if p.ID == "" {
+		return fmt.Sprintf("record %d: %v", p.Record, p.Err)
+	}
+	return fmt.Sprintf("record %d (id=%s): %v", p.Record, p.ID, p.Err)
+}
+
+// importReport is the data quality report of import --validate-only
+type importReport struct {
+	Records  int
+	Invalid  int
+	Problems []importProblem
+}
+
+// checkImport reports every problem with every record. A record breaking
+// the schema s gets its schema violations only; any other gets a missing
+// id, each invalid field, an id used by an earlier record, and an id the
+// store already holds, soft-deleted products included.
+func checkImport(ctx context.Context, s *schema.Schema, records []json.RawMessage) (importReport, error) {
+	stored, err := productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if err != nil {
+		return importReport{}, err
+	}
+	inStore := make(map[string]bool, len(stored))
+	for _, p := range stored {
+		inStore[p.ID] = true
+	}
+
+	report := importReport{Records: len(records)}
+	firstSeen := make(map[string]int, len(records))
+	for i, rec := range records {
+		n := len(report.Problems)
+		var p domain.Product
+		decodeErr := json.Unmarshal(rec, &p)
+		if decodeErr != nil {
+			// label the record by id even when another field does not decode
+			var withID struct {
+				ID string `json:"id"`
+			}
+			_ = json.Unmarshal(rec, &withID)
+			p.ID = withID.ID
+		}
+		add := func(err error) {
+			report.Problems = append(report.Problems, importProblem{Record: i, ID: p.ID, Err: err})
+		}
+		if violations := s.ValidateJSON(rec); len(violations) > 0 {
+			for _, fe := range violations {
+				add(fmt.Errorf("schema: %w", fe))
+			}
+			report.Invalid++
+			continue
+		}
+		if decodeErr != nil {
+			add(decodeErr)
+			report.Invalid++
+			continue
+		}
+		if p.ID == "" {
+			add(domain.NewInvalidProductError("id", "id cannot be empty", p.ID))
+		}
+		for _, err := range domain.ValidateProductAll(p) {
+			add(err)
+		}
+		if p.ID != "" {
+			if j, ok := firstSeen[p.ID]; ok {
+				add(fmt.Errorf("same id as record %d: %w", j, domain.NewDuplicateProductError(p.ID)))
+			} else {
+				firstSeen[p.ID] = i
+				if inStore[p.ID] {
+					add(fmt.Errorf("already in the store: %w", domain.NewDuplicateProductError(p.ID)))
+				}
+			}
+		}
+		if len(report.Problems) > n {
+			report.Invalid++
+		}
+	}
+	return report, nil
+}
+
+// print writes the report to w, one line per problem after the summary
+func (r importReport) print(w io.Writer) {
+	fmt.Fprintf(w, "validate-only: %d record(s) checked, %d valid, %d invalid\n", r.Records, r.Records-r.Invalid, r.Invalid)
+	for _, p := range r.Problems {
+		fmt.Fprintln(w, p)
+	}
+}
+
+// err returns an error when any record would fail to import
+func (r importReport) err() error {
+	if r.Invalid == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d record(s) would fail to import", r.Invalid, r.Records)
+}
+
+// importBatches streams the import data read from r into the store,
+// batchSize products at a time. A goroutine decodes ahead into a channel
+// holding at most one batch, so about two batches are in memory however
+// large the input. Each batch is its own BulkImportWithOptions with opts:
+// an atomic import stops at the first batch that fails, and earlier batches
+// stay stored. A record breaking the schema s stops the import. Failures
+// are returned as a *domain.BulkImportError whose Applied lists every id
+// stored, in the order imported.
+func importBatches(ctx context.Context, r io.Reader, format string, s *schema.Schema, batchSize int, opts domain.BulkImportOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	products := make(chan domain.Product, batchSize)
+	decoded := make(chan error, 1)
+	go func() {
+		defer close(products)
+		i := 0
+		decoded <- eachRecord(r, format, func(rec json.RawMessage) error {
+			defer func() { i++ }()
+			if violations := s.ValidateJSON(rec); len(violations) > 0 {
+				msgs := make([]string, len(violations))
+				for j, fe := range violations {
+					msgs[j] = fmt.Sprintf("record %d: %s", i, fe.Error())
+				}
+				return fmt.Errorf("schema validation failed: %s", strings.Join(msgs, "; "))
+			}
+			var p domain.Product
+			if err := json.Unmarshal(rec, &p); err != nil {
+				return fmt.Errorf("record %d: %w", i, err)
+			}
+			select {
+			case products <- p:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var applied []string
+	var failed domain.MultiError
+	batch := make([]domain.Product, 0, batchSize)
+	// flush imports batch and reports whether the import should go on
+	flush := func() (bool, error) {
+		err := productStore.BulkImportWithOptions(ctx, batch, opts)
+		var bie *domain.BulkImportError
+		switch {
+		case err == nil:
+			for _, p := range batch {
+				applied = append(applied, p.ID)
+			}
+		case errors.As(err, &bie):
+			applied = append(applied, bie.Applied...)
+			for _, e := range bie.Failed.Errors() {
+				failed.Append(e)
+			}
+		default:
+			return false, err
+		}
+		batch = batch[:0]
+		return err == nil || !opts.Atomic, nil
+	}
+
+	var err error
+	goOn := true
+	for p := range products {
+		batch = append(batch, p)
+		if len(batch) == batchSize {
+			if goOn, err = flush(); !goOn {
+				break
+			}
+		}
+	}
+	if goOn {
+		// the decoder's error comes first: a partial last batch is not stored
+		if err = <-decoded; err == nil && len(batch) > 0 {
+			_, err = flush()
+		}
+	}
+	cancel()
+	for range products {
+		// let the decoder see the cancellation and finish
+	}
+	if err != nil {
+		if len(applied) > 0 {
+			return fmt.Errorf("%w (%d product(s) from earlier batches stay imported)", err, len(applied))
+		}
+		return err
+	}
+	return domain.NewBulkImportError(applied, &failed)
+}
@@ -0,0 +1,440 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/schema"
+	"aexp_assesment/store"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImport_SchemaViolationReportsRecordIndex(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	data := `[{"id":"s1","name":"Ok","price":1},{"id":"s2","name":"Bad","price":"9.99"}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{"import", "--file", path})
+	err := Execute()
+	if err == nil {
+		t.Fatalf("expected schema validation error")
+	}
+	if !strings.Contains(err.Error(), "record 1: price") {
+		t.Fatalf("expected record index and field in error, got %v", err)
+	}
+}
+
+func TestImport_CustomSchema(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(`{"type":"object","required":["category"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dataPath := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(dataPath, []byte(`{"id":"c1","name":"NoCategory","price":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{"import", "--file", dataPath, "--schema", schemaPath})
+	err := Execute()
+	if err == nil || !strings.Contains(err.Error(), "record 0: category: is required") {
+		t.Fatalf("expected custom schema violation, got %v", err)
+	}
+}
+
+func TestImport_FromStdin(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.SetIn(nil)
+
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{"dash reads stdin", []string{"import", "--file", "-"}, `[{"id":"a1","name":"A","price":1,"quantity":1}]`},
+		{"piped stdin without --file", []string{"import"}, "{\"id\":\"a1\",\"name\":\"A\",\"price\":1,\"quantity\":1}\n{\"id\":\"a2\",\"name\":\"B\",\"price\":2,\"quantity\":1}\n"},
+		{"pretty-printed single object", []string{"import", "--file", "-"}, "{\n  \"id\": \"a1\",\n  \"name\": \"A\",\n  \"price\": 1\n}\n"},
+		{"pretty-printed ndjson records", []string{"import", "--file", "-"}, "{\n  \"id\": \"a1\",\n  \"name\": \"A\"\n}\n{\n  \"id\": \"a2\",\n  \"name\": \"B\"\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCLI()
+			productStore = store.NewInMemoryStore()
+			rootCmd.SetIn(strings.NewReader(tt.input))
+			_, err := captureOutput(func() error {
+				rootCmd.SetArgs(tt.args)
+				return rootCmd.Execute()
+			})
+			if err != nil {
+				t.Fatalf("import failed: %v", err)
+			}
+			if _, err := productStore.Get(context.Background(), "a1"); err != nil {
+				t.Fatalf("expected a1 to be imported: %v", err)
+			}
+		})
+	}
+}
+
+func TestProgressPrinter(t *testing.T) {
+	var buf strings.Builder
+	p := progressPrinter(&buf)
+	for i := 1; i <= 250; i++ {
+		p(i, 250)
+	}
+	out := buf.String()
+	if strings.Count(out, "\r") != 125 || !strings.HasSuffix(out, "\rimporting 250/250\n") {
+		t.Fatalf("unexpected progress rendering: %q", out)
+	}
+}
+
+func TestImport_FromURL(t *testing.T) {
+	defer resetCLI()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":"r1","name":"Remote","price":1,"quantity":1}]`))
+	}))
+	defer srv.Close()
+
+	productStore = store.NewInMemoryStore()
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"import", "--file", srv.URL + "/catalog.json"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if _, err := productStore.Get(context.Background(), "r1"); err != nil {
+		t.Fatalf("expected r1 to be imported: %v", err)
+	}
+
+	resetCLI()
+	productStore = store.NewInMemoryStore()
+	_, err = captureOutput(func() error {
+		rootCmd.SetArgs([]string{"import", "--file", srv.URL + "/missing.json"})
+		return rootCmd.Execute()
+	})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a 404 status error, got %v", err)
+	}
+}
+
+func TestImport_AtomicAndBestEffort(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "dup.json")
+	data := `[{"id":"a1","name":"A","price":1},{"id":"a2","name":"B","price":1},{"id":"a2","name":"B again","price":1}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	productStore = store.NewInMemoryStore()
+	rootCmd.SetArgs([]string{"import", "--file", path})
+	if err := Execute(); err == nil {
+		t.Fatal("expected the duplicate to fail the import")
+	}
+	if out, _ := productStore.List(context.Background(), domain.ListFilter{}); len(out) != 0 {
+		t.Fatalf("atomic import must store nothing, got %+v", out)
+	}
+
+	rootCmd.SetArgs([]string{"import", "--file", path, "--best-effort"})
+	out, err := captureOutput(Execute)
+	if err == nil {
+		t.Fatal("expected the duplicate to be reported")
+	}
+	if !strings.Contains(out, "imported 2 product(s): a1, a2") {
+		t.Fatalf("expected the applied ids, got %q", out)
+	}
+}
+
+func TestImport_RateLimitNeedsBestEffort(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	rootCmd.SetArgs([]string{"import", "--file", "x.json", "--rate-limit", "10"})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), "require --best-effort") {
+		t.Fatalf("expected --rate-limit to require --best-effort, got %v", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"array", `[{"id":"a"}]`, formatJSON},
+		{"single object on one line", `{"id":"a"}`, formatJSON},
+		{"pretty-printed object", "{\n  \"id\": \"a\"\n}", formatJSON},
+		{"store envelope", `{"version":1,"products":[]}`, formatJSON},
+		{"ndjson", "{\"id\":\"a\"}\n{\"id\":\"b\"}", formatNDJSON},
+		{"csv header", "id,name,price\na,A,1", formatCSV},
+		{"yaml list", "- id: a\n  name: A", formatYAML},
+		{"yaml document", "---\n- id: a", formatYAML},
+		{"yaml object", "id: a\nname: A", formatYAML},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat([]byte(tt.input))
+			if err != nil || got != tt.want {
+				t.Fatalf("detectFormat = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+	for _, input := range []string{"", "   ", "hello"} {
+		if got, err := detectFormat([]byte(input)); err == nil {
+			t.Fatalf("expected %q to be undetectable, got %q", input, got)
+		}
+	}
+}
+
+func TestDecodeRecords_Formats(t *testing.T) {
+	tests := []struct {
+		name, format, input string
+		want                []string
+	}{
+		{"pretty-printed object", "", "{\n  \"id\": \"a\",\n  \"name\": \"A\"\n}", []string{`{"id":"a","name":"A"}`}},
+		{"csv", formatCSV, "id,name,price,quantity,tags,attributes\na,\"Pen, blue\",1.50,3,office;sale,color=blue;size=M\nb,B,x,,,",
+			[]string{
+				`{"attributes":{"color":"blue","size":"M"},"id":"a","name":"Pen, blue","price":1.50,"quantity":3,"tags":["office","sale"]}`,
+				`{"id":"b","name":"B","price":"x"}`,
+			}},
+		{"csv numbers outside JSON syntax", formatCSV, "id,price,quantity\na,NaN,+3\nb,0x1p4,007\nc,Inf,1\nd,1e2,-0",
+			[]string{
+				`{"id":"a","price":"NaN","quantity":"+3"}`,
+				`{"id":"b","price":"0x1p4","quantity":"007"}`,
+				`{"id":"c","price":"Inf","quantity":1}`,
+				`{"id":"d","price":1e2,"quantity":-0}`,
+			}},
+		{"yaml list", formatYAML, "- id: a\n  name: A\n  price: 1.5\n  tags: [x]\n- id: b\n  name: B", []string{`{"id":"a","name":"A","price":1.5,"tags":["x"]}`, `{"id":"b","name":"B"}`}},
+		{"one-line object as ndjson", formatNDJSON, `{"id":"a"}`, []string{`{"id":"a"}`}},
+		{"multi-line ndjson records", "", "{\n  \"id\": \"a\"\n}\n\n{\"id\": \"b\",\n \"name\": \"B\"}\n", []string{`{"id":"a"}`, `{"id":"b","name":"B"}`}},
+		{"pretty-printed object as ndjson", formatNDJSON, "{\n  \"id\": \"a\"\n}", []string{`{"id":"a"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := decodeRecords([]byte(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			var got []string
+			for _, r := range records {
+				var buf bytes.Buffer
+				if err := json.Compact(&buf, r); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, buf.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := decodeRecords([]byte("{\"id\":\"a\"}\n{\"id\":"), formatNDJSON); err == nil || !strings.Contains(err.Error(), "record 1:") {
+		t.Fatalf("expected the truncated second record to be reported, got %v", err)
+	}
+	if _, err := decodeProducts(strings.NewReader("id,name,price\na,A,NaN"), formatCSV, schema.DefaultProduct()); err == nil || !strings.Contains(err.Error(), "record 0: price") {
+		t.Fatalf("expected a schema problem for the NaN price, got %v", err)
+	}
+	if _, err := decodeRecords([]byte("id,colour\na,red"), formatCSV); err == nil || !strings.Contains(err.Error(), `unknown column "colour"`) {
+		t.Fatalf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestImport_FormatFlag(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "products.txt")
+	if err := os.WriteFile(path, []byte("id,name,price,quantity\nf1,Pen,1.25,4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	productStore = store.NewInMemoryStore()
+	rootCmd.SetArgs([]string{"import", "--file", path, "--format", "csv"})
+	if _, err := captureOutput(Execute); err != nil {
+		t.Fatalf("csv import failed: %v", err)
+	}
+	if p, err := productStore.Get(context.Background(), "f1"); err != nil || p.Price != 125 || p.Quantity != 4 {
+		t.Fatalf("unexpected product %+v (%v)", p, err)
+	}
+
+	rootCmd.SetArgs([]string{"import", "--file", path, "--format", "xml"})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), `unknown --format "xml"`) {
+		t.Fatalf("expected an unknown format error, got %v", err)
+	}
+
+	// forcing json on CSV content fails instead of guessing
+	rootCmd.SetArgs([]string{"import", "--file", path, "--format", "json"})
+	if err := Execute(); err == nil {
+		t.Fatal("expected CSV read as JSON to fail")
+	}
+}
+
+func TestImport_ValidateOnly(t *testing.T) {
+	defer resetCLI()
+	path := filepath.Join(t.TempDir(), "batch.ndjson")
+	data := `{"id":"v1","name":"Taken","price":1}
+{"id":"n1","name":"","price":1}
+{"id":"n4","name":"Odd\u0007","price":1,"currency":"XXX"}
+{"id":"n2","name":"B","price":1}
+{"id":"n2","name":"B again","price":1}
+{"name":"No id","price":1}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewInMemoryStore()
+	_ = st.Create(context.Background(), domain.Product{ID: "v1", Name: "A", Price: 1})
+	productStore = st
+
+	rootCmd.SetArgs([]string{"import", "--file", path, "--validate-only"})
+	out, err := captureOutput(Execute)
+	if err == nil || !strings.Contains(err.Error(), "5 of 6 record(s) would fail to import") {
+		t.Fatalf("expected validate-only to fail, got %v", err)
+	}
+	for _, want := range []string{
+		"validate-only: 6 record(s) checked, 1 valid, 5 invalid",
+		"record 0 (id=v1): already in the store",
+		"record 1 (id=n1): schema: name: length must be >= 1",
+		"record 2 (id=n4): invalid product: field=name",
+		"record 2 (id=n4): invalid product: field=currency",
+		"record 4 (id=n2): same id as record 3",
+		"record 5: schema: id: is required",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in report:\n%s", want, out)
+		}
+	}
+	if all, _ := productStore.List(context.Background(), domain.ListFilter{}); len(all) != 1 {
+		t.Fatalf("validate-only must not import, store has %d product(s)", len(all))
+	}
+
+	// a clean file passes and still imports nothing
+	if err := os.WriteFile(path, []byte(`{"id":"n3","name":"C","price":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"import", "--file", path, "--validate-only"})
+	out, err = captureOutput(Execute)
+	if err != nil || !strings.Contains(out, "1 record(s) checked, 1 valid, 0 invalid") {
+		t.Fatalf("unexpected result %q (%v)", out, err)
+	}
+	if _, err := productStore.Get(context.Background(), "n3"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("validate-only must not import, got %v", err)
+	}
+}
+
+// benchImportRecords sizes the import benchmarks' NDJSON file; each record
+// is about 130 bytes, so -import-records=4000000 makes a 500MB file
+var benchImportRecords = flag.Int("import-records", 100_000, "records in the import benchmark file")
+
+// writeBenchImportFile writes an NDJSON file of *benchImportRecords products
+func writeBenchImportFile(b *testing.B) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "bench.ndjson")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	for i := 0; i < *benchImportRecords; i++ {
+		fmt.Fprintf(w, `{"id":"b-%d","name":"Benchmark product %d","price":12.5,"quantity":3,"category":"Bulk","tags":["bench"]}`+"\n", i, i)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// reportPeakHeap runs fn and reports the most heap it held, sampled every
+// millisecond, as peak-MB
+func reportPeakHeap(b *testing.B, fn func()) {
+	runtime.GC()
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	var peak uint64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(time.Millisecond)
+		defer t.Stop()
+		for {
+			metrics.Read(sample)
+			peak = max(peak, sample[0].Value.Uint64())
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-stopped
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
+
+// benchImport imports the benchmark file into an empty memory store with
+// run, reporting the peak live heap
+func benchImport(b *testing.B, run func(f *os.File, sch *schema.Schema) error) {
+	path := writeBenchImportFile(b)
+	sch := schema.DefaultProduct()
+	defer func() { productStore = nil }()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		productStore = store.NewInMemoryStore()
+		reportPeakHeap(b, func() {
+			f, err := os.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+			if err := run(f, sch); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+// BenchmarkImport_OneBatch imports the whole file as one batch, as import
+// does by default
+func BenchmarkImport_OneBatch(b *testing.B) {
+	benchImport(b, func(f *os.File, sch *schema.Schema) error {
+		products, err := decodeProducts(f, "", sch)
+		if err != nil {
+			return err
+		}
+		return productStore.BulkImport(context.Background(), products)
+	})
+}
+
+// BenchmarkImport_Batches streams the file into the store 10k products at a
+// time, as import --batch-size 10000 does
+func BenchmarkImport_Batches(b *testing.B) {
+	benchImport(b, func(f *os.File, sch *schema.Schema) error {
+		return importBatches(context.Background(), f, "", sch, 10_000, domain.DefaultBulkImportOptions)
+	})
+}
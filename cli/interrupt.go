@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// ErrInterrupted marks the error of a command stopped with Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// interruptedError reports err from a command that was stopped with Ctrl-C
+func interruptedError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return ErrInterrupted
+	}
+	return fmt.Errorf("%w: %w", ErrInterrupted, err)
+}
+
+// interrupts routes Ctrl-C (SIGINT) to the innermost running operation.
+// While any handler is installed the signal no longer ends the process.
+var interrupts struct {
+	mu       sync.Mutex
+	ch       chan os.Signal
+	handlers []func()
+}
+
+// onInterrupt makes fn the handler for Ctrl-C until the returned function is
+// called, when the handler installed before it takes over again; calls must
+// be nested. fn runs on a separate goroutine.
+func onInterrupt(fn func()) (restore func()) {
+	interrupts.mu.Lock()
+	defer interrupts.mu.Unlock()
+	if len(interrupts.handlers) == 0 {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt)
+		interrupts.ch = ch
+		go dispatchInterrupts(ch)
+	}
+	interrupts.handlers = append(interrupts.handlers, fn)
+	n := len(interrupts.handlers)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			interrupts.mu.Lock()
+			defer interrupts.mu.Unlock()
+			interrupts.handlers = interrupts.handlers[:n-1]
+			if len(interrupts.handlers) == 0 {
+				signal.Stop(interrupts.ch)
+				close(interrupts.ch)
+				interrupts.ch = nil
+			}
+		})
+	}
+}
+
+// dispatchInterrupts calls the innermost handler for every signal on ch
+// until ch is closed
+func dispatchInterrupts(ch chan os.Signal) {
+	for range ch {
+		interrupts.mu.Lock()
+		var fn func()
+		if n := len(interrupts.handlers); n > 0 {
+			fn = interrupts.handlers[n-1]
+		}
+		interrupts.mu.Unlock()
+		if fn != nil {
+			fn()
+		}
+	}
+}
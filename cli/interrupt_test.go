@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// interruptSelf sends Ctrl-C's signal to the test process. Only call it
+// while a handler is installed.
+func interruptSelf(t *testing.T) {
+	t.Helper()
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot send an interrupt here: %v", err)
+	}
+}
+
+func TestOnInterrupt_InnermostHandlerWins(t *testing.T) {
+	outer, inner := make(chan struct{}, 1), make(chan struct{}, 1)
+	restoreOuter := onInterrupt(func() { outer <- struct{}{} })
+	defer restoreOuter()
+
+	restoreInner := onInterrupt(func() { inner <- struct{}{} })
+	interruptSelf(t)
+	select {
+	case <-inner:
+	case <-outer:
+		t.Fatal("expected the inner handler to get the interrupt")
+	case <-time.After(2 * time.Second):
+		t.Fatal("interrupt not delivered")
+	}
+
+	restoreInner()
+	interruptSelf(t)
+	select {
+	case <-outer:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the outer handler to take over again")
+	}
+}
+
+func TestInterruptedError(t *testing.T) {
+	if err := interruptedError(context.Canceled); err != ErrInterrupted {
+		t.Fatalf("expected a bare cancellation to become ErrInterrupted, got %v", err)
+	}
+	err := interruptedError(fmt.Errorf("import stopped at record 3: %w", context.Canceled))
+	if !errors.Is(err, ErrInterrupted) || err.Error() != "interrupted" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = interruptedError(errors.New("write failed"))
+	if !errors.Is(err, ErrInterrupted) || err.Error() != "interrupted: write failed" {
+		t.Fatalf("expected other errors to be kept, got %v", err)
+	}
+}
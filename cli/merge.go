@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// merge --on-conflict policies
+const (
+	conflictFirst = "first"
+	conflictLast  = "last"
+	conflictError = "error"
+)
+
+// loadCatalog reads the products of one catalog file in any layout import
+// accepts: a JSON array, a single object or NDJSON. A file encrypted by
+// backupStore is decrypted with --store-encryption-key.
+func loadCatalog(path string) ([]domain.Product, error) {
+	key, err := parseEncryptionKey(viper.GetString("store-encryption-key"))
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = store.Decrypt(key, b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	records, err := decodeRecords(b, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	products, err := recordsToProducts(records)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return products, nil
+}
+
+// mergeCatalogs combines the catalogs at paths, in order, into one list
+// sorted by id. Every product is validated like BulkImport would; when an id
+// appears twice onConflict keeps the first or last occurrence, or reports
+// it. All problems are returned together in a domain.MultiError.
+func mergeCatalogs(paths []string, onConflict string) ([]domain.Product, error) {
+	switch onConflict {
+	case conflictFirst, conflictLast, conflictError:
+	default:
+		return nil, fmt.Errorf("invalid --on-conflict %q: want first, last or error", onConflict)
+	}
+
+	merged := make(map[string]domain.Product)
+	source := make(map[string]string)
+	var errs domain.MultiError
+	for _, path := range paths {
+		products, err := loadCatalog(path)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range products {
+			if p.ID == "" {
+				errs.Append(fmt.Errorf("%s: record %d: %w", path, i, domain.NewInvalidProductError("id", "cannot be empty", p.ID)))
+				continue
+			}
+			if err := domain.ValidateProduct(p); err != nil {
+				errs.Append(fmt.Errorf("%s: id=%s: %w", path, p.ID, err))
+				continue
+			}
+			if first, dup := source[p.ID]; dup {
+				if onConflict == conflictFirst {
+					continue
+				}
+				if onConflict == conflictError {
+					errs.Append(fmt.Errorf("%s: %w (also in %s)", path, domain.NewDuplicateProductError(p.ID), first))
+					continue
+				}
+			}
+			p.Currency = p.EffectiveCurrency()
+			merged[p.ID] = p
+			source[p.ID] = path
+		}
+	}
+	if err := errs.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Product, 0, len(merged))
+	for _, p := range merged {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// writeCatalog writes products as an indented JSON array, which the file
+// store and import both load, replacing path atomically.
+func writeCatalog(path string, products []domain.Product) error {
+	b, err := json.MarshalIndent(products, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, b)
+}
+
+// replaceFile writes b to path through a temporary file and a rename, so
+// readers see the old content or the new, never part of it
+func replaceFile(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
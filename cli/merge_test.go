@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCatalogFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMergeCatalogs_ConflictPolicies(t *testing.T) {
+	dir := writeCatalogFiles(t, map[string]string{
+		"a.json": `[{"id":"p2","name":"From A","price":1},{"id":"p1","name":"Only A","price":1}]`,
+		"b.json": "{\"id\":\"p2\",\"name\":\"From B\",\"price\":2}\n{\"id\":\"p3\",\"name\":\"Only B\",\"price\":3}\n",
+	})
+	paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+
+	tests := []struct {
+		policy string
+		p2Name string
+	}{
+		{conflictFirst, "From A"},
+		{conflictLast, "From B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			out, err := mergeCatalogs(paths, tt.policy)
+			if err != nil {
+				t.Fatalf("merge failed: %v", err)
+			}
+			if len(out) != 3 || out[0].ID != "p1" || out[1].ID != "p2" || out[2].ID != "p3" {
+				t.Fatalf("expected p1, p2, p3 sorted, got %+v", out)
+			}
+			if out[1].Name != tt.p2Name || out[1].Currency != domain.DefaultCurrency {
+				t.Fatalf("unexpected p2 %+v", out[1])
+			}
+		})
+	}
+
+	_, err := mergeCatalogs(paths, conflictError)
+	if !domain.IsDuplicateProductError(err) || !strings.Contains(err.Error(), "a.json") {
+		t.Fatalf("expected duplicate naming both files, got %v", err)
+	}
+	if _, err := mergeCatalogs(paths, "newest"); err == nil {
+		t.Fatal("expected an unknown policy to be rejected")
+	}
+}
+
+func TestMergeCatalogs_ValidatesProducts(t *testing.T) {
+	dir := writeCatalogFiles(t, map[string]string{
+		"bad.json": `[{"id":"","name":"NoID","price":1},{"id":"n1","name":"","price":1}]`,
+	})
+	_, err := mergeCatalogs([]string{filepath.Join(dir, "bad.json")}, conflictError)
+	var me *domain.MultiError
+	if !errors.As(err, &me) || len(me.Errors()) != 2 {
+		t.Fatalf("expected both invalid products reported, got %v", err)
+	}
+}
+
+func TestMergeCommand_WritesLoadableFile(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	dir := writeCatalogFiles(t, map[string]string{
+		"a.json": `[{"id":"m2","name":"Two","price":2},{"id":"m1","name":"One","price":1}]`,
+		"b.json": `[{"id":"m3","name":"Three","price":3}]`,
+	})
+	outPath := filepath.Join(dir, "out.json")
+
+	rootCmd.SetArgs([]string{"merge", outPath, filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")})
+	out, err := captureOutput(Execute)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if !strings.Contains(out, "merged 3 product(s) from 2 file(s)") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	fs, err := store.NewFileStore(outPath)
+	if err != nil {
+		t.Fatalf("merged file does not load as a file store: %v", err)
+	}
+	if got, _ := fs.List(context.Background(), domain.ListFilter{}); len(got) != 3 {
+		t.Fatalf("expected 3 products in merged file, got %+v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// minSearchScore drops results too far from the query to be useful
+const minSearchScore = 0.5
+
+// searchResult is one ranked match of "search"
+type searchResult struct {
+	Product domain.Product `json:"product"`
+	Score   float64        `json:"score"`
+}
+
+// rankProducts scores every product against query and returns the best
+// limit results, highest score first and ties by id. Results below
+// minSearchScore are dropped; limit <= 0 keeps all of them.
+func rankProducts(products []domain.Product, query string, limit int) []searchResult {
+	q := strings.ToLower(strings.TrimSpace(query))
+	var out []searchResult
+	for _, p := range products {
+		if s := matchScore(q, p); s >= minSearchScore {
+			out = append(out, searchResult{Product: p, Score: s})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Product.ID < out[j].Product.ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// matchScore rates how well the lower-cased query q matches p's name or
+// category, from 0 to 1. A substring match scores 1; otherwise the best
+// similarity of q to the whole name, any word of it, or the category.
+func matchScore(q string, p domain.Product) float64 {
+	name := strings.ToLower(p.Name)
+	category := strings.ToLower(p.Category)
+	if q == "" {
+		return 0
+	}
+	if strings.Contains(name, q) || strings.Contains(category, q) {
+		return 1
+	}
+	candidates := append([]string{name, category}, strings.Fields(name)...)
+	best := 0.0
+	for _, c := range candidates {
+		best = max(best, similarity(q, c))
+	}
+	return best
+}
+
+// similarity is 1 minus the Levenshtein distance between a and b divided by
+// the longer length, so 1 means equal
+func similarity(a, b string) float64 {
+	n := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(n)
+}
+
+// levenshtein counts the single-rune edits that turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRankProducts(t *testing.T) {
+	products := []domain.Product{
+		{ID: "s1", Name: "Wireless Keyboard", Category: "Electronics"},
+		{ID: "s2", Name: "Keyboard Cover", Category: "Accessories"},
+		{ID: "s3", Name: "Desk Lamp", Category: "Office"},
+		{ID: "s4", Name: "Notebook", Category: "Stationery"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"keyboard", []string{"s1", "s2"}},
+		{"keybaord", []string{"s1", "s2"}}, // transposed letters
+		{"ofice", []string{"s3"}},          // category typo
+		{"lamp", []string{"s3"}},
+		{"zzzz", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, r := range rankProducts(products, tt.query, 0) {
+				got = append(got, r.Product.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if got := rankProducts(products, "keyboard", 1); len(got) != 1 {
+		t.Fatalf("expected --limit to cap results, got %d", len(got))
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Fatalf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSearchCommand(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	_ = productStore.Create(context.Background(), domain.Product{ID: "k1", Name: "Keyboard", Price: 1, Quantity: 1})
+	_ = productStore.Create(context.Background(), domain.Product{ID: "m1", Name: "Mouse", Price: 1, Quantity: 1})
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"search", "keybord"})
+		return rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if !strings.Contains(out, "k1 | Keyboard") || strings.Contains(out, "Mouse") {
+		t.Fatalf("unexpected search output:\n%s", out)
+	}
+}
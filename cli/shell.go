@@ -0,0 +1,448 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// shellPrompt is printed before every line of the interactive shell
+const shellPrompt = "inventory> "
+
+// lineReader reads one line of shell input after printing the prompt
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// plainReader reads lines without editing support, for pipes and terminals
+// the line editor does not handle
+type plainReader struct {
+	r   *bufio.Reader
+	out io.Writer
+}
+
+func (p plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	return p.r.ReadString('\n')
+}
+
+// newShellReader returns a line editor with Tab completion when stdin is a
+// terminal it supports, and a plain reader otherwise. Prompts go to out.
+func newShellReader(out io.Writer) lineReader {
+	if r, ok := newTermReader(os.Stdin, out, shellCompletions); ok {
+		return r
+	}
+	return plainReader{r: bufio.NewReader(os.Stdin), out: out}
+}
+
+// shellCompletions returns the candidates for the last word of line: command
+// names first, then flag names, values registered for the preceding flag
+// (such as categories) and the command's positional arguments (such as
+// product ids). Descriptions are dropped.
+func shellCompletions(line string) []string {
+	words := strings.Fields(line)
+	toComplete := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		toComplete = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	if len(words) == 0 {
+		var names []string
+		for _, c := range rootCmd.Commands() {
+			if c.IsAvailableCommand() {
+				names = append(names, c.Name())
+			}
+		}
+		names = append(names, "exit", "quit")
+		return withPrefix(names, toComplete)
+	}
+
+	cmd, rest, err := rootCmd.Find(words)
+	if err != nil || cmd == rootCmd {
+		return nil
+	}
+	if len(rest) > 0 {
+		if name, ok := strings.CutPrefix(rest[len(rest)-1], "--"); ok {
+			if f := cmd.Flag(name); f != nil && f.NoOptDefVal == "" {
+				fn, ok := cmd.GetFlagCompletionFunc(name)
+				if !ok {
+					return nil
+				}
+				return stripDescriptions(fn(cmd, nil, toComplete))
+			}
+		}
+	}
+	if strings.HasPrefix(toComplete, "-") {
+		var names []string
+		add := func(f *pflag.Flag) {
+			if !f.Hidden && f.Deprecated == "" {
+				names = append(names, "--"+f.Name)
+			}
+		}
+		cmd.NonInheritedFlags().VisitAll(add)
+		cmd.InheritedFlags().VisitAll(add)
+		sort.Strings(names)
+		return withPrefix(names, toComplete)
+	}
+	if cmd.ValidArgsFunction == nil {
+		return nil
+	}
+	return stripDescriptions(cmd.ValidArgsFunction(cmd, positionalArgs(cmd, rest), toComplete))
+}
+
+// positionalArgs drops flags and their values from words
+func positionalArgs(cmd *cobra.Command, words []string) []string {
+	var args []string
+	for i := 0; i < len(words); i++ {
+		name, ok := strings.CutPrefix(words[i], "--")
+		if !ok {
+			args = append(args, words[i])
+			continue
+		}
+		if f := cmd.Flag(name); f != nil && f.NoOptDefVal == "" && !strings.Contains(name, "=") {
+			i++ // skip the value
+		}
+	}
+	return args
+}
+
+func stripDescriptions(candidates []string, _ cobra.ShellCompDirective) []string {
+	out := make([]string, len(candidates))
+	for i, c := range candidates {
+		out[i], _, _ = strings.Cut(c, "\t")
+	}
+	return out
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// completeLine applies candidates to the last word of line. A single
+// candidate replaces the word and adds a space; several extend it to their
+// longest common prefix. show lists the candidates when the line could not
+// be extended, so the caller can print them.
+func completeLine(line string, candidates []string) (completed string, show []string) {
+	if len(candidates) == 0 {
+		return line, nil
+	}
+	start := strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+	if len(candidates) == 1 {
+		return line[:start] + candidates[0] + " ", nil
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(word) {
+		return line[:start] + prefix, nil
+	}
+	return line, candidates
+}
+
+// printCandidates lists completion candidates on their own line
+func printCandidates(w io.Writer, candidates []string) {
+	fmt.Fprintf(w, "\n%s\n", strings.Join(candidates, "  "))
+}
+
+// runScript executes the commands in r, one per line, with the shell's
+// syntax. Blank lines and lines starting with # are skipped and exit or quit
+// ends the script. The first failure stops it unless keepGoing, in which
+// case every failure is reported and a summary error returned at the end.
+// name labels errors with their line number. Every command runs under ctx
+// and writes to stdout unless the line redirects its output.
+func runScript(ctx context.Context, r io.Reader, name string, keepGoing bool, stdout io.Writer) error {
+	sc := bufio.NewScanner(r)
+	failed, ran := 0, 0
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			break
+		}
+		ran++
+		sl, err := parseShellLine(line)
+		if err == nil {
+			err = runShellLine(ctx, sl, stdout)
+		}
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s:%d: %w", name, n, err)
+		if !keepGoing {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, err)
+		failed++
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d command(s) failed", name, failed, ran)
+	}
+	return nil
+}
+
+// shellToken is one word of a shell line; op marks an unquoted >, >> or |
+type shellToken struct {
+	text string
+	op   bool
+}
+
+// tokenizeShellLine splits line into words like a POSIX shell would for the
+// supported subset: whitespace separates words, single quotes keep text
+// literally, double quotes keep spaces, a backslash escapes the next
+// character, and unquoted >, >> and | are operators even without spaces.
+func tokenizeShellLine(line string) ([]shellToken, error) {
+	var tokens []shellToken
+	var cur strings.Builder
+	inWord := false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, shellToken{text: cur.String()})
+			cur.Reset()
+			inWord = false
+		}
+	}
+	rs := []rune(line)
+	for i := 0; i < len(rs); i++ {
+		switch c := rs[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		case c == '|':
+			flush()
+			tokens = append(tokens, shellToken{text: "|", op: true})
+		case c == '>':
+			flush()
+			if i+1 < len(rs) && rs[i+1] == '>' {
+				i++
+				tokens = append(tokens, shellToken{text: ">>", op: true})
+			} else {
+				tokens = append(tokens, shellToken{text: ">", op: true})
+			}
+		case c == '\\':
+			if i+1 == len(rs) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			cur.WriteRune(rs[i])
+			inWord = true
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(rs) && rs[end] != c {
+				if c == '"' && rs[end] == '\\' && end+1 < len(rs) {
+					end++
+				}
+				cur.WriteRune(rs[end])
+				end++
+			}
+			if end == len(rs) {
+				return nil, fmt.Errorf("unterminated %c quote", c)
+			}
+			i = end
+			inWord = true
+		default:
+			cur.WriteRune(c)
+			inWord = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// shellLine is a parsed shell line: the inventory command, the external
+// commands its output is piped through, and the file the final output goes
+// to, if any.
+type shellLine struct {
+	args      []string
+	pipes     [][]string
+	outFile   string
+	appendOut bool
+}
+
+// parseShellLine parses cmd [| prog args...]... [> file | >> file]
+func parseShellLine(line string) (shellLine, error) {
+	tokens, err := tokenizeShellLine(line)
+	if err != nil {
+		return shellLine{}, err
+	}
+	var sl shellLine
+	stage := &sl.args
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case !t.op:
+			*stage = append(*stage, t.text)
+		case t.text == "|":
+			if len(*stage) == 0 || sl.outFile != "" {
+				return shellLine{}, errors.New("syntax error near |")
+			}
+			sl.pipes = append(sl.pipes, nil)
+			stage = &sl.pipes[len(sl.pipes)-1]
+		default: // > or >>
+			if len(*stage) == 0 || i+1 >= len(tokens) || tokens[i+1].op || i+2 != len(tokens) {
+				return shellLine{}, fmt.Errorf("syntax error near %s: want %s <file> at the end of the line", t.text, t.text)
+			}
+			sl.outFile, sl.appendOut = tokens[i+1].text, t.text == ">>"
+			i++
+		}
+	}
+	if len(sl.pipes) > 0 && len(sl.pipes[len(sl.pipes)-1]) == 0 {
+		return shellLine{}, errors.New("syntax error: | without a command")
+	}
+	return sl, nil
+}
+
+// runShellLine executes one parsed line under ctx, which carries the
+// session's cancellation; --timeout applies to each line as in one-shot
+// mode. Ctrl-C cancels just this line and makes its error an ErrInterrupted.
+// The inventory command writes to stdout, or to the redirect file or the
+// first pipe, through rootCmd's output, which every command prints to;
+// piped programs run without a shell.
+func runShellLine(ctx context.Context, sl shellLine, stdout io.Writer) error {
+	out := stdout
+	if sl.outFile != "" {
+		flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if sl.appendOut {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(sl.outFile, flag, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// start the pipeline from its end so each program can read the next pipe
+	var procs []*exec.Cmd
+	var writeEnds []*os.File
+	for i := len(sl.pipes) - 1; i >= 0; i-- {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		p := exec.Command(sl.pipes[i][0], sl.pipes[i][1:]...)
+		p.Stdin, p.Stdout, p.Stderr = r, out, os.Stderr
+		if err := p.Start(); err != nil {
+			r.Close()
+			w.Close()
+			closeAll(writeEnds)
+			waitAll(procs)
+			return err
+		}
+		r.Close()
+		procs = append(procs, p)
+		writeEnds = append(writeEnds, w)
+		out = w
+	}
+
+	lineCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var interrupted atomic.Bool
+	restore := onInterrupt(func() {
+		interrupted.Store(true)
+		cancel()
+	})
+
+	resetLocalFlags(rootCmd.Commands())
+	rootCmd.SetArgs(sl.args)
+	rootCmd.SetOut(out)
+	_, err := executeContext(lineCtx)
+	rootCmd.SetOut(nil)
+	rootCmd.SetArgs(nil)
+	restore()
+	if err != nil && interrupted.Load() {
+		err = interruptedError(err)
+	}
+
+	closeAll(writeEnds)
+	if werr := waitAll(procs); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// executeContext runs rootCmd under ctx and returns the command that ran.
+// Cobra only hands a command the root's context while the command has none,
+// so every later run, such as the next shell line, would keep the first
+// run's context. Each command's context is cleared for the run and put back
+// after, which also leaves a running shell command's own intact.
+func executeContext(ctx context.Context) (*cobra.Command, error) {
+	saved := map[*cobra.Command]context.Context{rootCmd: rootCmd.Context()}
+	var clear func(cmds []*cobra.Command)
+	clear = func(cmds []*cobra.Command) {
+		for _, c := range cmds {
+			saved[c] = c.Context()
+			c.SetContext(nil)
+			clear(c.Commands())
+		}
+	}
+	clear(rootCmd.Commands())
+	defer func() {
+		for c, ctx := range saved {
+			c.SetContext(ctx)
+		}
+	}()
+
+	return rootCmd.ExecuteContextC(ctx)
+}
+
+// resetLocalFlags puts every command's own flags back to their defaults so
+// a flag given on one shell line does not stick to the next. Flags of the
+// root command, such as --store or --dry-run given when starting the
+// shell, are kept.
+func resetLocalFlags(cmds []*cobra.Command) {
+	for _, c := range cmds {
+		c.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				_ = sv.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+		resetLocalFlags(c.Commands())
+	}
+}
+
+// closeAll closes the pipe write ends, the last-created (closest to the
+// command) first, so each program in turn sees end of input
+func closeAll(files []*os.File) {
+	for i := len(files) - 1; i >= 0; i-- {
+		files[i].Close()
+	}
+}
+
+// waitAll waits for every piped program and returns the first failure
+func waitAll(procs []*exec.Cmd) error {
+	var first error
+	for i := len(procs) - 1; i >= 0; i-- {
+		if err := procs[i].Wait(); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", procs[i].Path, err)
+		}
+	}
+	return first
+}
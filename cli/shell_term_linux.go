@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// termReader is a minimal line editor for Linux terminals: it echoes input,
+// handles backspace and completes the last word on Tab. Other keys with
+// escape sequences, such as arrows, are ignored.
+type termReader struct {
+	in       *os.File
+	out      io.Writer
+	complete func(line string) []string
+}
+
+// newTermReader returns a termReader when in is a terminal
+func newTermReader(in *os.File, out io.Writer, complete func(string) []string) (lineReader, bool) {
+	if _, err := unix.IoctlGetTermios(int(in.Fd()), unix.TCGETS); err != nil {
+		return nil, false
+	}
+	return &termReader{in: in, out: out, complete: complete}, true
+}
+
+// ReadLine switches the terminal out of canonical mode for one line and
+// restores it before returning. Ctrl-C still interrupts; Ctrl-D on an empty
+// line returns io.EOF.
+func (t *termReader) ReadLine(prompt string) (string, error) {
+	fd := int(t.in.Fd())
+	saved, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return "", err
+	}
+	raw := *saved
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN], raw.Cc[unix.VTIME] = 1, 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, saved)
+
+	io.WriteString(t.out, prompt)
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := t.in.Read(b); err != nil {
+			return "", err
+		}
+		switch c := b[0]; {
+		case c == '\r' || c == '\n':
+			io.WriteString(t.out, "\n")
+			return string(line) + "\n", nil
+		case c == 4: // Ctrl-D
+			if len(line) == 0 {
+				io.WriteString(t.out, "\n")
+				return "", io.EOF
+			}
+		case c == 127 || c == 8: // backspace
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				line = line[:len(line)-size]
+				io.WriteString(t.out, "\b \b")
+			}
+		case c == '\t':
+			completed, show := completeLine(string(line), t.complete(string(line)))
+			if show != nil {
+				printCandidates(t.out, show)
+				io.WriteString(t.out, prompt+completed)
+			} else {
+				io.WriteString(t.out, completed[len(line):])
+			}
+			line = []byte(completed)
+		case c == 27: // escape sequence: skip "[" and its final byte
+			t.skipEscape()
+		case c >= 32:
+			line = append(line, c)
+			t.out.Write(b)
+		}
+	}
+}
+
+func (t *termReader) skipEscape() {
+	b := make([]byte, 1)
+	if _, err := t.in.Read(b); err != nil || b[0] != '[' {
+		return
+	}
+	for {
+		if _, err := t.in.Read(b); err != nil || b[0] >= 0x40 {
+			return
+		}
+	}
+}
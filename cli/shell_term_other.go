@@ -0,0 +1,13 @@
+//go:build !linux
+
+package cli
+
+import (
+	"io"
+	"os"
+)
+
+// newTermReader has no line editor outside Linux; the shell reads plain lines
+func newTermReader(in *os.File, out io.Writer, complete func(string) []string) (lineReader, bool) {
+	return nil, false
+}
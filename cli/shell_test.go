@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellCompletions(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "abc-1", Name: "Apple", Price: 1, Quantity: 1, Category: "Fruit"})
+	_ = productStore.Create(ctx, domain.Product{ID: "abd-2", Name: "Desk", Price: 1, Quantity: 1, Category: "Furniture"})
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"li", []string{"list"}},
+		{"ex", []string{"exit", "export"}},
+		{"get ab", []string{"abc-1", "abd-2"}},
+		{"delete --force abc", []string{"abc-1"}},
+		{"get abc-1 ", nil},
+		{"list --category F", []string{"Fruit", "Furniture"}},
+		{"update abc-1 --set-category ", []string{"Fruit", "Furniture"}},
+		{"list --all-", []string{"--all-tags"}},
+		{"nope x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := shellCompletions(tt.line)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompleteLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		candidates []string
+		want       string
+		show       []string
+	}{
+		{"li", []string{"list"}, "list ", nil},
+		{"get ab", []string{"abc-1", "abd-2"}, "get ab", []string{"abc-1", "abd-2"}},
+		{"get a", []string{"abc-1", "abd-2"}, "get ab", nil},
+		{"get z", nil, "get z", nil},
+	}
+	for _, tt := range tests {
+		got, show := completeLine(tt.line, tt.candidates)
+		if got != tt.want || strings.Join(show, ",") != strings.Join(tt.show, ",") {
+			t.Fatalf("completeLine(%q) = %q, %v; want %q, %v", tt.line, got, show, tt.want, tt.show)
+		}
+	}
+}
+
+func TestParseShellLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		args    string
+		pipes   string
+		outFile string
+		append  bool
+	}{
+		{`list --output json > out.json`, "list|--output|json", "", "out.json", false},
+		{`list>>log.txt`, "list", "", "log.txt", true},
+		{`create --name "Desk Lamp" --category 'A > B'`, "create|--name|Desk Lamp|--category|A > B", "", "", false},
+		{`list | grep Desk | wc -l > n.txt`, "list", "grep Desk;wc -l", "n.txt", false},
+		{`get a\ b`, "get|a b", "", "", false},
+	}
+	for _, tt := range tests {
+		sl, err := parseShellLine(tt.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.line, err)
+		}
+		var pipes []string
+		for _, p := range sl.pipes {
+			pipes = append(pipes, strings.Join(p, " "))
+		}
+		if strings.Join(sl.args, "|") != tt.args || strings.Join(pipes, ";") != tt.pipes ||
+			sl.outFile != tt.outFile || sl.appendOut != tt.append {
+			t.Fatalf("%s: unexpected parse %+v", tt.line, sl)
+		}
+	}
+
+	for _, bad := range []string{`list >`, `list > a b`, `| grep x`, `list |`, `list > a | grep x`, `get "abc`, `get abc\`} {
+		if _, err := parseShellLine(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRunShellLine_UsesSessionContext(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	sl, _ := parseShellLine("list")
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runShellLine(canceled, sl, io.Discard); err == nil {
+		t.Fatal("expected a canceled session to cancel the command")
+	}
+	// the next line must not inherit the previous line's context
+	if err := runShellLine(context.Background(), sl, io.Discard); err != nil {
+		t.Fatalf("expected a live session to run the command, got %v", err)
+	}
+}
+
+func TestRunShellLine_InterruptStopsOnlyTheCommand(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	// the shell's own handler keeps a stray interrupt from killing the test
+	defer onInterrupt(func() {})()
+
+	sl, _ := parseShellLine("watch")
+	done := make(chan error, 1)
+	go func() { done <- runShellLine(context.Background(), sl, io.Discard) }()
+	time.Sleep(100 * time.Millisecond)
+	interruptSelf(t)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Ctrl-C to stop the running command")
+	}
+}
+
+func TestRunShellLine_RedirectAndPipe(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = productStore.Create(ctx, domain.Product{ID: "r1", Name: "Desk", Price: 1, Quantity: 1})
+	_ = productStore.Create(ctx, domain.Product{ID: "r2", Name: "Lamp", Price: 1, Quantity: 1})
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+
+	run := func(line string) {
+		t.Helper()
+		sl, err := parseShellLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := runShellLine(context.Background(), sl, io.Discard); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+
+	run("list -q --sort-by name > " + out)
+	run("get r1 --fields id >> " + out)
+	b, _ := os.ReadFile(out)
+	if !strings.HasPrefix(string(b), "r1\nr2\n{") || !strings.Contains(string(b), `"id": "r1"`) {
+		t.Fatalf("unexpected redirected output:\n%s", b)
+	}
+
+	if _, err := exec.LookPath("grep"); err != nil {
+		t.Skip("grep not available")
+	}
+	run("list | grep Lamp > " + out)
+	b, _ = os.ReadFile(out)
+	if got := strings.TrimSpace(string(b)); !strings.HasPrefix(got, "r2 | Lamp") || strings.Contains(got, "Desk") {
+		t.Fatalf("unexpected piped output:\n%s", b)
+	}
+}
+
+func TestRunShellLine_WritesToGivenWriter(t *testing.T) {
+	defer resetCLI()
+	productStore = store.NewInMemoryStore()
+	_ = productStore.Create(context.Background(), domain.Product{ID: "o1", Name: "Desk", Price: 1, Quantity: 1})
+	stdout := os.Stdout
+
+	var buf bytes.Buffer
+	sl, _ := parseShellLine("list -q")
+	if err := runShellLine(context.Background(), sl, &buf); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if buf.String() != "o1\n" {
+		t.Fatalf("expected the output in the given writer, got %q", buf.String())
+	}
+	if os.Stdout != stdout {
+		t.Fatal("expected os.Stdout to be left alone")
+	}
+
+	// the next command prints to stdout again
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"get", "o1", "--fields", "id"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, `"o1"`) {
+		t.Fatalf("expected get to print to stdout, got %q (%v)", out, err)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	defer resetCLI()
+	st := store.NewInMemoryStore()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "seed.txt")
+	if err := os.WriteFile(script, []byte(`# seed data
+create --id s1 --name "Desk Lamp" --price 10 --quantity 1
+
+create --id s1 --name Again --price 1 --quantity 1
+create --id s2 --name Chair --price 20 --quantity 2
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) error {
+		resetCLI()
+		productStore = st
+		_, err := captureOutput(func() error {
+			rootCmd.SetArgs(args)
+			return rootCmd.Execute()
+		})
+		return err
+	}
+
+	err := run("run", script)
+	if !domain.IsDuplicateProductError(err) || !strings.Contains(err.Error(), "seed.txt:4:") {
+		t.Fatalf("expected the duplicate on line 4 to stop the script, got %v", err)
+	}
+	if _, err := st.Get(context.Background(), "s2"); err == nil {
+		t.Fatalf("expected the script to stop before s2")
+	}
+	if p, _ := st.Get(context.Background(), "s1"); p.Name != "Desk Lamp" {
+		t.Fatalf("expected the quoted name, got %q", p.Name)
+	}
+
+	_ = st.Delete(context.Background(), "s1")
+	err = run("run", script, "--keep-going")
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 command(s) failed") {
+		t.Fatalf("expected a summary of the failure, got %v", err)
+	}
+	if _, err := st.Get(context.Background(), "s2"); err != nil {
+		t.Fatalf("expected --keep-going to reach s2, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// blockingStore's List waits until its context is done, standing in for a
+// store that hangs.
+type blockingStore struct {
+	domain.ProductStore
+}
+
+func (blockingStore) List(ctx context.Context, _ domain.ListFilter) ([]domain.Product, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeoutFlag(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("timeout", "0")
+	productStore = blockingStore{}
+
+	_, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"--timeout", "20ms", "list"})
+		return rootCmd.Execute()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestJSONErrorEnvelope(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("timeout", "0")
+	productStore = blockingStore{}
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"--timeout", "10ms", "list", "--output", "json"})
+		return Execute()
+	})
+	if err == nil {
+		t.Fatalf("expected list to fail")
+	}
+	var env domain.ErrorEnvelope
+	if jerr := json.Unmarshal([]byte(out), &env); jerr != nil {
+		t.Fatalf("expected a JSON error envelope on stdout, got %q: %v", out, jerr)
+	}
+	if env.Code != domain.CodeInternal || env.Message != err.Error() {
+		t.Fatalf("unexpected envelope %+v", env)
+	}
+}
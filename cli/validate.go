@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// skuAttribute is the product attribute validate treats as a stock keeping
+// unit, which should be unique among live products
+const skuAttribute = "sku"
+
+// storeRecords returns every record the store holds, soft-deleted ones
+// included. The file store's file is read as written, so an id that appears
+// twice, which loading would silently collapse, is returned twice.
+func storeRecords(ctx context.Context) ([]domain.Product, error) {
+	kind, path, err := storeLocation()
+	if err != nil || kind != "file" {
+		return productStore.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	}
+	opts, err := storeOptions()
+	if err != nil {
+		return nil, err
+	}
+	return store.ReadFile(path, opts)
+}
+
+// checkStoreRecords reports every record without an id, with an id seen
+// before, or failing ValidateProduct, then every live product whose SKU
+// another live product already uses
+func checkStoreRecords(products []domain.Product) []error {
+	var problems []error
+	var me *domain.MultiError
+	if err := checkBackup(products); errors.As(err, &me) {
+		problems = append(problems, me.Errors()...)
+	}
+
+	bySKU := make(map[string][]string)
+	for _, p := range products {
+		sku := strings.TrimSpace(p.Attributes[skuAttribute])
+		if sku == "" || p.IsDeleted() {
+			continue
+		}
+		bySKU[sku] = append(bySKU[sku], p.ID)
+	}
+	skus := make([]string, 0, len(bySKU))
+	for sku := range bySKU {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+	for _, sku := range skus {
+		ids := bySKU[sku]
+		sort.Strings(ids)
+		for _, id := range ids[1:] {
+			problems = append(problems, fmt.Errorf("id=%s: duplicate sku %q (also on id=%s)", id, sku, ids[0]))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckStoreRecords(t *testing.T) {
+	products := []domain.Product{
+		{ID: "a", Name: "A", Price: 1, Attributes: map[string]string{"sku": "S-1"}},
+		{ID: "b", Name: "", Price: 1},
+		{ID: "a", Name: "A again", Price: 1},
+		{ID: "c", Name: "C", Price: 1, Attributes: map[string]string{"sku": " S-1 "}},
+		{ID: "d", Name: "D", Price: 1, Attributes: map[string]string{"sku": "S-2"}},
+		{ID: "", Name: "No id", Price: 1},
+	}
+	problems := checkStoreRecords(products)
+	var msgs []string
+	for _, p := range problems {
+		msgs = append(msgs, p.Error())
+	}
+	got := strings.Join(msgs, "\n")
+	for _, want := range []string{"id=b:", "id=a: duplicate product", "record 5:", `id=c: duplicate sku "S-1" (also on id=a)`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q among problems:\n%s", want, got)
+		}
+	}
+	if len(problems) != 4 {
+		t.Fatalf("expected 4 problems, got %d:\n%s", len(problems), got)
+	}
+}
+
+func TestValidateCommand_FileStore(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("store", "memory")
+	defer rootCmd.PersistentFlags().Set("store-file", "data/products.json")
+
+	path := filepath.Join(t.TempDir(), "products.json")
+	edited := `[{"id":"p1","name":"Pen","price":1},{"id":"p1","name":"Pen copy","price":1},{"id":"p2","name":"","price":1}]`
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.PersistentFlags().Set("store", "file")
+	rootCmd.PersistentFlags().Set("store-file", path)
+	productStore = store.NewInMemoryStore()
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"validate"})
+		return rootCmd.Execute()
+	})
+	if err == nil || !strings.Contains(err.Error(), "found 2 problem(s) in 3 record(s)") {
+		t.Fatalf("expected validate to fail, got %v", err)
+	}
+	if !strings.Contains(out, "id=p1:") || !strings.Contains(out, "id=p2:") {
+		t.Fatalf("expected both problems printed, got %q", out)
+	}
+}
+
+func TestValidateCommand_Clean(t *testing.T) {
+	defer resetCLI()
+	defer rootCmd.PersistentFlags().Set("store", "memory")
+	rootCmd.PersistentFlags().Set("store", "memory")
+	st := store.NewInMemoryStore()
+	_ = st.Create(context.Background(), domain.Product{ID: "v1", Name: "A", Price: 1, Quantity: 1})
+	productStore = st
+
+	out, err := captureOutput(func() error {
+		rootCmd.SetArgs([]string{"validate"})
+		return rootCmd.Execute()
+	})
+	if err != nil || !strings.Contains(out, "ok: 1 record(s) checked") {
+		t.Fatalf("unexpected validate result %q (%v)", out, err)
+	}
+}
@@ -2,13 +2,44 @@ package main
 
 import (
 	"aexp_assesment/cli"
+	"aexp_assesment/domain"
+	"errors"
 	"fmt"
 	"os"
 )
 
+// Process exit codes; keep in sync with the root command help in cli.
+const (
+	exitGeneric   = 1
+	exitNotFound  = 2
+	exitInvalid   = 3
+	exitDuplicate = 4
+	exitLimit     = 5
+	// conventional status of a process ended by SIGINT
+	exitInterrupted = 130
+)
+
 func main() {
 	if err := cli.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode maps an error returned by the CLI to a stable exit status
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, cli.ErrInterrupted):
+		return exitInterrupted
+	case domain.IsProductNotFoundError(err):
+		return exitNotFound
+	case domain.IsInvalidProductError(err):
+		return exitInvalid
+	case domain.IsDuplicateProductError(err):
+		return exitDuplicate
+	case domain.IsLimitExceededError(err):
+		return exitLimit
+	default:
+		return exitGeneric
 	}
 }
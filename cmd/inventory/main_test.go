@@ -0,0 +1,32 @@
+package main
+
+import (
+	"aexp_assesment/cli"
+	"aexp_assesment/domain"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", domain.NewProductNotFoundError("x"), 2},
+		{"invalid", domain.NewInvalidProductError("name", "empty", ""), 3},
+		{"duplicate", domain.NewDuplicateProductError("x"), 4},
+		{"limit", domain.NewLimitExceededError(1, 1, 1), 5},
+		{"wrapped", fmt.Errorf("id=x: %w", domain.NewDuplicateProductError("x")), 4},
+		{"interrupted", fmt.Errorf("%w: %w", cli.ErrInterrupted, domain.NewProductNotFoundError("x")), 130},
+		{"generic", errors.New("boom"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Fatalf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// BulkImportOptions control how BulkImportWithOptions treats a batch in
+// which some products fail.
+type BulkImportOptions struct {
+	// Atomic stores nothing when any product fails. When false the import
+	// is best effort: every valid product is stored and the failures are
+	// reported alongside the ids that were applied.
+	Atomic bool
+	// RateLimit caps a best-effort import at this many creates a second,
+	// so a slow backend is not flooded by the workers. Zero means no limit.
+	// Atomic imports, and stores that write a batch at once, ignore it.
+	RateLimit int
+	// MaxRetries is how many times a best-effort import retries a product
+	// whose create failed with an error IsRetryable accepts. Zero disables
+	// retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry; it doubles for each
+	// retry after that. Zero uses the store's default.
+	RetryBackoff time.Duration
+}
+
+// DefaultBulkImportOptions are the options BulkImport uses
+var DefaultBulkImportOptions = BulkImportOptions{Atomic: true}
+
+// BulkImportError reports a BulkImport in which some products failed or
+// that was cancelled part way. Applied lists the ids stored anyway, sorted;
+// it is empty for an atomic import. errors.Is and errors.As reach the
+// per-product errors, and a context error, through Failed.
+type BulkImportError struct {
+	Applied []string
+	Failed  *MultiError
+}
+
+// NewBulkImportError returns a BulkImportError, or nil when failed is empty
+func NewBulkImportError(applied []string, failed *MultiError) error {
+	if failed.ErrOrNil() == nil {
+		return nil
+	}
+	return &BulkImportError{Applied: applied, Failed: failed}
+}
+
+// Error summarises the applied and failed counts followed by each failure
+func (e *BulkImportError) Error() string {
+	return fmt.Sprintf("bulk import: %d applied, %d failed: %s", len(e.Applied), len(e.Failed.Errors()), e.Failed.Error())
+}
+
+// Unwrap exposes the per-product failures to errors.Is and errors.As
+func (e *BulkImportError) Unwrap() error {
+	return e.Failed
+}
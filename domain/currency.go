@@ -0,0 +1,62 @@
+package domain
+
+import "strings"
+
+// DefaultCurrency is assumed for products that do not specify one
+const DefaultCurrency = "USD"
+
+// Currency describes how amounts in an ISO 4217 currency are displayed
+type Currency struct {
+	Code       string
+	Symbol     string
+	MinorUnits int
+}
+
+// Currencies is the set of ISO 4217 codes accepted on products
+var Currencies = map[string]Currency{
+	"USD": {Code: "USD", Symbol: "$", MinorUnits: 2},
+	"EUR": {Code: "EUR", Symbol: "€", MinorUnits: 2},
+	"GBP": {Code: "GBP", Symbol: "£", MinorUnits: 2},
+	"JPY": {Code: "JPY", Symbol: "¥", MinorUnits: 0},
+	"CAD": {Code: "CAD", Symbol: "CA$", MinorUnits: 2},
+	"AUD": {Code: "AUD", Symbol: "A$", MinorUnits: 2},
+	"INR": {Code: "INR", Symbol: "₹", MinorUnits: 2},
+	"CHF": {Code: "CHF", Symbol: "CHF ", MinorUnits: 2},
+}
+
+// EffectiveCurrency returns p.Currency, or DefaultCurrency when it is unset
+func (p Product) EffectiveCurrency() string {
+	if p.Currency == "" {
+		return DefaultCurrency
+	}
+	return p.Currency
+}
+
+// FormatMoney renders m with the symbol and minor-unit count of the given
+// currency code, e.g. "$9.99" or "¥1500". Unknown codes fall back to
+// "<amount> <code>".
+func FormatMoney(m Money, code string) string {
+	c, ok := Currencies[strings.ToUpper(code)]
+	if !ok {
+		return m.String() + " " + code
+	}
+	if c.MinorUnits == 0 {
+		return c.Symbol + Money(roundHalfEvenDiv(int64(m), 100)*100).StringFixed(0)
+	}
+	return c.Symbol + m.String()
+}
+
+// roundHalfEvenDiv divides n by d rounding half-to-even
+func roundHalfEvenDiv(n, d int64) int64 {
+	q, r := n/d, n%d
+	if r < 0 {
+		r = -r
+	}
+	if 2*r > d || (2*r == d && q%2 != 0) {
+		if n < 0 {
+			return q - 1
+		}
+		return q + 1
+	}
+	return q
+}
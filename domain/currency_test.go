@@ -0,0 +1,40 @@
+package domain
+
+import "testing"
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		code string
+		want string
+	}{
+		{"usd", 999, "USD", "$9.99"},
+		{"eur lower-case code", 1050, "eur", "€10.50"},
+		{"jpy has no minor units", 150000, "JPY", "¥1500"},
+		{"jpy rounds half to even", 150050, "JPY", "¥1500"},
+		{"jpy rounds up", 150150, "JPY", "¥1502"},
+		{"unknown code", 999, "XYZ", "9.99 XYZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatMoney(tt.m, tt.code); got != tt.want {
+				t.Fatalf("FormatMoney(%d, %q) = %q, want %q", tt.m, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProduct_Currency(t *testing.T) {
+	if err := ValidateProduct(Product{Name: "a", Currency: "EUR"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateProduct(Product{Name: "a"}); err != nil {
+		t.Fatalf("empty currency should default to %s: %v", DefaultCurrency, err)
+	}
+	err := ValidateProduct(Product{Name: "a", Currency: "XYZ"})
+	ipe, ok := err.(*InvalidProductError)
+	if !ok || ipe.Field != "currency" {
+		t.Fatalf("expected currency InvalidProductError, got %v", err)
+	}
+}
@@ -2,8 +2,21 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Stable error codes reported in ErrorEnvelope
+const (
+	CodeProductNotFound  = "PRODUCT_NOT_FOUND"
+	CodeInvalidProduct   = "INVALID_PRODUCT"
+	CodeDuplicateProduct = "DUPLICATE_PRODUCT"
+	CodeLimitExceeded    = "LIMIT_EXCEEDED"
+	CodeMultipleErrors   = "MULTIPLE_ERRORS"
+	CodeInternal         = "INTERNAL"
 )
 
 // ProductNotFoundError is returned when a product with the given ID is not found
@@ -16,6 +29,9 @@ func (e *ProductNotFoundError) Error() string {
 	return fmt.Sprintf("product not found: id=%s", e.ProductID)
 }
 
+// Code returns the stable machine-readable code for ProductNotFoundError
+func (e *ProductNotFoundError) Code() string { return CodeProductNotFound }
+
 // Is allows proper error type checking with errors.Is()
 func (e *ProductNotFoundError) Is(target error) bool {
 	_, ok := target.(*ProductNotFoundError)
@@ -34,6 +50,9 @@ func (e *InvalidProductError) Error() string {
 	return fmt.Sprintf("invalid product: field=%s, reason=%s, value=%v", e.Field, e.Reason, e.Value)
 }
 
+// Code returns the stable machine-readable code for InvalidProductError
+func (e *InvalidProductError) Code() string { return CodeInvalidProduct }
+
 // Is allows proper error type checking with errors.Is()
 func (e *InvalidProductError) Is(target error) bool {
 	_, ok := target.(*InvalidProductError)
@@ -50,12 +69,37 @@ func (e *DuplicateProductError) Error() string {
 	return fmt.Sprintf("duplicate product: id=%s already exists", e.ProductID)
 }
 
+// Code returns the stable machine-readable code for DuplicateProductError
+func (e *DuplicateProductError) Code() string { return CodeDuplicateProduct }
+
 // Is allows proper error type checking with errors.Is()
 func (e *DuplicateProductError) Is(target error) bool {
 	_, ok := target.(*DuplicateProductError)
 	return ok
 }
 
+// LimitExceededError is returned when a write would take the store past its
+// maximum number of products
+type LimitExceededError struct {
+	Limit int
+	Count int // live products before the write
+	Added int // products the write would add
+}
+
+// Error implements the error interface for LimitExceededError
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("product limit exceeded: %d product(s) stored, adding %d would pass the limit of %d", e.Count, e.Added, e.Limit)
+}
+
+// Code returns the stable machine-readable code for LimitExceededError
+func (e *LimitExceededError) Code() string { return CodeLimitExceeded }
+
+// Is allows proper error type checking with errors.Is()
+func (e *LimitExceededError) Is(target error) bool {
+	_, ok := target.(*LimitExceededError)
+	return ok
+}
+
 // Helper functions for creating errors with context
 
 // NewProductNotFoundError creates a new ProductNotFoundError
@@ -77,6 +121,11 @@ func NewDuplicateProductError(productID string) error {
 	return &DuplicateProductError{ProductID: productID}
 }
 
+// NewLimitExceededError creates a new LimitExceededError
+func NewLimitExceededError(limit, count, added int) error {
+	return &LimitExceededError{Limit: limit, Count: count, Added: added}
+}
+
 // Type assertion helpers for use with errors.As()
 
 // IsProductNotFoundError checks if an error is a ProductNotFoundError
@@ -96,3 +145,147 @@ func IsDuplicateProductError(err error) bool {
 	var dpe *DuplicateProductError
 	return errors.As(err, &dpe)
 }
+
+// TransientError marks a failure that may pass if the operation is tried
+// again, such as a timeout talking to a remote store
+type TransientError struct {
+	Err error
+}
+
+// Error returns the message of the wrapped error
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes the wrapped error to errors.Is and errors.As
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransientError marks err as transient, or returns nil for a nil err
+func NewTransientError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Err: err}
+}
+
+// IsRetryable reports whether err is worth retrying: it must be marked with
+// NewTransientError. The domain errors (not found, invalid and duplicate
+// product) are never retryable, even when marked, since trying again gives
+// the same answer; neither is a cancelled or expired context.
+func IsRetryable(err error) bool {
+	var te *TransientError
+	if !errors.As(err, &te) {
+		return false
+	}
+	if IsProductNotFoundError(err) || IsInvalidProductError(err) || IsDuplicateProductError(err) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsLimitExceededError checks if an error is a LimitExceededError
+func IsLimitExceededError(err error) bool {
+	var lee *LimitExceededError
+	return errors.As(err, &lee)
+}
+
+// HTTPStatus maps an error to the HTTP status code transports should report:
+// 404 for ProductNotFoundError, 409 for DuplicateProductError, 400 for
+// InvalidProductError, 403 for LimitExceededError and 500 for anything else
+// (including nil).
+func HTTPStatus(err error) int {
+	switch {
+	case IsProductNotFoundError(err):
+		return http.StatusNotFound
+	case IsDuplicateProductError(err):
+		return http.StatusConflict
+	case IsInvalidProductError(err):
+		return http.StatusBadRequest
+	case IsLimitExceededError(err):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// MultiError collects several independent failures, such as the per-product
+// errors of a bulk import. errors.Is and errors.As inspect every entry.
+type MultiError struct {
+	errs []error
+}
+
+// Append adds err; nil errors are ignored
+func (m *MultiError) Append(err error) {
+	if err != nil {
+		m.errs = append(m.errs, err)
+	}
+}
+
+// Errors returns the collected errors in the order they were added
+func (m *MultiError) Errors() []error {
+	return m.errs
+}
+
+// Unwrap exposes the collected errors to errors.Is and errors.As
+func (m *MultiError) Unwrap() []error {
+	return m.errs
+}
+
+// Error joins the messages of all collected errors with "; "
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.errs))
+	for i, err := range m.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ErrOrNil returns m as an error, or nil when nothing was collected
+func (m *MultiError) ErrOrNil() error {
+	if m == nil || len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// ErrorEnvelope is the machine-readable form of an error used by JSON
+// outputs. Field is set for InvalidProductError; Errors lists the entries of
+// a MultiError and Applied the ids a BulkImportError stored anyway.
+type ErrorEnvelope struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Field   string          `json:"field,omitempty"`
+	Errors  []ErrorEnvelope `json:"errors,omitempty"`
+	Applied []string        `json:"applied,omitempty"`
+}
+
+// NewErrorEnvelope describes err, using CodeInternal for errors that carry
+// no code of their own.
+func NewErrorEnvelope(err error) ErrorEnvelope {
+	env := ErrorEnvelope{Code: CodeInternal, Message: err.Error()}
+
+	var bie *BulkImportError
+	if errors.As(err, &bie) {
+		env.Applied = bie.Applied
+	}
+	var me *MultiError
+	if errors.As(err, &me) {
+		env.Code = CodeMultipleErrors
+		for _, e := range me.Errors() {
+			env.Errors = append(env.Errors, NewErrorEnvelope(e))
+		}
+		return env
+	}
+
+	var c interface{ Code() string }
+	if errors.As(err, &c) {
+		env.Code = c.Code()
+	}
+	var ipe *InvalidProductError
+	if errors.As(err, &ipe) {
+		env.Field = ipe.Field
+	}
+	return env
+}
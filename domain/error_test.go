@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -153,3 +155,134 @@ func TestErrorTypeDiscrimination(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", NewProductNotFoundError("x"), 404},
+		{"duplicate", NewDuplicateProductError("x"), 409},
+		{"invalid", NewInvalidProductError("name", "empty", ""), 400},
+		{"limit", NewLimitExceededError(10, 10, 1), 403},
+		{"wrapped not found", fmt.Errorf("get: %w", NewProductNotFoundError("x")), 404},
+		{"wrapped invalid", fmt.Errorf("id=1: %w", NewInvalidProductError("price", "negative", -1)), 400},
+		{"other", errors.New("disk full"), 500},
+		{"nil", nil, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.want {
+				t.Fatalf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	var m MultiError
+	if m.ErrOrNil() != nil {
+		t.Fatalf("empty MultiError should be nil")
+	}
+	m.Append(nil)
+	m.Append(NewDuplicateProductError("a"))
+	m.Append(fmt.Errorf("id=b: %w", NewInvalidProductError("price", "negative", -1)))
+
+	err := m.ErrOrNil()
+	if len(m.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(m.Errors()))
+	}
+	if !IsDuplicateProductError(err) || !IsInvalidProductError(err) || IsProductNotFoundError(err) {
+		t.Fatalf("errors.As should see each collected error")
+	}
+	var ipe *InvalidProductError
+	if !errors.As(err, &ipe) || ipe.Field != "price" {
+		t.Fatalf("expected to extract the price InvalidProductError, got %v", ipe)
+	}
+	want := "duplicate product: id=a already exists; id=b: invalid product: field=price, reason=negative, value=-1"
+	if err.Error() != want {
+		t.Fatalf("unexpected message:\n got %q\nwant %q", err.Error(), want)
+	}
+}
+
+func TestNewErrorEnvelope(t *testing.T) {
+	var multi MultiError
+	multi.Append(NewDuplicateProductError("a"))
+	multi.Append(NewInvalidProductError("quantity", "negative", -1))
+
+	tests := []struct {
+		name  string
+		err   error
+		code  string
+		field string
+	}{
+		{"not found", NewProductNotFoundError("x"), CodeProductNotFound, ""},
+		{"duplicate", NewDuplicateProductError("x"), CodeDuplicateProduct, ""},
+		{"invalid", NewInvalidProductError("name", "empty", ""), CodeInvalidProduct, "name"},
+		{"wrapped invalid", fmt.Errorf("id=1: %w", NewInvalidProductError("price", "negative", -1)), CodeInvalidProduct, "price"},
+		{"other", errors.New("disk full"), CodeInternal, ""},
+		{"multi", &multi, CodeMultipleErrors, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := NewErrorEnvelope(tt.err)
+			if env.Code != tt.code || env.Field != tt.field || env.Message != tt.err.Error() {
+				t.Fatalf("unexpected envelope %+v", env)
+			}
+		})
+	}
+
+	env := NewErrorEnvelope(&multi)
+	if len(env.Errors) != 2 || env.Errors[1].Code != CodeInvalidProduct || env.Errors[1].Field != "quantity" {
+		t.Fatalf("unexpected nested envelopes %+v", env.Errors)
+	}
+}
+
+func TestBulkImportError(t *testing.T) {
+	var empty MultiError
+	if err := NewBulkImportError([]string{"a"}, &empty); err != nil {
+		t.Fatalf("expected nil without failures, got %v", err)
+	}
+
+	var failed MultiError
+	failed.Append(fmt.Errorf("id=b: %w", NewDuplicateProductError("b")))
+	err := NewBulkImportError([]string{"a"}, &failed)
+	if !IsDuplicateProductError(err) {
+		t.Fatalf("expected the duplicate to be reachable, got %v", err)
+	}
+	env := NewErrorEnvelope(err)
+	if env.Code != CodeMultipleErrors || len(env.Errors) != 1 || len(env.Applied) != 1 || env.Applied[0] != "a" {
+		t.Fatalf("unexpected envelope %+v", env)
+	}
+
+	if !DefaultBulkImportOptions.Atomic {
+		t.Fatal("expected atomic imports by default")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	timeout := errors.New("connection timed out")
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"transient", NewTransientError(timeout), true},
+		{"wrapped transient", fmt.Errorf("id=p1: %w", NewTransientError(timeout)), true},
+		{"plain error", timeout, false},
+		{"nil", nil, false},
+		{"invalid", NewTransientError(NewInvalidProductError("name", "required", "")), false},
+		{"duplicate", NewTransientError(NewDuplicateProductError("p1")), false},
+		{"not found", NewTransientError(NewProductNotFoundError("p1")), false},
+		{"cancelled", NewTransientError(context.Canceled), false},
+	}
+	for _, tc := range cases {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if NewTransientError(nil) != nil {
+		t.Error("expected NewTransientError(nil) to be nil")
+	}
+}
@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in integer cents. It marshals to and from JSON as a
+// decimal number ("price": 9.99) so existing catalog files keep loading,
+// while sums and comparisons stay exact.
+type Money int64
+
+// PricePrecision selects how amounts finer than one cent are handled when
+// parsing Money
+type PricePrecision string
+
+const (
+	// PricePrecisionOff rounds sub-cent amounts half-to-even without complaint
+	PricePrecisionOff PricePrecision = ""
+	// PricePrecisionReject fails parsing for amounts with more than two decimals
+	PricePrecisionReject PricePrecision = "reject"
+	// PricePrecisionRound rounds sub-cent amounts half-to-even
+	PricePrecisionRound PricePrecision = "round"
+)
+
+// Cents returns m as whole cents
+func (m Money) Cents() int64 { return int64(m) }
+
+// Float64 returns m in major units. Use it for display only.
+func (m Money) Float64() float64 { return float64(m) / 100 }
+
+// String formats m with exactly two decimal places, e.g. "9.90"
+func (m Money) String() string {
+	return m.StringFixed(2)
+}
+
+// StringFixed formats m with the given number of decimal places, rounding
+// half-to-even when that is fewer than two. It works on the cents directly,
+// so every amount formats exactly.
+func (m Money) StringFixed(decimals int) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+	// the magnitude, unsigned so math.MinInt64 fits
+	c := uint64(m)
+	if m < 0 {
+		c = -c
+	}
+	scale := 2
+	if decimals < scale {
+		div := uint64(1)
+		for ; scale > decimals; scale-- {
+			div *= 10
+		}
+		q, r := c/div, c%div
+		if 2*r > div || 2*r == div && q%2 == 1 {
+			q++
+		}
+		c = q
+	}
+	s := strconv.FormatUint(c, 10)
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale+1-len(s)) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if decimals > scale {
+		s += strings.Repeat("0", decimals-scale)
+	}
+	if m < 0 && c != 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON writes m as its shortest decimal number, e.g. 9.99 or 10
+func (m Money) MarshalJSON() ([]byte, error) {
+	s := m.String()
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = []byte(s)
+	}
+	v, err := ParseMoney(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Set implements pflag.Value so Money can back CLI flags
+func (m *Money) Set(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Type implements pflag.Value
+func (m *Money) Type() string { return "decimal" }
+
+// maxAmountLen bounds the text ParseMoney will parse. It leaves room for
+// every amount Money can hold with plenty of decimals to round away, while
+// keeping an untrusted input from costing more than a few big.Int words.
+const maxAmountLen = 64
+
+// ParseMoney parses a plain decimal amount such as "9.99" or "-3" into
+// cents. Fractions, exponents and anything longer than maxAmountLen are
+// rejected. Amounts finer than one cent are rounded half-to-even, or
+// rejected when Validation.PricePrecision is PricePrecisionReject.
+func ParseMoney(s string) (Money, error) {
+	if len(s) > maxAmountLen {
+		return 0, fmt.Errorf("invalid amount: longer than %d characters", maxAmountLen)
+	}
+	if !isDecimal(s) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() && Validation.PricePrecision == PricePrecisionReject {
+		return 0, NewInvalidProductError("price", "price must have at most two decimal places", s)
+	}
+	m, ok := roundCents(r)
+	if !ok {
+		return 0, fmt.Errorf("amount %q out of range", s)
+	}
+	return m, nil
+}
+
+// isDecimal reports whether s is an optional minus sign, one or more digits,
+// and optionally a point followed by one or more digits
+func isDecimal(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	return allDigits(whole) && (!hasPoint || allDigits(frac))
+}
+
+// allDigits reports whether s is non-empty and made of ASCII digits only
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// roundCents rounds an amount of cents half-to-even; ok is false when the
+// result does not fit in Money
+func roundCents(r *big.Rat) (m Money, ok bool) {
+	num, den := r.Num(), r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// compare |2*rem| against den to decide the rounding direction
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	switch c := twice.Cmp(den); {
+	case c > 0, c == 0 && quo.Bit(0) == 1:
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		return 0, false
+	}
+	return Money(quo.Int64()), true
+}
+
+// AdjustByPercent returns m changed by pct percent, e.g. -10 for a 10% cut,
+// rounded half-to-even to the cent. pct is taken as its shortest decimal
+// form, so 1.1 means exactly 1.1%. Results beyond the range of Money
+// saturate at its bounds.
+func (m Money) AdjustByPercent(pct float64) Money {
+	p, ok := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	if !ok {
+		return m // NaN or ±Inf; callers reject those first
+	}
+	factor := p.Add(p, big.NewRat(100, 1))
+	factor.Quo(factor, big.NewRat(100, 1))
+	r := factor.Mul(factor, new(big.Rat).SetInt64(int64(m)))
+	adjusted, ok := roundCents(r)
+	if !ok {
+		if r.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return adjusted
+}
+
+// MoneyFromFloat converts a float amount using its shortest decimal form, so
+// 2.675 is treated as exactly 2.675 rather than its binary approximation.
+func MoneyFromFloat(f float64) (Money, error) {
+	return ParseMoney(strconv.FormatFloat(f, 'f', -1, 64))
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseMoney_RoundsHalfEven(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"9.99", 999},
+		{"10", 1000},
+		{"9.999", 1000},
+		{"2.675", 268},
+		{"2.665", 266},
+		{"2.6651", 267},
+		{"0.125", 12},
+		{"0.135", 14},
+		{"-1.005", -100},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q) failed: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseMoney("abc"); err == nil {
+		t.Fatalf("expected error for non-numeric amount")
+	}
+}
+
+func TestParseMoney_OnlyPlainDecimals(t *testing.T) {
+	for _, in := range []string{"1/3", "1e2", "1e100000", "0x1p4", "+1", ".5", "5.", "", "-", "1.2.3", " 1", "NaN", "Inf", strings.Repeat("9", 65)} {
+		if m, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) = %d, want an error", in, m)
+		}
+	}
+	for in, want := range map[string]Money{"-0.5": -50, "007": 700, "0.10": 10} {
+		if got, err := ParseMoney(in); err != nil || got != want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+
+	var p Product
+	if err := json.Unmarshal([]byte(`{"price":"1/3"}`), &p); err == nil {
+		t.Fatalf("expected a fraction to be rejected, got %d", p.Price)
+	}
+}
+
+func TestParseMoney_RejectMode(t *testing.T) {
+	defer func(old ValidationConfig) { Validation = old }(Validation)
+	Validation.PricePrecision = PricePrecisionReject
+
+	_, err := ParseMoney("9.999")
+	if ipe, ok := err.(*InvalidProductError); !ok || ipe.Field != "price" {
+		t.Fatalf("expected price InvalidProductError, got %v", err)
+	}
+	if m, err := ParseMoney("9.99"); err != nil || m != 999 {
+		t.Fatalf("two decimals should pass, got %d, %v", m, err)
+	}
+}
+
+func TestMoney_JSONRoundTrip(t *testing.T) {
+	var p Product
+	if err := json.Unmarshal([]byte(`{"id":"1","name":"A","price":0.1}`), &p); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if p.Price != 10 {
+		t.Fatalf("expected 10 cents, got %d", p.Price)
+	}
+	if err := json.Unmarshal([]byte(`{"price":"19.95"}`), &p); err != nil || p.Price != 1995 {
+		t.Fatalf("expected quoted price to parse, got %d, %v", p.Price, err)
+	}
+
+	b, _ := json.Marshal(Product{Price: 1000})
+	var raw map[string]interface{}
+	_ = json.Unmarshal(b, &raw)
+	if raw["price"] != float64(10) {
+		t.Fatalf("expected price to marshal as number 10, got %s", b)
+	}
+
+	// summing cents stays exact where float64 drifts
+	var total Money
+	for i := 0; i < 10; i++ {
+		m, _ := MoneyFromFloat(0.1)
+		total += m
+	}
+	if total != 100 || total.String() != "1.00" {
+		t.Fatalf("expected exact 1.00, got %s", total)
+	}
+}
+
+func TestMoney_FormatsExactly(t *testing.T) {
+	tests := []struct {
+		m                 Money
+		wantStr, wantJSON string
+	}{
+		{0, "0.00", "0"},
+		{5, "0.05", "0.05"},
+		{-5, "-0.05", "-0.05"},
+		{990, "9.90", "9.9"},
+		{1000, "10.00", "10"},
+		{1<<53 + 1, "90071992547409.93", "90071992547409.93"},
+		{math.MaxInt64, "92233720368547758.07", "92233720368547758.07"},
+		{math.MinInt64, "-92233720368547758.08", "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.wantStr {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.m), got, tt.wantStr)
+		}
+		b, _ := json.Marshal(tt.m)
+		if string(b) != tt.wantJSON {
+			t.Errorf("Money(%d) marshals to %s, want %s", int64(tt.m), b, tt.wantJSON)
+		}
+		var back Money
+		if err := json.Unmarshal(b, &back); err != nil || back != tt.m {
+			t.Errorf("Money(%d) round-trips to %d, %v", int64(tt.m), int64(back), err)
+		}
+	}
+
+	for _, tt := range []struct {
+		m        Money
+		decimals int
+		want     string
+	}{{250, 0, "2"}, {350, 0, "4"}, {-251, 0, "-3"}, {-49, 0, "0"}, {125, 1, "1.2"}, {135, 1, "1.4"}, {999, 3, "9.990"}} {
+		if got := tt.m.StringFixed(tt.decimals); got != tt.want {
+			t.Errorf("Money(%d).StringFixed(%d) = %q, want %q", int64(tt.m), tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestMoney_AdjustByPercent(t *testing.T) {
+	tests := []struct {
+		m    Money
+		pct  float64
+		want Money
+	}{
+		{1000, -10, 900},
+		{1999, -10, 1799}, // 17.991 rounds down
+		{125, -10, 112},   // 1.125 rounds half to even
+		{135, -10, 122},   // 1.215 rounds half to even
+		{1000, 1.1, 1011}, // 1.1 is taken as exactly 1.1%
+		{1000, 0, 1000},
+		{1000, -100, 0},
+		{0, 50, 0},
+		{math.MaxInt64, 100, math.MaxInt64}, // saturates
+	}
+	for _, tt := range tests {
+		if got := tt.m.AdjustByPercent(tt.pct); got != tt.want {
+			t.Errorf("%d adjusted by %v%%: got %d, want %d", tt.m, tt.pct, got, tt.want)
+		}
+	}
+}
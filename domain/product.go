@@ -1,24 +1,185 @@
 // Package domain defines core business types and interfaces.
 package domain
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
 
 // Product represents an inventory product
 type Product struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
-	Category string  `json:"category"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Price    Money  `json:"price"`
+	Quantity int    `json:"quantity"`
+	Category string `json:"category"`
+	// ReorderLevel is the minimum desired stock; zero means no reorder point
+	ReorderLevel int `json:"reorder_level,omitempty"`
+	// Tags are free-form labels used for filtering
+	Tags []string `json:"tags,omitempty"`
+	// Currency is the ISO 4217 code Price is expressed in; stores default it to DefaultCurrency
+	Currency string `json:"currency,omitempty"`
+	// Attributes hold category-specific metadata such as color or size
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// DeletedAt is set when a store with soft delete enabled deletes the
+	// product; such products are hidden until restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// UpdatedAt is when the store last created, changed, deleted or restored
+	// the product. Stores set it on every write, overwriting what the caller
+	// passed; products saved before it existed have none.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
-// ListFilter allows filtering and sorting results from List
+// IsDeleted reports whether p has been soft-deleted
+func (p Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
+
+// Value is the stock value of p: price times quantity, in p's currency
+func (p Product) Value() Money {
+	return p.Price * Money(p.Quantity)
+}
+
+// Clone returns a copy of p that shares no slices or maps with it, so stores
+// can hand products to callers without exposing their own state.
+func (p Product) Clone() Product {
+	p.Tags = slices.Clone(p.Tags)
+	p.Attributes = maps.Clone(p.Attributes)
+	if p.DeletedAt != nil {
+		t := *p.DeletedAt
+		p.DeletedAt = &t
+	}
+	if p.UpdatedAt != nil {
+		t := *p.UpdatedAt
+		p.UpdatedAt = &t
+	}
+	return p
+}
+
+// ListFilter allows filtering and sorting results from List. Its JSON form,
+// which leaves unset fields out, describes the filter in list --output
+// json-meta.
 type ListFilter struct {
-	Category string
-	MinPrice *float64
-	MaxPrice *float64
-	SortBy   string // "name", "price", "quantity"
-	Order    string // "asc" or "desc"
+	Category string `json:"category,omitempty"`
+	// Categories matches products in any of the listed categories; when
+	// non-empty, Category is treated as one more member of the set.
+	Categories []string `json:"categories,omitempty"`
+	MinPrice   *Money   `json:"minPrice,omitempty"`
+	MaxPrice   *Money   `json:"maxPrice,omitempty"`
+	// Currency keeps only products priced in this ISO 4217 code
+	Currency string `json:"currency,omitempty"`
+	// MinQuantity keeps only products with Quantity >= *MinQuantity
+	MinQuantity *int `json:"minQuantity,omitempty"`
+	// MaxQuantity keeps only products with Quantity <= *MaxQuantity
+	MaxQuantity *int `json:"maxQuantity,omitempty"`
+	// InStock, when set, keeps products with Quantity > 0 if true and
+	// Quantity == 0 if false
+	InStock *bool `json:"inStock,omitempty"`
+	// TagsAny keeps products carrying at least one of the listed tags
+	TagsAny []string `json:"tagsAny,omitempty"`
+	// TagsAll keeps products carrying every listed tag
+	TagsAll []string `json:"tagsAll,omitempty"`
+	// AttributeEquals keeps products whose attributes have every listed value
+	AttributeEquals map[string]string `json:"attributeEquals,omitempty"`
+	// UpdatedAfter keeps products whose UpdatedAt is at or after this time;
+	// products without an UpdatedAt never match
+	UpdatedAfter *time.Time `json:"updatedAfter,omitempty"`
+	// IncludeDeleted also returns soft-deleted products
+	IncludeDeleted bool   `json:"includeDeleted,omitempty"`
+	SortBy         string `json:"sortBy,omitempty"` // "name", "price", "quantity", "value"; comma-separate for tie-breakers, e.g. "price,name"; the id breaks remaining ties
+	Order          string `json:"order,omitempty"`  // "asc" or "desc"
+	// CaseInsensitive compares names by their lower-cased form when sorting
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+	// Offset skips that many products of the sorted result, which is
+	// ordered by id when SortBy is empty; Limit, when positive, returns at
+	// most that many after them
+	Offset int `json:"offset,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+}
+
+// ProductPatch lists field changes applied by UpdateWhere; nil fields are
+// left unchanged
+type ProductPatch struct {
+	Category *string
+	Price    *Money
+	Quantity *int
+	// PricePercent changes the price by this percentage (see
+	// Money.AdjustByPercent), after Price is applied
+	PricePercent *float64
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (pp ProductPatch) IsEmpty() bool {
+	return pp.Category == nil && pp.Price == nil && pp.Quantity == nil && pp.PricePercent == nil
+}
+
+// Apply returns p with the patch's non-nil fields set
+func (pp ProductPatch) Apply(p Product) Product {
+	if pp.Category != nil {
+		p.Category = *pp.Category
+	}
+	if pp.Price != nil {
+		p.Price = *pp.Price
+	}
+	if pp.Quantity != nil {
+		p.Quantity = *pp.Quantity
+	}
+	if pp.PricePercent != nil {
+		p.Price = p.Price.AdjustByPercent(*pp.PricePercent)
+	}
+	return p
+}
+
+// ChangeOp identifies the kind of mutation carried by a ChangeEvent
+type ChangeOp string
+
+const (
+	ChangeCreated  ChangeOp = "created"
+	ChangeUpdated  ChangeOp = "updated"
+	ChangeDeleted  ChangeOp = "deleted"
+	ChangeRestored ChangeOp = "restored"
+)
+
+// ChangeEvent describes a single mutation applied to a ProductStore
+type ChangeEvent struct {
+	Op      ChangeOp `json:"op"`
+	ID      string   `json:"id"`
+	Product Product  `json:"product"`
+}
+
+// PriceChange records one change of a product's price
+type PriceChange struct {
+	At       time.Time `json:"at"`
+	OldPrice Money     `json:"old_price"`
+	NewPrice Money     `json:"new_price"`
+}
+
+// InventoryStats summarises the products matched by a filter
+type InventoryStats struct {
+	Count    int   `json:"count"`
+	Quantity int   `json:"quantity"`
+	Value    Money `json:"value"` // sum of price * quantity
+}
+
+// Add counts p into the totals
+func (s *InventoryStats) Add(p Product) {
+	s.Count++
+	s.Quantity += p.Quantity
+	s.Value += p.Value()
+}
+
+// DuplicateGroup is a set of products sharing a name and category under
+// different ids
+type DuplicateGroup struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	IDs      []string `json:"ids"`
 }
 
 // ProductStore defines the storage interface for products
@@ -27,34 +188,246 @@ type ProductStore interface {
 	Get(ctx context.Context, id string) (Product, error)
 	Update(ctx context.Context, id string, product Product) error
 	Delete(ctx context.Context, id string) error
+	// BatchDelete removes every listed product, or none if any id is missing.
+	BatchDelete(ctx context.Context, ids []string) error
 	List(ctx context.Context, filter ListFilter) ([]Product, error)
+	// UpdateWhere applies patch to every product matching filter in one step
+	// and returns the updated products. If any result is invalid nothing changes.
+	UpdateWhere(ctx context.Context, filter ListFilter, patch ProductPatch) ([]Product, error)
+	// BulkImport is BulkImportWithOptions with DefaultBulkImportOptions.
 	BulkImport(ctx context.Context, products []Product) error
+	// BulkImportWithOptions stores products as opts asks and reports
+	// failures as a *BulkImportError.
+	BulkImportWithOptions(ctx context.Context, products []Product, opts BulkImportOptions) error
+	// NeedsReorder returns products whose Quantity is below their ReorderLevel.
+	NeedsReorder(ctx context.Context) ([]Product, error)
+	// Watch streams change events until ctx is done, then closes the channel.
+	Watch(ctx context.Context) (<-chan ChangeEvent, error)
+	// Restore brings back a soft-deleted product; restoring a live product
+	// does nothing.
+	Restore(ctx context.Context, id string) error
+	// Purge permanently removes every soft-deleted product, publishes a
+	// ChangeDeleted event for each and returns how many were removed.
+	Purge(ctx context.Context) (int, error)
+	// Clear removes every product, live or soft-deleted, publishes a
+	// ChangeDeleted event for each and returns how many were removed.
+	Clear(ctx context.Context) (int, error)
+	// Categories counts live products per category; products without one
+	// are counted under "".
+	Categories(ctx context.Context) (map[string]int, error)
+	// RenameCategory moves every live product in category from to category
+	// to in one step and returns how many moved. If any result is invalid
+	// nothing changes.
+	RenameCategory(ctx context.Context, from, to string) (int, error)
+	// AdjustPriceWhere changes the price of every live product matching
+	// filter by pct percent, rounded to the cent, in one step and returns
+	// how many products matched. A pct below -100, which would make prices
+	// negative, is rejected; if any result is invalid nothing changes.
+	AdjustPriceWhere(ctx context.Context, filter ListFilter, pct float64) (int, error)
+	// PriceHistory returns the price changes recorded for id, oldest first.
+	// Stores record them only when asked to; otherwise the history is empty.
+	PriceHistory(ctx context.Context, id string) ([]PriceChange, error)
+	// Stats totals the products matching filter. Paging and sort fields are
+	// ignored.
+	Stats(ctx context.Context, filter ListFilter) (InventoryStats, error)
+	// FindDuplicates groups live products by name and category, ignoring
+	// case and surrounding spaces, and returns the groups holding more than
+	// one id, ordered by name then category.
+	FindDuplicates(ctx context.Context) ([]DuplicateGroup, error)
+}
+
+// StoreCloser is implemented by stores that buffer writes or hold resources.
+// Flush writes anything pending; Close flushes and releases the store.
+type StoreCloser interface {
+	Flush(ctx context.Context) error
+	Close(ctx context.Context) error
 }
 
+// FlushStore flushes s if it implements StoreCloser and does nothing otherwise
+func FlushStore(ctx context.Context, s ProductStore) error {
+	if c, ok := s.(StoreCloser); ok {
+		return c.Flush(ctx)
+	}
+	return nil
+}
+
+// CloseStore closes s if it implements StoreCloser and does nothing otherwise
+func CloseStore(ctx context.Context, s ProductStore) error {
+	if c, ok := s.(StoreCloser); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+// ValidationConfig holds the tunable rules applied by ValidateProduct
+type ValidationConfig struct {
+	// MaxNameLength is the maximum number of characters in a name; zero disables the check
+	MaxNameLength int
+	// PricePrecision controls amounts with more than two decimal places when
+	// parsing Money: PricePrecisionReject fails, anything else rounds half-to-even.
+	PricePrecision PricePrecision
+	// AllowedCategories, when non-empty, is the exhaustive list of accepted
+	// categories; an empty list accepts any category.
+	AllowedCategories []string
+	// MaxAttributes caps the number of attributes per product; zero disables the check
+	MaxAttributes int
+	// MaxAttributeLength caps the characters in each attribute key and value;
+	// zero disables the check
+	MaxAttributeLength int
+}
+
+// DefaultValidationConfig returns the rules used when nothing is configured
+func DefaultValidationConfig() ValidationConfig {
+	return ValidationConfig{
+		MaxNameLength:      200,
+		PricePrecision:     PricePrecisionOff,
+		MaxAttributes:      20,
+		MaxAttributeLength: 256,
+	}
+}
+
+// Validation is the package-level configuration consulted by ValidateProduct.
+// Set it once at startup; it is not guarded for concurrent modification.
+var Validation = DefaultValidationConfig()
+
+// ValidateProduct returns the first rule p breaks, checking fields in the
+// order name, category, price, currency, quantity, reorder level and
+// attributes
 func ValidateProduct(p Product) error {
-	if p.Name == "" {
+	for _, rule := range productRules {
+		if err := rule(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateProductAll returns one *InvalidProductError for every field of p
+// that breaks a rule, in the same order as ValidateProduct, or nil. Each
+// field reports only its first problem.
+func ValidateProductAll(p Product) []error {
+	var errs []error
+	for _, rule := range productRules {
+		if err := rule(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// productRules each check one field of a product
+var productRules = []func(Product) error{
+	func(p Product) error { return validateName(p.Name) },
+	func(p Product) error { return ValidateCategory(p.Category) },
+	func(p Product) error {
+		if p.Price < 0 {
+			return NewInvalidProductError(
+				"price",
+				"price must be non-negative",
+				p.Price,
+			)
+		}
+		return nil
+	},
+	func(p Product) error {
+		if _, ok := Currencies[p.EffectiveCurrency()]; !ok {
+			return NewInvalidProductError(
+				"currency",
+				"currency must be a supported ISO 4217 code",
+				p.Currency,
+			)
+		}
+		return nil
+	},
+	func(p Product) error {
+		if p.Quantity < 0 {
+			return NewInvalidProductError(
+				"quantity",
+				"quantity must be non-negative",
+				p.Quantity,
+			)
+		}
+		return nil
+	},
+	func(p Product) error {
+		if p.ReorderLevel < 0 {
+			return NewInvalidProductError(
+				"reorder_level",
+				"reorder level must be non-negative",
+				p.ReorderLevel,
+			)
+		}
+		return nil
+	},
+	func(p Product) error { return validateAttributes(p.Attributes) },
+}
+
+// validateName checks that name is present, not too long and printable
+func validateName(name string) error {
+	if name == "" {
 		return NewInvalidProductError(
 			"name",
 			"name cannot be empty",
-			p.Name,
+			name,
 		)
 	}
 
-	if p.Price < 0 {
+	if n := utf8.RuneCountInString(name); Validation.MaxNameLength > 0 && n > Validation.MaxNameLength {
 		return NewInvalidProductError(
-			"price",
-			"price must be non-negative",
-			p.Price,
+			"name",
+			fmt.Sprintf("name must be at most %d characters", Validation.MaxNameLength),
+			n,
 		)
 	}
 
-	if p.Quantity < 0 {
+	if !utf8.ValidString(name) || strings.IndexFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
 		return NewInvalidProductError(
-			"quantity",
-			"quantity must be non-negative",
-			p.Quantity,
+			"name",
+			"name must not contain non-printable characters",
+			name,
 		)
 	}
+	return nil
+}
 
+// ValidateCategory checks category against Validation.AllowedCategories
+func ValidateCategory(category string) error {
+	if len(Validation.AllowedCategories) > 0 && !slices.Contains(Validation.AllowedCategories, category) {
+		return NewInvalidProductError(
+			"category",
+			"category must be one of: "+strings.Join(Validation.AllowedCategories, ", "),
+			category,
+		)
+	}
+	return nil
+}
+
+// validateAttributes applies the attribute limits of Validation. Keys are
+// checked in sorted order so the reported attribute is deterministic.
+func validateAttributes(attrs map[string]string) error {
+	if Validation.MaxAttributes > 0 && len(attrs) > Validation.MaxAttributes {
+		return NewInvalidProductError(
+			"attributes",
+			fmt.Sprintf("at most %d attributes are allowed", Validation.MaxAttributes),
+			len(attrs),
+		)
+	}
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		if k == "" {
+			return NewInvalidProductError(
+				"attributes",
+				"attribute names cannot be empty",
+				k,
+			)
+		}
+		max := Validation.MaxAttributeLength
+		if max > 0 && (utf8.RuneCountInString(k) > max || utf8.RuneCountInString(attrs[k]) > max) {
+			return NewInvalidProductError(
+				"attributes",
+				fmt.Sprintf("attribute names and values must be at most %d characters", max),
+				k,
+			)
+		}
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"strings"
 	"testing"
 )
 
@@ -56,6 +57,18 @@ func TestValidateProduct(t *testing.T) {
 			expectError: true,
 			errField:    "quantity",
 		},
+		{
+			name: "negative reorder level",
+			product: Product{
+				ID:           "5",
+				Name:         "Ink",
+				Price:        1,
+				Quantity:     1,
+				ReorderLevel: -1,
+			},
+			expectError: true,
+			errField:    "reorder_level",
+		},
 	}
 
 	for _, tt := range tests {
@@ -90,7 +103,7 @@ func TestProductStructFields(t *testing.T) {
 	p := Product{
 		ID:       "id",
 		Name:     "name",
-		Price:    10.5,
+		Price:    1050,
 		Quantity: 3,
 		Category: "cat",
 	}
@@ -138,13 +151,191 @@ func (m *mockProductStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockProductStore) BatchDelete(ctx context.Context, ids []string) error {
+	return nil
+}
+
 func (m *mockProductStore) List(ctx context.Context, f ListFilter) ([]Product, error) {
 	return nil, nil
 }
 
+func (m *mockProductStore) UpdateWhere(ctx context.Context, f ListFilter, pp ProductPatch) ([]Product, error) {
+	return nil, nil
+}
+
 func (m *mockProductStore) BulkImport(ctx context.Context, p []Product) error {
 	return nil
 }
 
+func (m *mockProductStore) BulkImportWithOptions(ctx context.Context, p []Product, opts BulkImportOptions) error {
+	return nil
+}
+
+func (m *mockProductStore) NeedsReorder(ctx context.Context) ([]Product, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) Watch(ctx context.Context) (<-chan ChangeEvent, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockProductStore) Categories(ctx context.Context) (map[string]int, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) AdjustPriceWhere(ctx context.Context, filter ListFilter, pct float64) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) Purge(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) Clear(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) PriceHistory(ctx context.Context, id string) ([]PriceChange, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) Stats(ctx context.Context, f ListFilter) (InventoryStats, error) {
+	return InventoryStats{}, nil
+}
+
+func (m *mockProductStore) FindDuplicates(ctx context.Context) ([]DuplicateGroup, error) {
+	return nil, nil
+}
+
 // compile-time assertion
 var _ ProductStore = (*mockProductStore)(nil)
+
+func TestValidateProduct_NameRules(t *testing.T) {
+	defer func(old ValidationConfig) { Validation = old }(Validation)
+	Validation = ValidationConfig{MaxNameLength: 5}
+
+	tests := []struct {
+		name        string
+		productName string
+		expectError bool
+	}{
+		{"one below limit", "abcd", false},
+		{"at limit", "abcde", false},
+		{"one over limit", "abcdef", true},
+		{"multibyte at limit", "ééééé", false},
+		{"multibyte over limit", "éééééé", true},
+		{"control character", "ab\x07c", true},
+		{"newline", "ab\nc", true},
+		{"invalid utf8", "ab\xffc", true},
+		{"inner space ok", "a b c", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProduct(Product{ID: "1", Name: tt.productName, Price: 1, Quantity: 1})
+			if tt.expectError {
+				ipe, ok := err.(*InvalidProductError)
+				if !ok || ipe.Field != "name" {
+					t.Fatalf("expected name InvalidProductError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefaultValidationConfig(t *testing.T) {
+	if got := DefaultValidationConfig().MaxNameLength; got != 200 {
+		t.Fatalf("expected default max name length 200, got %d", got)
+	}
+	long := make([]byte, 201)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if err := ValidateProduct(Product{Name: string(long)}); !IsInvalidProductError(err) {
+		t.Fatalf("expected default config to reject 201 characters, got %v", err)
+	}
+}
+
+func TestValidateProduct_AllowedCategories(t *testing.T) {
+	defer func(old ValidationConfig) { Validation = old }(Validation)
+	Validation = DefaultValidationConfig()
+
+	if err := ValidateProduct(Product{Name: "a", Category: "Electronis"}); err != nil {
+		t.Fatalf("empty whitelist should accept any category: %v", err)
+	}
+
+	Validation.AllowedCategories = []string{"Electronics", "Books"}
+	if err := ValidateProduct(Product{Name: "a", Category: "Books"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := ValidateProduct(Product{Name: "a", Category: "Electronis"})
+	ipe, ok := err.(*InvalidProductError)
+	if !ok || ipe.Field != "category" {
+		t.Fatalf("expected category InvalidProductError, got %v", err)
+	}
+}
+
+func TestValidateProduct_Attributes(t *testing.T) {
+	defer func(old ValidationConfig) { Validation = old }(Validation)
+	Validation = DefaultValidationConfig()
+	Validation.MaxAttributes = 2
+	Validation.MaxAttributeLength = 5
+
+	tests := []struct {
+		name        string
+		attrs       map[string]string
+		expectError bool
+	}{
+		{"none", nil, false},
+		{"at limits", map[string]string{"color": "red", "size": "large"}, false},
+		{"too many", map[string]string{"a": "1", "b": "2", "c": "3"}, true},
+		{"long key", map[string]string{"colour": "red"}, true},
+		{"long value", map[string]string{"size": "medium"}, true},
+		{"empty key", map[string]string{"": "x"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProduct(Product{Name: "a", Attributes: tt.attrs})
+			if tt.expectError {
+				ipe, ok := err.(*InvalidProductError)
+				if !ok || ipe.Field != "attributes" {
+					t.Fatalf("expected attributes InvalidProductError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateProductAll(t *testing.T) {
+	p := Product{ID: "1", Name: "", Price: -1, Quantity: -2, Currency: "XXX"}
+	errs := ValidateProductAll(p)
+	var fields []string
+	for _, err := range errs {
+		ipe, ok := err.(*InvalidProductError)
+		if !ok {
+			t.Fatalf("expected InvalidProductError, got %v", err)
+		}
+		fields = append(fields, ipe.Field)
+	}
+	if got := strings.Join(fields, ","); got != "name,price,currency,quantity" {
+		t.Fatalf("unexpected fields %q", got)
+	}
+	if first := ValidateProduct(p); first.Error() != errs[0].Error() {
+		t.Fatalf("ValidateProduct should report the first problem, got %v", first)
+	}
+	if errs := ValidateProductAll(Product{ID: "1", Name: "ok", Price: 1}); errs != nil {
+		t.Fatalf("expected no problems, got %v", errs)
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// ProgressFunc is told how many of total items a long-running store
+// operation has processed so far.
+type ProgressFunc func(done, total int)
+
+type progressKey struct{}
+
+// WithProgress returns a context carrying fn; BulkImport calls it once per
+// processed product.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the ProgressFunc attached to ctx, or nil.
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
@@ -0,0 +1,208 @@
+// Package schema implements the subset of JSON Schema used to pre-validate
+// import records: type, properties, required, items, enum, minimum, maximum,
+// minLength, maxLength and additionalProperties (boolean form).
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+//go:embed product.schema.json
+var defaultProductSchema []byte
+
+// Schema is a parsed JSON Schema document
+type Schema struct {
+	Type                 typeList           `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+}
+
+// typeList accepts both "type": "string" and "type": ["string", "null"]
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("type must be a string or array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Path   string
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+}
+
+// Parse decodes a schema document
+func Parse(b []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	return &s, nil
+}
+
+// DefaultProduct returns the embedded schema describing domain.Product
+func DefaultProduct() *Schema {
+	s, err := Parse(defaultProductSchema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ValidateJSON decodes raw and validates it against s
+func (s *Schema) ValidateJSON(raw []byte) []FieldError {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return []FieldError{{Reason: err.Error()}}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into
+// interface{}) and returns every violation found.
+func (s *Schema) Validate(v interface{}) []FieldError {
+	var errs []FieldError
+	s.validate("", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]FieldError) {
+	add := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(v) {
+		add("expected %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		add("value %v is not one of the allowed values", v)
+	}
+
+	switch val := v.(type) {
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			add("must be >= %v, got %v", *s.Minimum, val)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			add("must be <= %v, got %v", *s.Maximum, val)
+		}
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			add("length must be >= %d, got %d", *s.MinLength, n)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			add("length must be <= %d, got %d", *s.MaxLength, n)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				*errs = append(*errs, FieldError{Path: join(path, name), Reason: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, FieldError{Path: join(path, k), Reason: "is not allowed"})
+				}
+				continue
+			}
+			prop.validate(join(path, k), val[k], errs)
+		}
+	}
+}
+
+func (t typeList) matches(v interface{}) bool {
+	for _, want := range t {
+		switch want {
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		default:
+			if typeOf(v) == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func inEnum(v interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) && typeOf(e) == typeOf(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
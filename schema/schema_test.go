@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestDefaultProduct_Validate(t *testing.T) {
+	s := DefaultProduct()
+
+	cases := []struct {
+		name     string
+		record   string
+		wantPath string
+	}{
+		{"valid", `{"id":"a","name":"A","price":1.5,"quantity":2,"tags":["x"]}`, ""},
+		{"price as string", `{"id":"a","name":"A","price":"1.5"}`, "price"},
+		{"missing name", `{"id":"a"}`, "name"},
+		{"fractional quantity", `{"id":"a","name":"A","quantity":1.5}`, "quantity"},
+		{"negative price", `{"id":"a","name":"A","price":-1}`, "price"},
+		{"non-string tag", `{"id":"a","name":"A","tags":[1]}`, "tags[0]"},
+		{"not an object", `[1,2]`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := s.ValidateJSON([]byte(tc.record))
+			if tc.name == "valid" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) == 0 {
+				t.Fatalf("expected a violation")
+			}
+			if errs[0].Path != tc.wantPath {
+				t.Fatalf("expected path %q, got %q (%v)", tc.wantPath, errs[0].Path, errs[0])
+			}
+		})
+	}
+}
+
+func TestParse_CustomSchema(t *testing.T) {
+	s, err := Parse([]byte(`{"type":"object","additionalProperties":false,"properties":{"id":{"type":["string","null"]},"category":{"enum":["A","B"]}}}`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if errs := s.ValidateJSON([]byte(`{"id":null,"category":"A"}`)); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	errs := s.ValidateJSON([]byte(`{"category":"C","extra":1}`))
+	if len(errs) != 2 {
+		t.Fatalf("expected enum and additionalProperties violations, got %v", errs)
+	}
+
+	if _, err := Parse([]byte(`{"type":1}`)); err == nil {
+		t.Fatalf("expected parse error for invalid type")
+	}
+}
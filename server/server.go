@@ -0,0 +1,293 @@
+// Package server exposes a domain.ProductStore over a JSON REST API.
+package server
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewHandler returns the REST routes for s:
+//
+//	GET    /products       list, filtered by query parameters
+//	POST   /products       create (an id is generated when empty)
+//	GET    /products/{id}  fetch one product
+//	PUT    /products/{id}  replace a product
+//	DELETE /products/{id}  delete a product
+func NewHandler(s domain.ProductStore) http.Handler {
+	h := &handler{store: s}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /products", h.list)
+	mux.HandleFunc("POST /products", h.create)
+	mux.HandleFunc("GET /products/{id}", h.get)
+	mux.HandleFunc("PUT /products/{id}", h.update)
+	mux.HandleFunc("DELETE /products/{id}", h.delete)
+	return mux
+}
+
+type handler struct {
+	store domain.ProductStore
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	out, err := h.store.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	var p domain.Product
+	if err := decodeBody(r, &p); err != nil {
+		writeError(w, err)
+		return
+	}
+	if p.ID == "" {
+		id, err := util.GenerateUUIDErr()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		p.ID = id
+	}
+	if err := h.store.Create(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.respondWithStored(w, r, http.StatusCreated, p.ID)
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	h.respondWithStored(w, r, http.StatusOK, r.PathValue("id"))
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request) {
+	var p domain.Product
+	if err := decodeBody(r, &p); err != nil {
+		writeError(w, err)
+		return
+	}
+	id := r.PathValue("id")
+	if err := h.store.Update(r.Context(), id, p); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.respondWithStored(w, r, http.StatusOK, id)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithStored writes the product as the store now holds it, so
+// defaults applied by the store are visible to the client.
+func (h *handler) respondWithStored(w http.ResponseWriter, r *http.Request, status int, id string) {
+	p, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, p)
+}
+
+// parseListFilter maps query parameters onto domain.ListFilter. category and
+// tag may repeat or be comma-separated; all_tags=true requires every tag.
+// attr=key=value may repeat and every pair must match.
+func parseListFilter(q url.Values) (domain.ListFilter, error) {
+	f := domain.ListFilter{
+		Categories: splitValues(q["category"]),
+		Currency:   strings.ToUpper(q.Get("currency")),
+		SortBy:     q.Get("sort_by"),
+		Order:      q.Get("order"),
+	}
+	tags := splitValues(q["tag"])
+	allTags, err := parseBool(q, "all_tags")
+	if err != nil {
+		return f, err
+	}
+	if allTags {
+		f.TagsAll = tags
+	} else {
+		f.TagsAny = tags
+	}
+	if f.CaseInsensitive, err = parseBool(q, "ignore_case"); err != nil {
+		return f, err
+	}
+	if f.IncludeDeleted, err = parseBool(q, "include_deleted"); err != nil {
+		return f, err
+	}
+	if f.MinPrice, err = parseMoney(q, "min_price"); err != nil {
+		return f, err
+	}
+	if f.MaxPrice, err = parseMoney(q, "max_price"); err != nil {
+		return f, err
+	}
+	for _, kv := range q["attr"] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return f, badRequest("attr must be key=value")
+		}
+		if f.AttributeEquals == nil {
+			f.AttributeEquals = make(map[string]string)
+		}
+		f.AttributeEquals[k] = v
+	}
+	if f.MinQuantity, err = parseInt(q, "min_quantity"); err != nil {
+		return f, err
+	}
+	if f.MaxQuantity, err = parseInt(q, "max_quantity"); err != nil {
+		return f, err
+	}
+	if f.Limit, err = parseCount(q, "limit"); err != nil {
+		return f, err
+	}
+	if f.Offset, err = parseCount(q, "offset"); err != nil {
+		return f, err
+	}
+	if f.UpdatedAfter, err = parseTime(q, "since"); err != nil {
+		return f, err
+	}
+	if q.Get("in_stock") != "" {
+		inStock, err := parseBool(q, "in_stock")
+		if err != nil {
+			return f, err
+		}
+		f.InStock = &inStock
+	}
+	return f, nil
+}
+
+func splitValues(vals []string) []string {
+	var out []string
+	for _, v := range vals {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func parseBool(q url.Values, key string) (bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, badRequest(key + " must be true or false")
+	}
+	return b, nil
+}
+
+func parseInt(q url.Values, key string) (*int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, badRequest(key + " must be an integer")
+	}
+	return &n, nil
+}
+
+// parseCount reads a non-negative integer, 0 when key is absent
+func parseCount(q url.Values, key string) (int, error) {
+	n, err := parseInt(q, key)
+	if err != nil || n == nil {
+		return 0, err
+	}
+	if *n < 0 {
+		return 0, badRequest(key + " must be non-negative")
+	}
+	return *n, nil
+}
+
+// parseTime reads an RFC 3339 timestamp, nil when key is absent
+func parseTime(q url.Values, key string) (*time.Time, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, badRequest(key + " must be an RFC 3339 time, e.g. 2024-01-01T00:00:00Z")
+	}
+	return &t, nil
+}
+
+func parseMoney(q url.Values, key string) (*domain.Money, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	m, err := domain.ParseMoney(v)
+	if err != nil {
+		return nil, badRequest(fmt.Sprintf("%s: %v", key, err))
+	}
+	return &m, nil
+}
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+// requestError is a malformed request that never reached the store
+type requestError struct{ msg string }
+
+func (e *requestError) Error() string { return e.msg }
+
+// Code returns the envelope code for malformed requests
+func (e *requestError) Code() string { return "BAD_REQUEST" }
+
+func badRequest(msg string) error { return &requestError{msg: msg} }
+
+// statusFor translates errors into HTTP status codes; everything except
+// malformed requests goes through domain.HTTPStatus
+func statusFor(err error) int {
+	var re *requestError
+	if errors.As(err, &re) {
+		return http.StatusBadRequest
+	}
+	return domain.HTTPStatus(err)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := statusFor(err)
+	if status == http.StatusInternalServerError {
+		slog.Error("request failed", "error", err)
+	}
+	writeJSON(w, status, domain.NewErrorEnvelope(err))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/store"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := store.NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "p1", Name: "Laptop", Price: 99900, Quantity: 5, Category: "Electronics"})
+	_ = s.Create(ctx, domain.Product{ID: "p2", Name: "Pen", Price: 150, Quantity: 100, Category: "Office"})
+	srv := httptest.NewServer(NewHandler(s))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestStatusCodes(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"get", "GET", "/products/p1", "", http.StatusOK},
+		{"get missing", "GET", "/products/nope", "", http.StatusNotFound},
+		{"create", "POST", "/products", `{"id":"p3","name":"Desk","price":49.99,"quantity":2}`, http.StatusCreated},
+		{"create duplicate", "POST", "/products", `{"id":"p1","name":"Again","price":1,"quantity":1}`, http.StatusConflict},
+		{"create invalid", "POST", "/products", `{"name":"","price":1,"quantity":1}`, http.StatusBadRequest},
+		{"create malformed", "POST", "/products", `{"name":`, http.StatusBadRequest},
+		{"update", "PUT", "/products/p2", `{"name":"Pen","price":2,"quantity":90}`, http.StatusOK},
+		{"update missing", "PUT", "/products/nope", `{"name":"X","price":1,"quantity":1}`, http.StatusNotFound},
+		{"delete", "DELETE", "/products/p2", "", http.StatusNoContent},
+		{"delete missing", "DELETE", "/products/p2", "", http.StatusNotFound},
+		{"bad filter", "GET", "/products?max_quantity=lots", "", http.StatusBadRequest},
+		{"bad attr filter", "GET", "/products?attr=color", "", http.StatusBadRequest},
+		{"negative limit", "GET", "/products?limit=-1", "", http.StatusBadRequest},
+		{"bad since", "GET", "/products?since=yesterday", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(t, tt.method, srv.URL+tt.path, tt.body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("%s %s: status %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestListQueryParams(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := do(t, "GET", srv.URL+"/products?category=Electronics,Books&min_price=10&sort_by=price&order=desc", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var got []domain.Product
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "p1" || got[0].Price != 99900 {
+		t.Fatalf("unexpected list result: %+v", got)
+	}
+
+	resp = do(t, "GET", srv.URL+"/products?since=2999-01-01T00:00:00Z", "")
+	got = nil
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || len(got) != 0 {
+		t.Fatalf("expected nothing changed since 2999, got %+v (%v)", got, err)
+	}
+}
+
+func TestCreateGeneratesID(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := do(t, "POST", srv.URL+"/products", `{"name":"Chair","price":25,"quantity":3}`)
+	var p domain.Product
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if p.ID == "" || p.Currency != domain.DefaultCurrency {
+		t.Fatalf("expected generated id and stored defaults, got %+v", p)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := do(t, "POST", srv.URL+"/products", `{"id":"x","name":"X","price":1,"quantity":-1}`)
+	var env domain.ErrorEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if env.Code != domain.CodeInvalidProduct || env.Field != "quantity" {
+		t.Fatalf("unexpected envelope %+v", env)
+	}
+}
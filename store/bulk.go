@@ -0,0 +1,144 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"aexp_assesment/util"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxImportWorkers bounds the goroutines of a concurrent import
+	maxImportWorkers = 10
+	// defaultRetryBackoff is the wait before the first retry when
+	// BulkImportOptions.RetryBackoff is zero
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+// importConcurrently is the best-effort BulkImport of stores that create
+// products one at a time. It runs create for every product on a pool of up
+// to maxImportWorkers goroutines, at most opts.RateLimit a second, retrying
+// transient failures as opts allows. Failures are returned as a
+// *domain.BulkImportError listing the ids that were stored; so is a
+// cancelled ctx, whose error the BulkImportError wraps.
+func importConcurrently(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions, create func(context.Context, domain.Product) error) error {
+	limiter := util.NewLimiter(opts.RateLimit)
+
+	type result struct {
+		id  string
+		err error
+	}
+
+	jobs := make(chan domain.Product)
+	results := make(chan result, len(products))
+	tick := progressTicker(ctx, len(products))
+
+	var wg sync.WaitGroup
+
+	worker := func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p, ok := <-jobs:
+				if !ok {
+					return
+				}
+				if err := createWithRetry(ctx, p, opts, limiter, create); err != nil {
+					results <- result{id: p.ID, err: fmt.Errorf("id=%s: %w", p.ID, err)}
+				} else {
+					results <- result{id: p.ID, err: nil}
+				}
+			}
+		}
+	}
+
+	nWorkers := maxImportWorkers
+	if len(products) < nWorkers {
+		nWorkers = len(products)
+	}
+
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go worker()
+	}
+
+	// feed jobs
+	go func() {
+		defer close(jobs)
+		for _, p := range products {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- p:
+			}
+		}
+	}()
+
+	// collect results
+	var collected domain.MultiError
+	var applied []string
+	collect := func(res result) {
+		tick()
+		if res.err != nil {
+			collected.Append(res.err)
+		} else {
+			applied = append(applied, res.id)
+		}
+	}
+	received := 0
+	// check ctx before each result: select picks among ready cases at
+	// random, so a full results channel could otherwise hide a cancellation
+	for received < len(products) && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case res := <-results:
+			received++
+			collect(res)
+		}
+	}
+
+	wg.Wait()
+	if received < len(products) {
+		// cancelled: keep the results the workers sent on the way out so
+		// the caller still learns what was stored
+		for len(results) > 0 {
+			collect(<-results)
+		}
+		collected.Append(ctx.Err())
+	}
+	sort.Strings(applied)
+	return domain.NewBulkImportError(applied, &collected)
+}
+
+// createWithRetry calls create until it succeeds, fails with an error
+// domain.IsRetryable rejects, or has been retried opts.MaxRetries times. The
+// wait between attempts starts at opts.RetryBackoff and doubles each time;
+// every attempt also waits its turn on limiter.
+func createWithRetry(ctx context.Context, p domain.Product, opts domain.BulkImportOptions, limiter *util.Limiter, create func(context.Context, domain.Product) error) error {
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := create(ctx, p)
+		if err == nil || attempt >= opts.MaxRetries || !domain.IsRetryable(err) {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyCreator fails the first failures creates of every product with err,
+// standing in for a remote store
+type flakyCreator struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    map[string]int
+	stored   map[string]bool
+}
+
+func newFlakyCreator(failures int, err error) *flakyCreator {
+	return &flakyCreator{failures: failures, err: err, calls: map[string]int{}, stored: map[string]bool{}}
+}
+
+func (f *flakyCreator) create(ctx context.Context, p domain.Product) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[p.ID]++
+	if f.calls[p.ID] <= f.failures {
+		return f.err
+	}
+	f.stored[p.ID] = true
+	return nil
+}
+
+func TestImportConcurrently_RetriesTransientErrors(t *testing.T) {
+	products := []domain.Product{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	fake := newFlakyCreator(2, domain.NewTransientError(errors.New("connection reset")))
+	opts := domain.BulkImportOptions{MaxRetries: 3, RetryBackoff: time.Millisecond}
+
+	if err := importConcurrently(context.Background(), products, opts, fake.create); err != nil {
+		t.Fatalf("expected the retries to succeed, got %v", err)
+	}
+	for _, p := range products {
+		if fake.calls[p.ID] != 3 || !fake.stored[p.ID] {
+			t.Fatalf("expected %s stored on the third attempt, got %d call(s)", p.ID, fake.calls[p.ID])
+		}
+	}
+}
+
+func TestImportConcurrently_GivesUpAfterMaxRetries(t *testing.T) {
+	products := []domain.Product{{ID: "a"}}
+	fake := newFlakyCreator(2, domain.NewTransientError(errors.New("connection reset")))
+	opts := domain.BulkImportOptions{MaxRetries: 1, RetryBackoff: time.Millisecond}
+
+	err := importConcurrently(context.Background(), products, opts, fake.create)
+	var bie *domain.BulkImportError
+	if !errors.As(err, &bie) || len(bie.Failed.Errors()) != 1 {
+		t.Fatalf("expected the product to fail after one retry, got %v", err)
+	}
+	if fake.calls["a"] != 2 {
+		t.Fatalf("expected 2 attempts, got %d", fake.calls["a"])
+	}
+}
+
+func TestImportConcurrently_DoesNotRetryDomainErrors(t *testing.T) {
+	products := []domain.Product{{ID: "a"}}
+	fake := newFlakyCreator(1, domain.NewDuplicateProductError("a"))
+	opts := domain.BulkImportOptions{MaxRetries: 3, RetryBackoff: time.Millisecond}
+
+	err := importConcurrently(context.Background(), products, opts, fake.create)
+	if !domain.IsDuplicateProductError(err) || fake.calls["a"] != 1 {
+		t.Fatalf("expected one attempt and the duplicate reported, got %d attempt(s): %v", fake.calls["a"], err)
+	}
+}
+
+func TestImportConcurrently_ReportsAppliedOnCancel(t *testing.T) {
+	s := NewInMemoryStore()
+	products := make([]domain.Product, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		products = append(products, domain.Product{ID: "c-" + strconv.Itoa(i), Name: "X", Price: 1, Quantity: 1})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// cancel once 10 products are stored; creates not yet started then
+	// see the cancelled ctx and are skipped
+	var created atomic.Int32
+	create := func(ctx context.Context, p domain.Product) error {
+		err := s.Create(ctx, p)
+		if created.Add(1) == 10 {
+			cancel()
+		}
+		return err
+	}
+
+	err := importConcurrently(ctx, products, domain.BulkImportOptions{}, create)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var bie *domain.BulkImportError
+	if !errors.As(err, &bie) {
+		t.Fatalf("expected a *domain.BulkImportError, got %T", err)
+	}
+	stored, _ := s.List(context.Background(), domain.ListFilter{})
+	ids := make([]string, len(stored))
+	for i, p := range stored {
+		ids[i] = p.ID
+	}
+	sort.Strings(ids)
+	if len(ids) < 10 || len(ids) == len(products) {
+		t.Fatalf("expected the import to stop part way, %d of %d stored", len(ids), len(products))
+	}
+	if !reflect.DeepEqual(bie.Applied, ids) {
+		t.Fatalf("Applied %v does not match the stored ids %v", bie.Applied, ids)
+	}
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultCacheSize is the number of products a CachingStore keeps
+const defaultCacheSize = 1024
+
+// CachingStore is a read-through cache in front of any domain.ProductStore.
+// Get results are kept in an LRU keyed by id for ttl; writes go to the inner
+// store and drop the affected entries. List and the other reads bypass it.
+type CachingStore struct {
+	inner domain.ProductStore
+	ttl   time.Duration
+	size  int
+	now   func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+	// gen counts invalidations. A read from inner is cached only if gen
+	// has not moved since before the read, so a value read just before a
+	// write cannot be cached after the write dropped its entry.
+	gen uint64
+}
+
+type cacheEntry struct {
+	product domain.Product
+	expires time.Time
+}
+
+// compile-time assertion
+var (
+	_ domain.ProductStore = (*CachingStore)(nil)
+	_ domain.StoreCloser  = (*CachingStore)(nil)
+)
+
+// NewCachingStore wraps inner with an LRU of up to 1024 products, each
+// served from cache for at most ttl.
+func NewCachingStore(inner domain.ProductStore, ttl time.Duration) *CachingStore {
+	return &CachingStore{
+		inner: inner,
+		ttl:   ttl,
+		size:  defaultCacheSize,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (s *CachingStore) lookup(id string) (domain.Product, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[id]
+	if !ok {
+		return domain.Product{}, false
+	}
+	e := el.Value.(*cacheEntry)
+	if s.now().After(e.expires) {
+		s.order.Remove(el)
+		delete(s.items, id)
+		return domain.Product{}, false
+	}
+	s.order.MoveToFront(el)
+	return e.product.Clone(), true
+}
+
+func (s *CachingStore) generation() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen
+}
+
+// put caches p unless an invalidation happened since gen was taken
+func (s *CachingStore) put(p domain.Product, gen uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.gen {
+		return
+	}
+	e := &cacheEntry{product: p.Clone(), expires: s.now().Add(s.ttl)}
+	if el, ok := s.items[p.ID]; ok {
+		el.Value = e
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[p.ID] = s.order.PushFront(e)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*cacheEntry).product.ID)
+	}
+}
+
+func (s *CachingStore) invalidate(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	for _, id := range ids {
+		if el, ok := s.items[id]; ok {
+			s.order.Remove(el)
+			delete(s.items, id)
+		}
+	}
+}
+
+func (s *CachingStore) invalidateCategory(category string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	for id, el := range s.items {
+		if el.Value.(*cacheEntry).product.Category == category {
+			s.order.Remove(el)
+			delete(s.items, id)
+		}
+	}
+}
+
+func (s *CachingStore) invalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	s.order.Init()
+	clear(s.items)
+}
+
+func (s *CachingStore) Create(ctx context.Context, product domain.Product) error {
+	if err := s.inner.Create(ctx, product); err != nil {
+		return err
+	}
+	// re-read so defaults applied by the inner store are cached too
+	gen := s.generation()
+	if p, err := s.inner.Get(ctx, product.ID); err == nil {
+		s.put(p, gen)
+	}
+	return nil
+}
+
+func (s *CachingStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Product{}, err
+	}
+	if p, ok := s.lookup(id); ok {
+		return p, nil
+	}
+	gen := s.generation()
+	p, err := s.inner.Get(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	s.put(p, gen)
+	return p, nil
+}
+
+func (s *CachingStore) Update(ctx context.Context, id string, product domain.Product) error {
+	defer s.invalidate(id)
+	return s.inner.Update(ctx, id, product)
+}
+
+func (s *CachingStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	out, err := s.inner.UpdateWhere(ctx, filter, patch)
+	for _, p := range out {
+		s.invalidate(p.ID)
+	}
+	return out, err
+}
+
+func (s *CachingStore) Delete(ctx context.Context, id string) error {
+	defer s.invalidate(id)
+	return s.inner.Delete(ctx, id)
+}
+
+func (s *CachingStore) BatchDelete(ctx context.Context, ids []string) error {
+	defer s.invalidate(ids...)
+	return s.inner.BatchDelete(ctx, ids)
+}
+
+func (s *CachingStore) Restore(ctx context.Context, id string) error {
+	defer s.invalidate(id)
+	return s.inner.Restore(ctx, id)
+}
+
+// Purge passes through: soft-deleted products are never cached
+func (s *CachingStore) Purge(ctx context.Context) (int, error) {
+	return s.inner.Purge(ctx)
+}
+
+func (s *CachingStore) Clear(ctx context.Context) (int, error) {
+	defer s.invalidateAll()
+	return s.inner.Clear(ctx)
+}
+
+func (s *CachingStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+	return s.inner.List(ctx, filter)
+}
+
+func (s *CachingStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	return s.inner.BulkImport(ctx, products)
+}
+
+func (s *CachingStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	return s.inner.BulkImportWithOptions(ctx, products, opts)
+}
+
+// RenameCategory drops every cached product of category from
+func (s *CachingStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	defer s.invalidateCategory(from)
+	return s.inner.RenameCategory(ctx, from, to)
+}
+
+// AdjustPriceWhere drops the whole cache: only a count comes back, not
+// which products changed
+func (s *CachingStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	defer s.invalidateAll()
+	return s.inner.AdjustPriceWhere(ctx, filter, pct)
+}
+
+func (s *CachingStore) Categories(ctx context.Context) (map[string]int, error) {
+	return s.inner.Categories(ctx)
+}
+
+func (s *CachingStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	return s.inner.PriceHistory(ctx, id)
+}
+
+func (s *CachingStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	return s.inner.Stats(ctx, filter)
+}
+
+func (s *CachingStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	return s.inner.FindDuplicates(ctx)
+}
+
+func (s *CachingStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	return s.inner.NeedsReorder(ctx)
+}
+
+func (s *CachingStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	return s.inner.Watch(ctx)
+}
+
+func (s *CachingStore) Flush(ctx context.Context) error {
+	return domain.FlushStore(ctx, s.inner)
+}
+
+func (s *CachingStore) Close(ctx context.Context) error {
+	return domain.CloseStore(ctx, s.inner)
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"testing"
+	"time"
+)
+
+// countingStore counts Get calls that reach the wrapped store
+type countingStore struct {
+	domain.ProductStore
+	gets int
+}
+
+func (c *countingStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	c.gets++
+	return c.ProductStore.Get(ctx, id)
+}
+
+func newTestCache(t *testing.T, ttl time.Duration) (*CachingStore, *countingStore) {
+	t.Helper()
+	inner := &countingStore{ProductStore: NewInMemoryStore()}
+	return NewCachingStore(inner, ttl), inner
+}
+
+func TestCachingStore_GetIsCached(t *testing.T) {
+	s, inner := newTestCache(t, time.Minute)
+	ctx := context.Background()
+	_ = inner.ProductStore.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1})
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Get(ctx, "c1"); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	if inner.gets != 1 {
+		t.Fatalf("expected 1 inner Get, got %d", inner.gets)
+	}
+}
+
+func TestCachingStore_UpdateInvalidates(t *testing.T) {
+	s, _ := newTestCache(t, time.Minute)
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "Old", Price: 1, Quantity: 1})
+	_, _ = s.Get(ctx, "c1")
+
+	if err := s.Update(ctx, "c1", domain.Product{Name: "New", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	p, err := s.Get(ctx, "c1")
+	if err != nil || p.Name != "New" {
+		t.Fatalf("expected fresh product after Update, got %+v (%v)", p, err)
+	}
+
+	if err := s.Delete(ctx, "c1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "c1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected not found after Delete, got %v", err)
+	}
+}
+
+func TestCachingStore_ClearInvalidates(t *testing.T) {
+	s, _ := newTestCache(t, time.Minute)
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "c2", Name: "B", Price: 1, Quantity: 1})
+
+	if n, err := s.Clear(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 cleared, got %d (%v)", n, err)
+	}
+	if _, err := s.Get(ctx, "c1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected not found after Clear, got %v", err)
+	}
+}
+
+func TestCachingStore_AdjustPriceWhereInvalidates(t *testing.T) {
+	s, _ := newTestCache(t, time.Minute)
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1000, Quantity: 1, Category: "Sale"})
+	_, _ = s.Get(ctx, "c1")
+
+	if n, err := s.AdjustPriceWhere(ctx, domain.ListFilter{Category: "Sale"}, -10); err != nil || n != 1 {
+		t.Fatalf("expected 1 adjusted, got %d (%v)", n, err)
+	}
+	if p, _ := s.Get(ctx, "c1"); p.Price != 900 {
+		t.Fatalf("expected the adjusted price after AdjustPriceWhere, got %d", p.Price)
+	}
+}
+
+func TestCachingStore_TTLAndEviction(t *testing.T) {
+	s, inner := newTestCache(t, time.Minute)
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+	s.size = 2
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		_ = inner.ProductStore.Create(ctx, domain.Product{ID: id, Name: id, Price: 1, Quantity: 1})
+	}
+
+	_, _ = s.Get(ctx, "a")
+	_, _ = s.Get(ctx, "b")
+	_, _ = s.Get(ctx, "a") // a is now most recent
+	_, _ = s.Get(ctx, "c") // evicts b
+	inner.gets = 0
+	_, _ = s.Get(ctx, "a")
+	_, _ = s.Get(ctx, "b")
+	if inner.gets != 1 {
+		t.Fatalf("expected only the evicted entry to miss, got %d inner Gets", inner.gets)
+	}
+
+	now = now.Add(2 * time.Minute)
+	inner.gets = 0
+	_, _ = s.Get(ctx, "a")
+	if inner.gets != 1 {
+		t.Fatalf("expected expired entry to be refetched, got %d inner Gets", inner.gets)
+	}
+}
+
+func TestCachingStore_ReturnsCopies(t *testing.T) {
+	c := NewCachingStore(NewInMemoryStore(), time.Minute)
+	ctx := context.Background()
+	_ = c.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1, Tags: []string{"sale"}})
+
+	got, _ := c.Get(ctx, "c1")
+	got.Tags[0] = "mutated"
+	if p, _ := c.Get(ctx, "c1"); p.Tags[0] != "sale" {
+		t.Fatalf("cached product was changed through a returned copy: %v", p.Tags)
+	}
+}
+
+// pausingStore holds a Get that has already read the inner store until
+// release is closed, so a write can land in between
+type pausingStore struct {
+	domain.ProductStore
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (s *pausingStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	p, err := s.ProductStore.Get(ctx, id)
+	if s.read != nil {
+		close(s.read)
+		s.read = nil
+		<-s.release
+	}
+	return p, err
+}
+
+func TestCachingStore_GetRacingUpdateDoesNotCacheStaleValue(t *testing.T) {
+	inner := &pausingStore{ProductStore: NewInMemoryStore()}
+	s := NewCachingStore(inner, time.Minute)
+	ctx := context.Background()
+	if err := inner.ProductStore.Create(ctx, domain.Product{ID: "c1", Name: "Old", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	read := make(chan struct{})
+	inner.read, inner.release = read, make(chan struct{})
+
+	done := make(chan domain.Product)
+	go func() {
+		p, _ := s.Get(ctx, "c1")
+		done <- p
+	}()
+	<-read // the Get has the old value but has not cached it yet
+	if err := s.Update(ctx, "c1", domain.Product{Name: "New", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	close(inner.release)
+	if p := <-done; p.Name != "Old" {
+		t.Fatalf("expected the racing Get to return what it read, got %q", p.Name)
+	}
+
+	if p, err := s.Get(ctx, "c1"); err != nil || p.Name != "New" {
+		t.Fatalf("expected the update after a racing Get, got %+v (%v)", p, err)
+	}
+}
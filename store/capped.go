@@ -0,0 +1,155 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"sync"
+)
+
+// CappedStore wraps any domain.ProductStore and refuses writes that would
+// take it past a maximum number of live products with a
+// *domain.LimitExceededError. Create, BulkImport and Restore are checked;
+// reads and the other writes, which never add a live product, pass through.
+// The cap holds for writes made through this store only.
+type CappedStore struct {
+	inner domain.ProductStore
+	max   int
+
+	// mu serialises the checked writes so two of them cannot both pass the
+	// count before either is stored
+	mu sync.Mutex
+}
+
+// compile-time assertion
+var (
+	_ domain.ProductStore = (*CappedStore)(nil)
+	_ domain.StoreCloser  = (*CappedStore)(nil)
+)
+
+// NewCappedStore wraps inner so it holds at most max live products
+func NewCappedStore(inner domain.ProductStore, max int) *CappedStore {
+	return &CappedStore{inner: inner, max: max}
+}
+
+// checkRoom returns a *domain.LimitExceededError unless n more live products
+// fit under the cap. The caller holds s.mu.
+func (s *CappedStore) checkRoom(ctx context.Context, n int) error {
+	stats, err := s.inner.Stats(ctx, domain.ListFilter{})
+	if err != nil {
+		return err
+	}
+	if stats.Count+n > s.max {
+		return domain.NewLimitExceededError(s.max, stats.Count, n)
+	}
+	return nil
+}
+
+func (s *CappedStore) Create(ctx context.Context, product domain.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkRoom(ctx, 1); err != nil {
+		return err
+	}
+	return s.inner.Create(ctx, product)
+}
+
+// BulkImport rejects the whole batch, storing nothing, when adding every
+// product in it would pass the cap, even if some would fail anyway.
+func (s *CappedStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	return s.BulkImportWithOptions(ctx, products, domain.DefaultBulkImportOptions)
+}
+
+// BulkImportWithOptions checks the cap like BulkImport, whatever opts.Atomic
+func (s *CappedStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkRoom(ctx, len(products)); err != nil {
+		return err
+	}
+	return s.inner.BulkImportWithOptions(ctx, products, opts)
+}
+
+// Restore counts as adding a product unless id is already live
+func (s *CappedStore) Restore(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Get only finds live products
+	if _, err := s.inner.Get(ctx, id); domain.IsProductNotFoundError(err) {
+		if err := s.checkRoom(ctx, 1); err != nil {
+			return err
+		}
+	}
+	return s.inner.Restore(ctx, id)
+}
+
+func (s *CappedStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	return s.inner.Get(ctx, id)
+}
+
+func (s *CappedStore) Update(ctx context.Context, id string, product domain.Product) error {
+	return s.inner.Update(ctx, id, product)
+}
+
+func (s *CappedStore) Delete(ctx context.Context, id string) error {
+	return s.inner.Delete(ctx, id)
+}
+
+func (s *CappedStore) BatchDelete(ctx context.Context, ids []string) error {
+	return s.inner.BatchDelete(ctx, ids)
+}
+
+func (s *CappedStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+	return s.inner.List(ctx, filter)
+}
+
+func (s *CappedStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	return s.inner.UpdateWhere(ctx, filter, patch)
+}
+
+func (s *CappedStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	return s.inner.NeedsReorder(ctx)
+}
+
+func (s *CappedStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	return s.inner.Watch(ctx)
+}
+
+func (s *CappedStore) Purge(ctx context.Context) (int, error) {
+	return s.inner.Purge(ctx)
+}
+
+func (s *CappedStore) Clear(ctx context.Context) (int, error) {
+	return s.inner.Clear(ctx)
+}
+
+func (s *CappedStore) Categories(ctx context.Context) (map[string]int, error) {
+	return s.inner.Categories(ctx)
+}
+
+func (s *CappedStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	return s.inner.RenameCategory(ctx, from, to)
+}
+
+func (s *CappedStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	return s.inner.AdjustPriceWhere(ctx, filter, pct)
+}
+
+func (s *CappedStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	return s.inner.PriceHistory(ctx, id)
+}
+
+func (s *CappedStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	return s.inner.Stats(ctx, filter)
+}
+
+func (s *CappedStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	return s.inner.FindDuplicates(ctx)
+}
+
+func (s *CappedStore) Flush(ctx context.Context) error {
+	return domain.FlushStore(ctx, s.inner)
+}
+
+func (s *CappedStore) Close(ctx context.Context) error {
+	return domain.CloseStore(ctx, s.inner)
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"testing"
+)
+
+func TestCappedStore_RefusesWritesPastTheLimit(t *testing.T) {
+	ctx := context.Background()
+	s := NewCappedStore(NewInMemoryStoreWithOptions(Options{SoftDelete: true}), 2)
+
+	if err := s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1}); err != nil {
+		t.Fatal(err)
+	}
+	batch := []domain.Product{{ID: "c2", Name: "B", Price: 1}, {ID: "c3", Name: "C", Price: 1}}
+	if err := s.BulkImport(ctx, batch); !domain.IsLimitExceededError(err) {
+		t.Fatalf("expected the batch to be refused, got %v", err)
+	}
+	if stats, _ := s.Stats(ctx, domain.ListFilter{}); stats.Count != 1 {
+		t.Fatalf("expected nothing from the refused batch stored, got %d product(s)", stats.Count)
+	}
+
+	if err := s.BulkImport(ctx, batch[:1]); err != nil {
+		t.Fatalf("expected a batch that fits to be stored, got %v", err)
+	}
+	if err := s.Create(ctx, domain.Product{ID: "c4", Name: "D", Price: 1}); !domain.IsLimitExceededError(err) {
+		t.Fatalf("expected create at the limit to fail, got %v", err)
+	}
+
+	// reads and updates still work at the limit, and deleting makes room
+	if _, err := s.List(ctx, domain.ListFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, "c1", domain.Product{Name: "A2", Price: 2}); err != nil {
+		t.Fatalf("expected update at the limit to work, got %v", err)
+	}
+	if err := s.Delete(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, domain.Product{ID: "c4", Name: "D", Price: 1}); err != nil {
+		t.Fatalf("expected create after a delete to work, got %v", err)
+	}
+
+	// restoring the soft-deleted product would make three
+	if err := s.Restore(ctx, "c1"); !domain.IsLimitExceededError(err) {
+		t.Fatalf("expected restore past the limit to fail, got %v", err)
+	}
+	if err := s.Restore(ctx, "c4"); err != nil {
+		t.Fatalf("expected restoring a live product to do nothing, got %v", err)
+	}
+}
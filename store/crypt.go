@@ -0,0 +1,91 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// encryptedMagic starts every file FileStore encrypts. It is followed by
+// the GCM nonce and then the sealed content.
+var encryptedMagic = []byte("AEXPENC1")
+
+var (
+	// ErrEncrypted is returned when loading an encrypted file without a key
+	ErrEncrypted = errors.New("store file is encrypted: an encryption key is required")
+	// ErrWrongKey is returned when an encrypted file does not open with the
+	// key given. The file is left alone, even with Options.RecoverCorrupt.
+	ErrWrongKey = errors.New("cannot decrypt store file: wrong encryption key or damaged file")
+)
+
+// newAEAD returns AES-GCM for key, which must be 16, 24 or 32 bytes long,
+// or nil for no key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts b under a fresh random nonce
+func seal(aead cipher.AEAD, b []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(b)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, b, encryptedMagic), nil
+}
+
+// unseal reverses seal. Content without the magic is returned as is, so a
+// plaintext file can be opened with a key and is encrypted on its next save.
+func unseal(aead cipher.AEAD, b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, encryptedMagic) {
+		return b, nil
+	}
+	if aead == nil {
+		return nil, ErrEncrypted
+	}
+	b = b[len(encryptedMagic):]
+	if len(b) < aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	out, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], encryptedMagic)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return out, nil
+}
+
+// Encrypt seals b under key the way FileStore seals its file, so that other
+// files holding store content, such as backups, are protected alike. The
+// result opens with Decrypt, or as a store file, under the same key.
+func Encrypt(key, b []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if aead == nil {
+		return nil, errors.New("encrypt: no key given")
+	}
+	return seal(aead, b)
+}
+
+// Decrypt reverses Encrypt. Like a store file, content that was never
+// encrypted is returned as is, and encrypted content without a key fails
+// with ErrEncrypted.
+func Decrypt(key, b []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return unseal(aead, b)
+}
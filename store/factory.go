@@ -3,20 +3,91 @@ package store
 import (
 	"aexp_assesment/domain"
 	"fmt"
+	"net/url"
+	"strings"
 )
 
 // NewStore constructs a domain.ProductStore by kind: "memory" or "file".
 // For file store, provide the file path in path; for memory, path is ignored.
+// kind may instead be a DSN (see ParseDSN), which then also names the path.
 func NewStore(kind, path string) (domain.ProductStore, error) {
+	return NewStoreWithOptions(kind, path, Options{})
+}
+
+// NewStoreWithOptions is NewStore using opts
+func NewStoreWithOptions(kind, path string, opts Options) (domain.ProductStore, error) {
+	kind, path, err := ResolveStore(kind, path)
+	if err != nil {
+		return nil, err
+	}
 	switch kind {
 	case "memory", "mem":
-		return NewInMemoryStore(), nil
+		return NewInMemoryStoreWithOptions(opts), nil
 	case "file":
 		if path == "" {
 			return nil, fmt.Errorf("file path required for file store")
 		}
-		return NewFileStore(path)
+		return NewFileStoreWithOptions(path, opts)
+	case "sqlite", "redis":
+		return nil, fmt.Errorf("store kind %s is not available in this build", kind)
 	default:
 		return nil, fmt.Errorf("unknown store kind: %s", kind)
 	}
 }
+
+// NewStoreFromDSN constructs the store dsn names, e.g. "memory://" or
+// "file://data/products.json"
+func NewStoreFromDSN(dsn string, opts Options) (domain.ProductStore, error) {
+	if !isDSN(dsn) {
+		return nil, fmt.Errorf("store DSN %q: want <kind>://<location>", dsn)
+	}
+	return NewStoreWithOptions(dsn, "", opts)
+}
+
+// ResolveStore returns the kind and path a store setting selects: those
+// ParseDSN finds when kindOrDSN is a DSN, which overrides path, else
+// kindOrDSN and path as given.
+func ResolveStore(kindOrDSN, path string) (kind, p string, err error) {
+	if !isDSN(kindOrDSN) {
+		return kindOrDSN, path, nil
+	}
+	return ParseDSN(kindOrDSN)
+}
+
+// ParseDSN splits a store DSN, <kind>://<location>, into its kind and
+// location. For files the location is the path, relative unless it starts
+// with a slash: "file://data/products.json" and "file:///var/lib/inv.json".
+// For other kinds it is everything after "//", e.g. "localhost:6379/0" for
+// "redis://localhost:6379/0". The location may be %-escaped; a raw "?" or
+// "#" is rejected, as query strings and fragments are not supported.
+func ParseDSN(dsn string) (kind, path string, err error) {
+	kind, rest, ok := strings.Cut(dsn, ":")
+	if !ok || kind == "" || strings.ContainsAny(kind, "/\\ ") {
+		return "", "", fmt.Errorf("store DSN %q: want <kind>://<location>", dsn)
+	}
+	if strings.ContainsAny(rest, "?#") {
+		return "", "", fmt.Errorf("store DSN %q: query strings and fragments are not supported", dsn)
+	}
+	path, err = url.PathUnescape(strings.TrimPrefix(rest, "//"))
+	if err != nil {
+		return "", "", fmt.Errorf("store DSN %q: %w", dsn, err)
+	}
+	return strings.ToLower(kind), path, nil
+}
+
+// dsnEscaper escapes the characters ParseDSN would not read back literally
+var dsnEscaper = strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23")
+
+// DSN returns the DSN for a store of kind at path, the form ParseDSN reads
+// back; the --store and --store-file flags are translated through it
+func DSN(kind, path string) string {
+	if kind != "file" {
+		path = ""
+	}
+	return kind + "://" + dsnEscaper.Replace(path)
+}
+
+// isDSN reports whether s is a DSN rather than a bare kind such as "file"
+func isDSN(s string) bool {
+	return strings.Contains(s, ":")
+}
@@ -1,7 +1,11 @@
 package store
 
 import (
+	"aexp_assesment/domain"
+	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +31,71 @@ func TestNewStoreFactory_MemoryAndFile(t *testing.T) {
 		t.Fatal("expected non-nil store for file")
 	}
 }
+
+func TestParseDSN(t *testing.T) {
+	cases := []struct {
+		dsn, kind, path string
+		wantErr         bool
+	}{
+		{"file://data/inv.json", "file", "data/inv.json", false},
+		{"file:///var/lib/inv.json", "file", "/var/lib/inv.json", false},
+		{"FILE://data/my%20store.json", "file", "data/my store.json", false},
+		{"memory://", "memory", "", false},
+		{"sqlite://data/inv.db", "sqlite", "data/inv.db", false},
+		{"redis://localhost:6379/0", "redis", "localhost:6379/0", false},
+		{"file", "", "", true},
+		{"://data/inv.json", "", "", true},
+		{"file://data/inv.json?mode=ro", "", "", true},
+		{"file://data/bad%zz.json", "", "", true},
+	}
+	for _, tc := range cases {
+		kind, path, err := ParseDSN(tc.dsn)
+		if (err != nil) != tc.wantErr || kind != tc.kind || path != tc.path {
+			t.Errorf("ParseDSN(%q) = %q, %q, %v; want %q, %q, error %v", tc.dsn, kind, path, err, tc.kind, tc.path, tc.wantErr)
+		}
+	}
+}
+
+func TestDSNRoundTrip(t *testing.T) {
+	for _, path := range []string{"data/products.json", "/abs/inv.json.gz", "odd #1 ?.json", "100%.json"} {
+		kind, got, err := ParseDSN(DSN("file", path))
+		if err != nil || kind != "file" || got != path {
+			t.Errorf("round trip of %q gave %q, %q, %v", path, kind, got, err)
+		}
+	}
+	if got := DSN("memory", "ignored.json"); got != "memory://" {
+		t.Errorf("expected the memory DSN to drop the path, got %q", got)
+	}
+}
+
+func TestNewStoreFromDSN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dsn store.json")
+	st, err := NewStoreFromDSN(DSN("file", path), Options{})
+	if err != nil {
+		t.Fatalf("NewStoreFromDSN failed: %v", err)
+	}
+	if err := st.Create(context.Background(), domain.Product{ID: "d1", Name: "DSN", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the store file at %s: %v", path, err)
+	}
+
+	// a DSN passed as the kind wins over the path argument
+	if st, err := NewStore("memory://", path); err != nil {
+		t.Fatalf("NewStore with a DSN failed: %v", err)
+	} else if _, ok := st.(*InMemoryStore); !ok {
+		t.Fatalf("expected a memory store, got %T", st)
+	}
+
+	for dsn, want := range map[string]string{
+		"redis://localhost:6379/0": "not available",
+		"bogus://x":                "unknown store kind",
+		"file://":                  "file path required",
+		"memory":                   "want <kind>://",
+	} {
+		if _, err := NewStoreFromDSN(dsn, Options{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewStoreFromDSN(%q): expected an error containing %q, got %v", dsn, want, err)
+		}
+	}
+}
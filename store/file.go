@@ -2,35 +2,99 @@ package store
 
 import (
 	"aexp_assesment/domain"
+	"aexp_assesment/util"
+	"compress/gzip"
 	"context"
+	"crypto/cipher"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
-// FileStore is a JSON file-backed implementation of domain.ProductStore
+// FileStore is a JSON file-backed implementation of domain.ProductStore. A
+// path ending in .gz is written gzip-compressed; either form is read.
 type FileStore struct {
 	mu       sync.RWMutex
 	products map[string]domain.Product
 	path     string
+	gzip     bool
+	aead     cipher.AEAD // nil unless Options.EncryptionKey
+	version  int         // format version of the file as loaded
+	watchers watchers
+	opts     Options
+
+	// pending SaveDelay flush; guarded by mu
+	dirty bool
+	timer *time.Timer
+
+	// records in the Options.Journal file since the last full save; guarded by mu
+	journaled int
+
+	// nil unless Options.TrackPriceHistory; guarded by mu. historyOnDisk is
+	// the history file content last read or written by this store.
+	history       priceHistory
+	historyOnDisk []byte
+
+	// Options.WatchFile state; guarded by mu. onDisk is the file content
+	// last read or written by this store.
+	fsw       *fsnotify.Watcher
+	watchDone chan struct{}
+	onDisk    []byte
 }
 
 // compile-time assertion
-var _ domain.ProductStore = (*FileStore)(nil)
+var (
+	_ domain.ProductStore = (*FileStore)(nil)
+	_ domain.StoreCloser  = (*FileStore)(nil)
+)
 
 // NewFileStore constructs a FileStore at the given path. If the file exists it will be loaded.
 func NewFileStore(path string) (*FileStore, error) {
+	return NewFileStoreWithOptions(path, Options{})
+}
+
+// NewFileStoreWithOptions is NewFileStore using opts
+func NewFileStoreWithOptions(path string, opts Options) (*FileStore, error) {
+	if opts.Journal && len(opts.EncryptionKey) > 0 {
+		return nil, errors.New("the journal is not encrypted: Journal cannot be combined with EncryptionKey")
+	}
+	aead, err := newAEAD(opts.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
 	s := &FileStore{
 		products: make(map[string]domain.Product),
 		path:     path,
+		gzip:     strings.HasSuffix(path, ".gz"),
+		aead:     aead,
+		opts:     opts,
+	}
+	// the history is loaded first so the journal can replay changes onto it
+	if opts.TrackPriceHistory {
+		h, raw, err := readPriceHistory(s.historyPath(), aead)
+		if err != nil {
+			return nil, fmt.Errorf("load price history: %w", err)
+		}
+		s.history, s.historyOnDisk = h, raw
 	}
 	if err := s.loadFromFile(); err != nil {
 		return nil, err
 	}
+	if opts.WatchFile {
+		if err := s.startWatch(); err != nil {
+			return nil, fmt.Errorf("watch %s: %w", path, err)
+		}
+	}
 	return s, nil
 }
 
@@ -38,30 +102,176 @@ func (s *FileStore) loadFromFile() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	b, err := ioutil.ReadFile(s.path)
+	list, version, err := s.readProducts(s.path)
+	s.version = version
+	if err != nil && s.opts.RecoverCorrupt {
+		list, err = s.recoverFile(err)
+	}
+	// no file yet is fine; a journal may still hold every product
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, p := range list {
+		s.products[p.ID] = p
+	}
+	return s.replayJournal()
+}
+
+// readProducts decodes the product list at path and returns it with the
+// file's format version; an empty file holds none
+func (s *FileStore) readProducts(path string) ([]domain.Product, int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.decodeProducts(b)
+}
+
+// encode turns the serialised product list into file content: compressed
+// for a .gz path, then encrypted under Options.EncryptionKey
+func (s *FileStore) encode(b []byte) ([]byte, error) {
+	var err error
+	if s.gzip {
+		if b, err = util.Gzip(b); err != nil {
+			return nil, err
+		}
+	}
+	if s.aead != nil {
+		return seal(s.aead, b)
+	}
+	return b, nil
+}
+
+// decodeProducts parses file content written by encode, or plain JSON, and
+// returns the products with the format version
+func (s *FileStore) decodeProducts(b []byte) ([]domain.Product, int, error) {
+	return decodeFile(s.aead, b)
+}
+
+// decodeFile is decodeProducts for a store with cipher aead, or none
+func decodeFile(aead cipher.AEAD, b []byte) ([]domain.Product, int, error) {
+	b, err := unseal(aead, b)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// no file yet; that's fine
-			return nil
+		return nil, 0, err
+	}
+	if b, err = util.MaybeGunzip(b); err != nil {
+		return nil, 0, err
+	}
+	return parseFileContent(b)
+}
+
+// isCorrupt reports whether err means the file was read but is not a valid
+// product list, as after a crash mid-write
+func isCorrupt(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) ||
+		errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// recoverFile handles loadErr under Options.RecoverCorrupt. A corrupt file is
+// moved aside to path.corrupt; then the temp file left by an interrupted
+// save, if it holds a valid list, takes its place. Otherwise the store starts
+// empty. Errors other than a missing or corrupt file are returned unchanged.
+// Callers hold s.mu.
+func (s *FileStore) recoverFile(loadErr error) ([]domain.Product, error) {
+	if !os.IsNotExist(loadErr) {
+		if !isCorrupt(loadErr) {
+			return nil, loadErr
+		}
+		corrupt := s.path + ".corrupt"
+		if err := os.Rename(s.path, corrupt); err != nil {
+			return nil, fmt.Errorf("move corrupt store file aside: %w", err)
+		}
+		slog.Warn("store file is corrupt; moved it aside", "path", s.path, "moved_to", corrupt, "error", loadErr)
+	}
+
+	tmp := s.path + ".tmp"
+	list, _, err := s.readProducts(tmp)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		slog.Warn("ignoring unreadable temp file from an interrupted save", "path", tmp, "error", err)
+		return nil, nil
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return nil, fmt.Errorf("recover %s: %w", tmp, err)
+	}
+	slog.Warn("recovered store from an interrupted save", "path", s.path, "from", tmp, "products", len(list))
+	return list, nil
+}
+
+// live returns the product stored under id unless it is missing or
+// soft-deleted. Callers hold s.mu.
+func (s *FileStore) live(id string) (domain.Product, bool) {
+	p, ok := s.products[id]
+	return p, ok && !p.IsDeleted()
+}
+
+// persist writes the products after a mutation that changed ids. With
+// Options.Journal only those products are appended to the journal. Otherwise,
+// or when no ids are given, the whole file is rewritten, now or, with
+// Options.SaveDelay and no journal, by scheduling a flush. Callers hold s.mu.
+func (s *FileStore) persist(ids ...string) error {
+	if s.opts.Journal {
+		if len(ids) == 0 {
+			return s.saveToFile()
 		}
+		return s.appendJournal(ids)
+	}
+	if s.opts.SaveDelay <= 0 {
+		return s.saveToFile()
+	}
+	s.dirty = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.opts.SaveDelay, s.flushPending)
+	}
+	return nil
+}
+
+// flushPending is the SaveDelay timer callback. A failed write stays dirty
+// and is retried by the next Flush or Close.
+func (s *FileStore) flushPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = nil
+	if s.dirty && s.saveToFile() == nil {
+		s.dirty = false
+	}
+}
+
+// Flush writes any changes still pending under Options.SaveDelay
+func (s *FileStore) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	var list []domain.Product
-	if len(b) == 0 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if !s.dirty {
 		return nil
 	}
-	if err := json.Unmarshal(b, &list); err != nil {
+	if err := s.saveToFile(); err != nil {
 		return err
 	}
-	for _, p := range list {
-		s.products[p.ID] = p
-	}
+	s.dirty = false
 	return nil
 }
 
+// Close flushes pending changes and stops watching the file. The file holds
+// no open handle between writes, so the store stays usable afterwards.
+func (s *FileStore) Close(ctx context.Context) error {
+	s.stopWatch()
+	return s.Flush(ctx)
+}
+
 func (s *FileStore) saveToFile() error {
 	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	if err := os.MkdirAll(dir, s.opts.dirMode()); err != nil {
 		return err
 	}
 	list := make([]domain.Product, 0, len(s.products))
@@ -70,33 +280,140 @@ func (s *FileStore) saveToFile() error {
 	}
 	// stable order for deterministic files
 	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
-	b, err := json.MarshalIndent(list, "", "  ")
+	b, err := json.MarshalIndent(fileEnvelope{Version: FileFormatVersion, Products: list}, "", "  ")
 	if err != nil {
 		return err
 	}
+	if b, err = s.encode(b); err != nil {
+		return err
+	}
 	tmp := s.path + ".tmp"
-	if err := ioutil.WriteFile(tmp, b, 0o644); err != nil {
+	if err := writeFile(tmp, b, s.opts.fileMode(), s.opts.Durable); err != nil {
 		return err
 	}
-	return os.Rename(tmp, s.path)
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.onDisk = b
+	s.version = FileFormatVersion
+	// the journal may hold price changes the history file lacks
+	if s.history != nil && s.journaled > 0 {
+		if err := s.writeHistory(); err != nil {
+			return fmt.Errorf("save price history: %w", err)
+		}
+	}
+	// the new files hold everything the journal recorded
+	if err := os.Remove(s.journalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.journaled = 0
+	if s.opts.Durable {
+		return syncDir(dir)
+	}
+	return nil
 }
 
-func (s *FileStore) Create(ctx context.Context, product domain.Product) error {
-	if err := ctx.Err(); err != nil {
+func (s *FileStore) historyPath() string {
+	return s.path + ".prices.json"
+}
+
+// recordPrices adds the price changes between old[i] and updated[i] to the
+// history under Options.TrackPriceHistory and saves it: with Options.Journal
+// by appending the changes to the journal, otherwise by rewriting the history
+// file. The products are already saved, so a failed history write is logged
+// rather than returned. Callers hold s.mu.
+func (s *FileStore) recordPrices(old, updated []domain.Product) {
+	if s.history == nil {
+		return
+	}
+	var recs []journalRecord
+	for i := range updated {
+		if s.history.record(old[i], updated[i]) {
+			changes := s.history[updated[i].ID]
+			recs = append(recs, journalRecord{Op: "price", ID: updated[i].ID, Change: &changes[len(changes)-1]})
+		}
+	}
+	switch {
+	case len(recs) == 0:
+	case s.opts.Journal:
+		if err := s.writeJournal(recs); err != nil {
+			slog.Warn("journaling price history failed", "path", s.journalPath(), "error", err)
+		}
+	default:
+		s.saveHistory()
+	}
+}
+
+// saveHistory rewrites the price history file, logging a failure. Callers
+// hold s.mu.
+func (s *FileStore) saveHistory() {
+	if err := s.writeHistory(); err != nil {
+		slog.Warn("saving price history failed", "path", s.historyPath(), "error", err)
+	}
+}
+
+// writeHistory rewrites the price history file, encrypted like the store
+// file. Callers hold s.mu.
+func (s *FileStore) writeHistory() error {
+	b, err := json.MarshalIndent(s.history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.aead != nil {
+		if b, err = seal(s.aead, b); err != nil {
+			return err
+		}
+	}
+	tmp := s.historyPath() + ".tmp"
+	if err := writeFile(tmp, b, s.opts.fileMode(), s.opts.Durable); err != nil {
 		return err
 	}
-	if product.ID == "" {
-		return domain.NewInvalidProductError("id", "cannot be empty", product.ID)
+	if err := os.Rename(tmp, s.historyPath()); err != nil {
+		return err
 	}
-	if product.Name == "" {
-		return domain.NewInvalidProductError("name", "cannot be empty", product.Name)
+	s.historyOnDisk = b
+	return nil
+}
+
+// writeFile writes b to path, creating it with mode and syncing it to disk
+// before closing when durable
+func writeFile(path string, b []byte, mode os.FileMode, durable bool) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
 	}
-	if product.Price < 0 {
-		return domain.NewInvalidProductError("price", "must be non-negative", product.Price)
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
 	}
-	if product.Quantity < 0 {
-		return domain.NewInvalidProductError("quantity", "must be non-negative", product.Quantity)
+	if durable {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
 	}
+	return f.Close()
+}
+
+// syncDir fsyncs dir so a rename inside it survives a power loss
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *FileStore) Create(ctx context.Context, product domain.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateNew(product); err != nil {
+		return err
+	}
+	product.Currency = product.EffectiveCurrency()
+	product = markUpdated(product)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -104,8 +421,12 @@ func (s *FileStore) Create(ctx context.Context, product domain.Product) error {
 	if _, ok := s.products[product.ID]; ok {
 		return domain.NewDuplicateProductError(product.ID)
 	}
-	s.products[product.ID] = product
-	return s.saveToFile()
+	s.products[product.ID] = product.Clone()
+	if err := s.persist(product.ID); err != nil {
+		return err
+	}
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeCreated, ID: product.ID, Product: product})
+	return nil
 }
 
 func (s *FileStore) Get(ctx context.Context, id string) (domain.Product, error) {
@@ -114,36 +435,83 @@ func (s *FileStore) Get(ctx context.Context, id string) (domain.Product, error)
 	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	p, ok := s.products[id]
+	p, ok := s.live(id)
 	if !ok {
 		return domain.Product{}, domain.NewProductNotFoundError(id)
 	}
-	return p, nil
+	return p.Clone(), nil
 }
 
 func (s *FileStore) Update(ctx context.Context, id string, product domain.Product) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if product.Name == "" {
-		return domain.NewInvalidProductError("name", "cannot be empty", product.Name)
-	}
-	if product.Price < 0 {
-		return domain.NewInvalidProductError("price", "must be non-negative", product.Price)
-	}
-	if product.Quantity < 0 {
-		return domain.NewInvalidProductError("quantity", "must be non-negative", product.Quantity)
+	if err := domain.ValidateProduct(product); err != nil {
+		return err
 	}
+	product.Currency = product.EffectiveCurrency()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.products[id]; !ok {
+	old, ok := s.live(id)
+	if !ok {
 		return domain.NewProductNotFoundError(id)
 	}
 	product.ID = id
-	s.products[id] = product
-	return s.saveToFile()
+	product = markUpdated(product)
+	s.products[id] = product.Clone()
+	if err := s.persist(id); err != nil {
+		s.products[id] = old
+		return err
+	}
+	s.recordPrices([]domain.Product{old}, []domain.Product{product})
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeUpdated, ID: id, Product: product})
+	return nil
+}
+
+// UpdateWhere patches every product matching filter and rewrites the file
+// once. Every result is validated before any is stored.
+func (s *FileStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := newListMatcher(filter)
+	var old, updated []domain.Product
+	var ids []string
+	for _, p := range s.products {
+		if !m.match(p) {
+			continue
+		}
+		np := markUpdated(patch.Apply(p))
+		if err := domain.ValidateProduct(np); err != nil {
+			return nil, fmt.Errorf("id=%s: %w", p.ID, err)
+		}
+		old = append(old, p)
+		updated = append(updated, np)
+		ids = append(ids, p.ID)
+	}
+	if len(updated) == 0 {
+		return updated, nil
+	}
+	for _, p := range updated {
+		s.products[p.ID] = p.Clone()
+	}
+	if err := s.persist(ids...); err != nil {
+		for _, p := range old {
+			s.products[p.ID] = p
+		}
+		return nil, err
+	}
+	s.recordPrices(old, updated)
+	for _, p := range updated {
+		s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeUpdated, ID: p.ID, Product: p})
+	}
+	sortProducts(updated, filter)
+	return updated, nil
 }
 
 func (s *FileStore) Delete(ctx context.Context, id string) error {
@@ -152,11 +520,150 @@ func (s *FileStore) Delete(ctx context.Context, id string) error {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.products[id]; !ok {
+	p, ok := s.live(id)
+	if !ok {
+		return domain.NewProductNotFoundError(id)
+	}
+	removed := s.remove(p)
+	if err := s.persist(id); err != nil {
+		s.products[id] = p
+		return err
+	}
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeDeleted, ID: id, Product: removed})
+	return nil
+}
+
+// remove deletes p from the map, or marks it deleted when soft delete is
+// enabled, and returns the product as the event should carry it. Callers
+// hold s.mu and save afterwards.
+func (s *FileStore) remove(p domain.Product) domain.Product {
+	if s.opts.SoftDelete {
+		p = markDeleted(p)
+		s.products[p.ID] = p
+		return p
+	}
+	delete(s.products, p.ID)
+	return p
+}
+
+// BatchDelete removes all ids and rewrites the file once. If any id is
+// unknown nothing is deleted and a ProductNotFoundError is returned.
+func (s *FileStore) BatchDelete(ctx context.Context, ids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := s.live(id); !ok {
+			return domain.NewProductNotFoundError(id)
+		}
+	}
+	old := make([]domain.Product, 0, len(ids))
+	removed := make([]domain.Product, 0, len(ids))
+	changed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		p, ok := s.live(id)
+		if !ok {
+			continue // listed twice
+		}
+		old = append(old, p)
+		removed = append(removed, s.remove(p))
+		changed = append(changed, id)
+	}
+	if err := s.persist(changed...); err != nil {
+		// put the products back so a failed write changes nothing
+		for _, p := range old {
+			s.products[p.ID] = p
+		}
+		return err
+	}
+	for _, p := range removed {
+		s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeDeleted, ID: p.ID, Product: p})
+	}
+	return nil
+}
+
+// Restore clears the deletion mark of a soft-deleted product and saves
+func (s *FileStore) Restore(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.products[id]
+	if !ok {
 		return domain.NewProductNotFoundError(id)
 	}
-	delete(s.products, id)
-	return s.saveToFile()
+	if !p.IsDeleted() {
+		return nil
+	}
+	restored := p
+	restored.DeletedAt = nil
+	restored = markUpdated(restored)
+	s.products[id] = restored
+	if err := s.persist(id); err != nil {
+		s.products[id] = p
+		return err
+	}
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeRestored, ID: id, Product: restored})
+	return nil
+}
+
+// Purge removes every soft-deleted product, rewrites the file once and
+// publishes a ChangeDeleted event for each product removed
+func (s *FileStore) Purge(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var purged []domain.Product
+	var ids []string
+	for id, p := range s.products {
+		if p.IsDeleted() {
+			delete(s.products, id)
+			purged = append(purged, p)
+			ids = append(ids, id)
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	if err := s.persist(ids...); err != nil {
+		for _, p := range purged {
+			s.products[p.ID] = p
+		}
+		return 0, err
+	}
+	s.watchers.publishDeleted(purged)
+	return len(purged), nil
+}
+
+// Clear removes every product, rewrites the file as an empty array and
+// publishes a ChangeDeleted event for each product removed
+func (s *FileStore) Clear(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.products
+	s.products = make(map[string]domain.Product)
+	if err := s.persist(); err != nil {
+		s.products = old
+		return 0, err
+	}
+	if len(s.history) > 0 {
+		s.history = make(priceHistory)
+		s.saveHistory()
+	}
+	removed := make([]domain.Product, 0, len(old))
+	for _, p := range old {
+		removed = append(removed, p)
+	}
+	s.watchers.publishDeleted(removed)
+	return len(old), nil
 }
 
 func (s *FileStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
@@ -165,135 +672,200 @@ func (s *FileStore) List(ctx context.Context, filter domain.ListFilter) ([]domai
 	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]domain.Product, 0, len(s.products))
+	var out []domain.Product
+	m := newListMatcher(filter)
+	visited := 0
 	for _, p := range s.products {
-		if filter.Category != "" && p.Category != filter.Category {
-			continue
-		}
-		if filter.MinPrice != nil && p.Price < *filter.MinPrice {
-			continue
+		// stop a long scan soon after cancellation
+		if visited++; visited%ctxCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 		}
-		if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
+		if !m.match(p) {
 			continue
 		}
 		out = append(out, p)
 	}
-	switch filter.SortBy {
-	case "name":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Name > out[j].Name
-			}
-			return out[i].Name < out[j].Name
-		})
-	case "price":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Price > out[j].Price
-			}
-			return out[i].Price < out[j].Price
-		})
-	case "quantity":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Quantity > out[j].Quantity
-			}
-			return out[i].Quantity < out[j].Quantity
-		})
-	}
-	return out, nil
+	return clonePage(out, filter), nil
 }
 
-func (s *FileStore) BulkImport(ctx context.Context, products []domain.Product) error {
+// RenameCategory moves every live product in category from to category to
+// through UpdateWhere, so it is validated and saved as one step
+func (s *FileStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	if err := checkRename(from, to); err != nil {
+		return 0, err
+	}
+	updated, err := s.UpdateWhere(ctx, domain.ListFilter{Category: from}, domain.ProductPatch{Category: &to})
+	return len(updated), err
+}
+
+// AdjustPriceWhere changes the price of every live product matching filter
+// by pct percent through UpdateWhere, so it is validated and saved as one
+// step
+func (s *FileStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	if err := checkPercent(pct); err != nil {
+		return 0, err
+	}
+	updated, err := s.UpdateWhere(ctx, filter, domain.ProductPatch{PricePercent: &pct})
+	return len(updated), err
+}
+
+// Categories counts live products per category
+func (s *FileStore) Categories(ctx context.Context) (map[string]int, error) {
 	if err := ctx.Err(); err != nil {
-		return err
+		return nil, err
 	}
-	const maxWorkers = 10
-	jobs := make(chan domain.Product)
-	errs := make(chan error, len(products))
 
-	var addMu sync.Mutex
-	toAdd := make(map[string]domain.Product)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return countCategories(s.products), nil
+}
 
-	var wg sync.WaitGroup
-	worker := func() {
-		defer wg.Done()
-		for p := range jobs {
+// Stats totals the products matching filter
+func (s *FileStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	var st domain.InventoryStats
+	if err := ctx.Err(); err != nil {
+		return st, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := newListMatcher(filter)
+	visited := 0
+	for _, p := range s.products {
+		if visited++; visited%ctxCheckEvery == 0 {
 			if err := ctx.Err(); err != nil {
-				errs <- err
-				return
-			}
-			// validate fields
-			if p.ID == "" || p.Name == "" || p.Price < 0 || p.Quantity < 0 {
-				errs <- domain.NewInvalidProductError("bulk", "invalid product", p)
-				continue
-			}
-			addMu.Lock()
-			if _, exists := toAdd[p.ID]; exists {
-				addMu.Unlock()
-				errs <- domain.NewDuplicateProductError(p.ID)
-				continue
+				return domain.InventoryStats{}, err
 			}
-			toAdd[p.ID] = p
-			addMu.Unlock()
+		}
+		if m.match(p) {
+			st.Add(p)
 		}
 	}
+	return st, nil
+}
 
-	nWorkers := maxWorkers
-	if len(products) < nWorkers {
-		nWorkers = len(products)
+// FindDuplicates reports live products sharing a name and category
+func (s *FileStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	if nWorkers == 0 {
-		return nil
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return findDuplicates(s.products), nil
+}
+
+// PriceHistory returns the price changes recorded for id. Changes are kept
+// after the product is deleted.
+func (s *FileStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	wg.Add(nWorkers)
-	for i := 0; i < nWorkers; i++ {
-		go worker()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.products[id]; !ok && len(s.history[id]) == 0 {
+		return nil, domain.NewProductNotFoundError(id)
 	}
+	return s.history.of(id), nil
+}
 
-	go func() {
-		for _, p := range products {
-			select {
-			case <-ctx.Done():
-				break
-			case jobs <- p:
-			}
-		}
-		close(jobs)
-	}()
-
-	wg.Wait()
-	close(errs)
-
-	var collected error
-	for e := range errs {
-		if collected == nil {
-			collected = e
-		} else {
-			collected = fmt.Errorf("%v; %w", collected, e)
+// NeedsReorder returns products whose stock has fallen below their own
+// ReorderLevel, lowest quantity first.
+func (s *FileStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]domain.Product, 0)
+	for _, p := range s.products {
+		if !p.IsDeleted() && p.Quantity < p.ReorderLevel {
+			out = append(out, p.Clone())
 		}
 	}
+	sortProducts(out, domain.ListFilter{SortBy: "quantity"})
+	return out, nil
+}
+
+// BulkImport is BulkImportWithOptions with domain.DefaultBulkImportOptions
+func (s *FileStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	return s.BulkCreate(ctx, products, domain.DefaultBulkImportOptions)
+}
+
+// BulkImportWithOptions stores products with a single write through
+// BulkCreate. Failures are returned as a *domain.BulkImportError; whether
+// the valid products are stored regardless depends on opts.Atomic.
+func (s *FileStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	return s.BulkCreate(ctx, products, opts)
+}
+
+// BulkCreate validates every product, merges the batch under one lock and
+// saves once, so seeding n products costs one write instead of n. Validation
+// is cheap next to the write, so it runs in order on the calling goroutine.
+// An atomic import stores nothing if any product fails; a best-effort one
+// keeps the valid products. The batch is written at once, so opts.RateLimit
+// and the retry options do not apply.
+func (s *FileStore) BulkCreate(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(products) == 0 {
+		return nil
+	}
+	tick := progressTicker(ctx, len(products))
+	var collected domain.MultiError
+	toAdd := make([]domain.Product, 0, len(products))
+	seen := make(map[string]bool, len(products))
 
-	// merge toAdd into store with lock, detect duplicates against existing store
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for id, p := range toAdd {
-		if _, exists := s.products[id]; exists {
-			e := domain.NewDuplicateProductError(id)
-			if collected == nil {
-				collected = e
-			} else {
-				collected = fmt.Errorf("%v; %w", collected, e)
+	for i, p := range products {
+		if i%ctxCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
+		}
+		tick()
+		// same rules as Create, keeping the failing field and reason
+		if err := validateNew(p); err != nil {
+			collected.Append(fmt.Errorf("id=%s: %w", p.ID, err))
 			continue
 		}
-		s.products[id] = p
+		if _, exists := s.products[p.ID]; exists || seen[p.ID] {
+			collected.Append(fmt.Errorf("id=%s: %w", p.ID, domain.NewDuplicateProductError(p.ID)))
+			continue
+		}
+		seen[p.ID] = true
+		p.Currency = p.EffectiveCurrency()
+		toAdd = append(toAdd, markUpdated(p).Clone())
 	}
-	if err := s.saveToFile(); err != nil {
-		if collected == nil {
-			return err
+	if len(toAdd) == 0 || (opts.Atomic && collected.ErrOrNil() != nil) {
+		return domain.NewBulkImportError(nil, &collected)
+	}
+
+	ids := make([]string, len(toAdd))
+	for i, p := range toAdd {
+		s.products[p.ID] = p
+		ids[i] = p.ID
+	}
+	if err := s.persist(ids...); err != nil {
+		// take the products out again so a failed write changes nothing
+		for _, id := range ids {
+			delete(s.products, id)
 		}
-		return fmt.Errorf("%v; %w", collected, err)
+		collected.Append(err)
+		return domain.NewBulkImportError(nil, &collected)
+	}
+	for _, p := range toAdd {
+		// toAdd holds the stored values; watchers get their own copy
+		s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeCreated, ID: p.ID, Product: p.Clone()})
 	}
-	return collected
+	sort.Strings(ids)
+	return domain.NewBulkImportError(ids, &collected)
+}
+
+// Watch subscribes to change events published after each successful save.
+func (s *FileStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	return s.watchers.subscribe(ctx)
 }
@@ -9,7 +9,7 @@ import (
 	"aexp_assesment/domain"
 )
 
-func floatPtr(v float64) *float64 { return &v }
+func moneyPtr(v domain.Money) *domain.Money { return &v }
 
 func TestFileStore_List_SortingAndFiltering(t *testing.T) {
 	path := filepath.Join(os.TempDir(), "file_store_list_test.json")
@@ -33,7 +33,7 @@ func TestFileStore_List_SortingAndFiltering(t *testing.T) {
 	}
 
 	// Filter by MinPrice
-	out, err := s.List(context.Background(), domain.ListFilter{MinPrice: floatPtr(30)})
+	out, err := s.List(context.Background(), domain.ListFilter{MinPrice: moneyPtr(30)})
 	if err != nil {
 		t.Fatalf("List MinPrice failed: %v", err)
 	}
@@ -42,7 +42,7 @@ func TestFileStore_List_SortingAndFiltering(t *testing.T) {
 	}
 
 	// Filter by MaxPrice
-	out, err = s.List(context.Background(), domain.ListFilter{MaxPrice: floatPtr(30)})
+	out, err = s.List(context.Background(), domain.ListFilter{MaxPrice: moneyPtr(30)})
 	if err != nil {
 		t.Fatalf("List MaxPrice failed: %v", err)
 	}
@@ -122,3 +122,87 @@ func TestFileStore_BulkImport_InvalidAndDuplicates(t *testing.T) {
 		t.Fatalf("expected error when importing duplicate against existing store, got nil")
 	}
 }
+
+func TestFileStore_List_MultipleCategories(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "file_store_categories_test.json")
+	_ = os.Remove(path)
+	defer os.Remove(path)
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	for _, p := range []domain.Product{
+		{ID: "a", Name: "A", Price: 1, Quantity: 1, Category: "C1"},
+		{ID: "b", Name: "B", Price: 1, Quantity: 1, Category: "C2"},
+		{ID: "c", Name: "C", Price: 1, Quantity: 1, Category: "C3"},
+	} {
+		if err := s.Create(context.Background(), p); err != nil {
+			t.Fatalf("setup Create failed: %v", err)
+		}
+	}
+
+	out, err := s.List(context.Background(), domain.ListFilter{Categories: []string{"C1", "C3"}})
+	if err != nil {
+		t.Fatalf("List Categories failed: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 items in C1|C3, got %d", len(out))
+	}
+
+	out, _ = s.List(context.Background(), domain.ListFilter{Category: "C2", Categories: []string{"C1"}})
+	if len(out) != 2 {
+		t.Fatalf("expected Category to join Categories set, got %d", len(out))
+	}
+}
+
+func TestFileStore_List_CaseInsensitiveNameSort(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "file_store_ignorecase_test.json")
+	_ = os.Remove(path)
+	defer os.Remove(path)
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	for _, p := range []domain.Product{
+		{ID: "z", Name: "Zebra", Price: 1, Quantity: 1},
+		{ID: "a", Name: "apple", Price: 1, Quantity: 1},
+		{ID: "m", Name: "Mango", Price: 1, Quantity: 1},
+	} {
+		if err := s.Create(context.Background(), p); err != nil {
+			t.Fatalf("setup Create failed: %v", err)
+		}
+	}
+
+	out, _ := s.List(context.Background(), domain.ListFilter{SortBy: "name"})
+	if out[0].Name != "Mango" || out[2].Name != "apple" {
+		t.Fatalf("expected byte-wise order, got %+v", out)
+	}
+
+	out, _ = s.List(context.Background(), domain.ListFilter{SortBy: "name", CaseInsensitive: true})
+	if out[0].Name != "apple" || out[1].Name != "Mango" || out[2].Name != "Zebra" {
+		t.Fatalf("expected case-insensitive order, got %+v", out)
+	}
+}
+
+func TestFileStore_List_SortByValue(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "value.json"))
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	for _, p := range []domain.Product{
+		{ID: "a", Name: "A", Price: 100, Quantity: 30}, // 3000
+		{ID: "b", Name: "B", Price: 5000, Quantity: 1}, // 5000
+		{ID: "c", Name: "C", Price: 10, Quantity: 10},  // 100
+	} {
+		if err := s.Create(context.Background(), p); err != nil {
+			t.Fatalf("setup Create failed: %v", err)
+		}
+	}
+
+	out, _ := s.List(context.Background(), domain.ListFilter{SortBy: "value"})
+	if out[0].ID != "c" || out[1].ID != "a" || out[2].ID != "b" {
+		t.Fatalf("expected ascending value order c, a, b, got %+v", out)
+	}
+}
@@ -66,13 +66,49 @@ func TestFileStore_List_BulkImport_LoadFromFile(t *testing.T) {
 		t.Fatalf("expected error when bulk importing duplicate id")
 	}
 
-	// Ensure file contains JSON array
+	// Ensure file contains the versioned envelope
 	b, err := os.ReadFile(path)
 	if err != nil {
 		t.Fatalf("failed to read file: %v", err)
 	}
-	var arr []domain.Product
-	if err := json.Unmarshal(b, &arr); err != nil {
-		t.Fatalf("file content is not JSON array: %v", err)
+	var env fileEnvelope
+	if err := json.Unmarshal(b, &env); err != nil || env.Version != FileFormatVersion {
+		t.Fatalf("file content is not a versioned envelope: %v", err)
+	}
+}
+
+func TestFileStore_LoadsLegacyDecimalPrices(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "file_store_legacy_price_test.json")
+	defer os.Remove(path)
+	legacy := `[{"id":"l1","name":"Legacy","price":19.99,"quantity":1,"category":"A"}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	got, err := s.Get(context.Background(), "l1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Price != 1999 {
+		t.Fatalf("expected 1999 cents, got %d", got.Price)
+	}
+
+	// saving writes the same decimal representation back out
+	if err := s.Update(context.Background(), "l1", got); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	var raw struct {
+		Products []map[string]interface{} `json:"products"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatalf("invalid file contents: %v", err)
+	}
+	if raw.Products[0]["price"] != 19.99 {
+		t.Fatalf("expected price 19.99 on disk, got %v", raw.Products[0]["price"])
 	}
 }
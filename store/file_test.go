@@ -2,9 +2,17 @@ package store
 
 import (
 	"aexp_assesment/domain"
+	"aexp_assesment/util"
 	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFileStore_CreateGetUpdateDelete(t *testing.T) {
@@ -16,7 +24,7 @@ func TestFileStore_CreateGetUpdateDelete(t *testing.T) {
 	}
 	ctx := context.Background()
 
-	p := domain.Product{ID: "f1", Name: "FileProd", Price: 3.14, Quantity: 2, Category: "F"}
+	p := domain.Product{ID: "f1", Name: "FileProd", Price: 314, Quantity: 2, Category: "F"}
 	if err := s.Create(ctx, p); err != nil {
 		t.Fatalf("create failed: %v", err)
 	}
@@ -36,3 +44,699 @@ func TestFileStore_CreateGetUpdateDelete(t *testing.T) {
 	}
 	_ = os.Remove(path)
 }
+
+func TestFileStore_WatchBulkImport(t *testing.T) {
+	path := "testdata/watch_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if err := s.BulkImport(ctx, []domain.Product{
+		{ID: "w1", Name: "A", Price: 1, Quantity: 1},
+		{ID: "w2", Name: "B", Price: 1, Quantity: 1},
+	}); err != nil {
+		t.Fatalf("bulk import failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := <-events; ev.Op != domain.ChangeCreated {
+			t.Fatalf("expected created event, got %+v", ev)
+		}
+	}
+}
+
+func TestFileStore_WatchBulkImportEventsAreCopies(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "products.json"))
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if err := s.BulkImport(ctx, []domain.Product{
+		{ID: "w1", Name: "A", Price: 1, Quantity: 1, Tags: []string{"red"}, Attributes: map[string]string{"size": "m"}},
+	}); err != nil {
+		t.Fatalf("bulk import failed: %v", err)
+	}
+	ev := <-events
+	ev.Product.Tags[0] = "blue"
+	ev.Product.Attributes["size"] = "xl"
+
+	got, err := s.Get(ctx, "w1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Tags[0] != "red" || got.Attributes["size"] != "m" {
+		t.Fatalf("changing an event changed the store: %+v", got)
+	}
+}
+
+func TestFileStore_PersistsTags(t *testing.T) {
+	path := "testdata/tags_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := s.Create(ctx, domain.Product{ID: "t1", Name: "A", Price: 1, Quantity: 1, Tags: []string{"sale", "new"}}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	out, err := reloaded.List(ctx, domain.ListFilter{TagsAll: []string{"new", "sale"}})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(out) != 1 || len(out[0].Tags) != 2 {
+		t.Fatalf("expected tags to survive reload, got %+v", out)
+	}
+}
+
+func TestFileStore_BatchDeletePersists(t *testing.T) {
+	path := "testdata/batch_delete_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	for _, id := range []string{"b1", "b2", "b3"} {
+		_ = s.Create(ctx, domain.Product{ID: id, Name: id, Price: 1, Quantity: 1})
+	}
+
+	if err := s.BatchDelete(ctx, []string{"b2", "missing"}); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected ProductNotFoundError, got %v", err)
+	}
+	if err := s.BatchDelete(ctx, []string{"b1", "b2"}); err != nil {
+		t.Fatalf("BatchDelete failed: %v", err)
+	}
+
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	out, _ := reloaded.List(ctx, domain.ListFilter{})
+	if len(out) != 1 || out[0].ID != "b3" {
+		t.Fatalf("unexpected products after reload: %+v", out)
+	}
+}
+
+func TestFileStore_BulkImportKeepsInvalidField(t *testing.T) {
+	path := "testdata/bulk_field_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	err = s.BulkImport(context.Background(), []domain.Product{
+		{ID: "v1", Name: "A", Price: 1, Quantity: -1},
+	})
+	var ipe *domain.InvalidProductError
+	if !errors.As(err, &ipe) {
+		t.Fatalf("expected *domain.InvalidProductError, got %v", err)
+	}
+	if ipe.Field != "quantity" {
+		t.Fatalf("expected field quantity, got %q (%v)", ipe.Field, err)
+	}
+	if !strings.Contains(err.Error(), "id=v1") {
+		t.Fatalf("expected error to name the product, got %v", err)
+	}
+}
+
+func TestFileStore_BulkImportAtomicAndBestEffort(t *testing.T) {
+	path := "testdata/bulk_atomic_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	batch := []domain.Product{
+		{ID: "a1", Name: "A", Price: 1, Quantity: 1},
+		{ID: "a2", Name: "", Price: 1, Quantity: 1},
+		{ID: "a3", Name: "C", Price: 1, Quantity: 1},
+	}
+
+	// atomic is the default: nothing is stored
+	err = s.BulkImport(context.Background(), batch)
+	var bie *domain.BulkImportError
+	if !errors.As(err, &bie) || len(bie.Applied) != 0 {
+		t.Fatalf("expected BulkImportError with nothing applied, got %v", err)
+	}
+	if out, _ := s.List(context.Background(), domain.ListFilter{}); len(out) != 0 {
+		t.Fatalf("atomic import must store nothing, got %+v", out)
+	}
+
+	err = s.BulkImportWithOptions(context.Background(), batch, domain.BulkImportOptions{Atomic: false})
+	if !errors.As(err, &bie) {
+		t.Fatalf("expected BulkImportError, got %v", err)
+	}
+	if len(bie.Applied) != 2 || bie.Applied[0] != "a1" || bie.Applied[1] != "a3" {
+		t.Fatalf("expected a1 and a3 applied, got %v", bie.Applied)
+	}
+	if !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected the invalid product to be reported, got %v", err)
+	}
+
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if out, _ := reloaded.List(context.Background(), domain.ListFilter{}); len(out) != 2 {
+		t.Fatalf("expected the applied products on disk, got %+v", out)
+	}
+}
+
+func TestFileStore_BulkImportStopsOnCancel(t *testing.T) {
+	path := "testdata/bulk_cancel_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	products := make([]domain.Product, 10000)
+	for i := range products {
+		products[i] = domain.Product{ID: fmt.Sprintf("c%d", i), Name: "C", Price: 1, Quantity: 1}
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.BulkImport(canceled, products); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for a canceled context, got %v", err)
+	}
+
+	// cancel after the first product; the feeder must stop handing out jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	processed := 0
+	ctx = domain.WithProgress(ctx, func(done, total int) {
+		processed = done
+		cancel()
+	})
+	done := make(chan error, 1)
+	go func() { done <- s.BulkImport(ctx, products) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BulkImport did not return after cancellation")
+	}
+	if processed >= len(products) {
+		t.Fatalf("expected cancellation to stop processing, got %d of %d", processed, len(products))
+	}
+	if out, _ := s.List(context.Background(), domain.ListFilter{}); len(out) != 0 {
+		t.Fatalf("a canceled atomic import must store nothing, got %d products", len(out))
+	}
+}
+
+func TestFileStore_PersistsAttributes(t *testing.T) {
+	path := "testdata/attributes_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	attrs := map[string]string{"color": "red", "size": "M"}
+	if err := s.Create(ctx, domain.Product{ID: "a1", Name: "A", Price: 1, Quantity: 1, Attributes: attrs}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	got, err := reloaded.Get(ctx, "a1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !maps.Equal(got.Attributes, attrs) {
+		t.Fatalf("expected attributes to survive reload, got %v", got.Attributes)
+	}
+}
+
+func TestFileStore_SoftDeletePersists(t *testing.T) {
+	path := "testdata/soft_delete_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStoreWithOptions(path, Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "d1", Name: "A", Price: 1, Quantity: 1})
+	if err := s.Delete(ctx, "d1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	// the mark survives a reload, even by a store without soft delete
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if _, err := reloaded.Get(ctx, "d1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected soft-deleted product to stay hidden, got %v", err)
+	}
+	if err := reloaded.Restore(ctx, "d1"); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if n, err := reloaded.Purge(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing to purge, got %d, %v", n, err)
+	}
+
+	again, _ := NewFileStore(path)
+	if _, err := again.Get(ctx, "d1"); err != nil {
+		t.Fatalf("expected restored product on disk, got %v", err)
+	}
+}
+
+func TestFileStore_SaveDelayFlushes(t *testing.T) {
+	path := "testdata/save_delay_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	s, err := NewFileStoreWithOptions(path, Options{SaveDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "s1", Name: "A", Price: 1, Quantity: 1})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no file before the delay elapses, got %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	reloaded, _ := NewFileStore(path)
+	if _, err := reloaded.Get(ctx, "s1"); err != nil {
+		t.Fatalf("expected Close to flush pending writes, got %v", err)
+	}
+
+	// a short delay flushes on its own
+	s, _ = NewFileStoreWithOptions(path, Options{SaveDelay: 10 * time.Millisecond})
+	_ = s.Create(ctx, domain.Product{ID: "s2", Name: "B", Price: 1, Quantity: 1})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		reloaded, _ = NewFileStore(path)
+		if _, err := reloaded.Get(ctx, "s2"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected debounced write to reach disk")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func benchmarkFileStoreCreate(b *testing.B, opts Options) {
+	path := b.TempDir() + "/bench.json"
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		b.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Create(ctx, domain.Product{ID: "b-" + strconv.Itoa(i), Name: "Bench", Price: 1, Quantity: 1})
+	}
+	if err := s.Close(ctx); err != nil {
+		b.Fatalf("close failed: %v", err)
+	}
+}
+
+func BenchmarkFileStore_CreatePerWrite(b *testing.B) {
+	benchmarkFileStoreCreate(b, Options{})
+}
+
+func BenchmarkFileStore_CreateDebounced(b *testing.B) {
+	benchmarkFileStoreCreate(b, Options{SaveDelay: 50 * time.Millisecond})
+}
+
+func TestFileStore_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/products.json"
+	truncated := []byte(`[{"id":"c1","name":"A","pri`)
+	if err := os.WriteFile(path, truncated, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileStore(path); err == nil {
+		t.Fatalf("expected a strict load of a corrupt file to fail")
+	}
+
+	s, err := NewFileStoreWithOptions(path, Options{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+	if out, _ := s.List(context.Background(), domain.ListFilter{}); len(out) != 0 {
+		t.Fatalf("expected an empty store, got %v", out)
+	}
+	if b, err := os.ReadFile(path + ".corrupt"); err != nil || string(b) != string(truncated) {
+		t.Fatalf("expected the corrupt file to be kept aside, got %q, %v", b, err)
+	}
+}
+
+func TestFileStore_GzipRoundTrip(t *testing.T) {
+	path := t.TempDir() + "/products.json.gz"
+	// a plain file under a .gz name still loads, and is compressed on save
+	if err := os.WriteFile(path, []byte(`[{"id":"g1","name":"Plain","price":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := s.Create(ctx, domain.Product{ID: "g2", Name: "Zipped", Price: 250, Quantity: 3}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	if !util.IsGzip(b) {
+		t.Fatalf("expected a gzip file, got %q", b)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if out, _ := reopened.List(ctx, domain.ListFilter{}); len(out) != 2 {
+		t.Fatalf("expected both products after a reopen, got %v", out)
+	}
+	if p, err := reopened.Get(ctx, "g2"); err != nil || p.Price != 250 || p.Quantity != 3 {
+		t.Fatalf("unexpected product %+v (%v)", p, err)
+	}
+
+	if err := os.WriteFile(path, b[:len(b)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(path); err == nil {
+		t.Fatal("expected a truncated gzip file to fail to load")
+	}
+	if _, err := NewFileStoreWithOptions(path, Options{RecoverCorrupt: true}); err != nil {
+		t.Fatalf("expected a truncated gzip file to count as corrupt, got %v", err)
+	}
+}
+
+func TestFileStore_EncryptedRoundTrip(t *testing.T) {
+	for _, name := range []string{"products.json", "products.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := t.TempDir() + "/" + name
+			key := []byte("0123456789abcdef0123456789abcdef")
+			opts := Options{EncryptionKey: key, TrackPriceHistory: true}
+			s, err := NewFileStoreWithOptions(path, opts)
+			if err != nil {
+				t.Fatalf("NewFileStore failed: %v", err)
+			}
+			ctx := context.Background()
+			_ = s.Create(ctx, domain.Product{ID: "e1", Name: "Secret Widget", Price: 4200, Quantity: 1})
+			_ = s.Update(ctx, "e1", domain.Product{Name: "Secret Widget", Price: 4300, Quantity: 1})
+
+			for _, p := range []string{path, path + ".prices.json"} {
+				b, _ := os.ReadFile(p)
+				if strings.Contains(string(b), "Secret") {
+					t.Fatalf("%s holds plaintext: %q", p, b)
+				}
+			}
+			first, _ := os.ReadFile(path)
+			_ = s.Update(ctx, "e1", domain.Product{Name: "Secret Widget", Price: 4300, Quantity: 1})
+			if second, _ := os.ReadFile(path); string(first) == string(second) {
+				t.Fatal("expected a fresh nonce on every save")
+			}
+
+			reopened, err := NewFileStoreWithOptions(path, opts)
+			if err != nil {
+				t.Fatalf("reopen failed: %v", err)
+			}
+			if p, err := reopened.Get(ctx, "e1"); err != nil || p.Price != 4300 {
+				t.Fatalf("unexpected product %+v (%v)", p, err)
+			}
+			if h, _ := reopened.PriceHistory(ctx, "e1"); len(h) != 1 {
+				t.Fatalf("expected the encrypted history to load, got %+v", h)
+			}
+		})
+	}
+}
+
+func TestFileStore_EncryptionKeyErrors(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	key := []byte("0123456789abcdef")
+	s, err := NewFileStoreWithOptions(path, Options{EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	_ = s.Create(context.Background(), domain.Product{ID: "e1", Name: "A", Price: 1, Quantity: 1})
+	before, _ := os.ReadFile(path)
+
+	wrong := Options{EncryptionKey: []byte("fedcba9876543210"), RecoverCorrupt: true}
+	if _, err := NewFileStoreWithOptions(path, wrong); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Fatal("expected a wrong key to leave the file alone")
+	}
+	if _, err := NewFileStore(path); !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected ErrEncrypted without a key, got %v", err)
+	}
+	if _, err := NewFileStoreWithOptions(path, Options{EncryptionKey: []byte("short")}); err == nil {
+		t.Fatal("expected a bad key length to fail")
+	}
+	if _, err := NewFileStoreWithOptions(path, Options{EncryptionKey: key, Journal: true}); err == nil {
+		t.Fatal("expected Journal with EncryptionKey to fail")
+	}
+}
+
+func TestFileStore_EncryptsPlaintextOnSave(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	if err := os.WriteFile(path, []byte(`[{"id":"p1","name":"Plain","price":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	key := []byte("0123456789abcdef")
+	s, err := NewFileStoreWithOptions(path, Options{EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("expected a plaintext file to load with a key, got %v", err)
+	}
+	_ = s.Create(context.Background(), domain.Product{ID: "p2", Name: "B", Price: 1, Quantity: 1})
+	if b, _ := os.ReadFile(path); strings.Contains(string(b), "Plain") {
+		t.Fatalf("expected the file to be encrypted after a save, got %q", b)
+	}
+}
+
+func TestFileStore_RecoversInterruptedSave(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/products.json"
+	ctx := context.Background()
+	// a complete temp file whose rename never happened, next to a torn file
+	good, _ := NewFileStore(path + ".tmp.src")
+	_ = good.Create(ctx, domain.Product{ID: "r1", Name: "A", Price: 1, Quantity: 1})
+	if err := os.Rename(path+".tmp.src", path+".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`[`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileStoreWithOptions(path, Options{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("expected recovery, got %v", err)
+	}
+	if _, err := s.Get(ctx, "r1"); err != nil {
+		t.Fatalf("expected the temp file's products, got %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("expected the temp file to replace the store file, got %v", err)
+	}
+	if _, err := NewFileStore(path); err != nil {
+		t.Fatalf("expected a valid store file after recovery, got %v", err)
+	}
+}
+
+func TestFileStore_DurableSave(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	s, err := NewFileStoreWithOptions(path, Options{Durable: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := s.Create(ctx, domain.Product{ID: "d1", Name: "A", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	reloaded, _ := NewFileStore(path)
+	if _, err := reloaded.Get(ctx, "d1"); err != nil {
+		t.Fatalf("expected durable write on disk, got %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("expected no temp file left behind, got %v", err)
+	}
+}
+
+func TestFileStore_FileMode(t *testing.T) {
+	path := t.TempDir() + "/private/products.json"
+	s, err := NewFileStoreWithOptions(path, Options{FileMode: 0o600, DirMode: 0o700})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	if err := s.Create(context.Background(), domain.Product{ID: "m1", Name: "A", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected file mode 0600, got %v", fi.Mode().Perm())
+	}
+	di, _ := os.Stat(filepath.Dir(path))
+	if di.Mode().Perm() != 0o700 {
+		t.Fatalf("expected dir mode 0700, got %v", di.Mode().Perm())
+	}
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFileStore_WatchFileReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	s, err := NewFileStoreWithOptions(path, Options{WatchFile: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	defer s.Close(ctx)
+	if err := s.Create(ctx, domain.Product{ID: "w1", Name: "Ours", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	external := `[{"id": "w2", "name": "Theirs", "price": 2, "quantity": 3}]`
+	if err := os.WriteFile(path, []byte(external), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, func() bool { _, err := s.Get(ctx, "w2"); return err == nil }) {
+		t.Fatal("external change was not reloaded")
+	}
+	if _, err := s.Get(ctx, "w1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected w1 gone after reload, got %v", err)
+	}
+}
+
+func TestFileStore_WatchFileKeepsUnsavedChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	s, err := NewFileStoreWithOptions(path, Options{WatchFile: true, SaveDelay: time.Hour})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := s.Create(ctx, domain.Product{ID: "w1", Name: "Unsaved", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`[{"id": "w2", "name": "Theirs", "price": 2, "quantity": 3}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * reloadDebounce)
+
+	if _, err := s.Get(ctx, "w1"); err != nil {
+		t.Fatalf("unsaved local change lost: %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	if !strings.Contains(string(b), `"w1"`) || strings.Contains(string(b), `"w2"`) {
+		t.Fatalf("expected local state to overwrite the file, got %s", b)
+	}
+}
+
+func TestFileStore_WatchFileKeepsJournaledChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	s, err := NewFileStoreWithOptions(path, Options{WatchFile: true, Journal: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	defer s.Close(ctx)
+	if err := s.Create(ctx, domain.Product{ID: "w1", Name: "Journaled", Price: 1, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"id": "w2", "name": "Theirs", "price": 2, "quantity": 3}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor(t, func() bool { _, err := s.Get(ctx, "w2"); return err == nil }) {
+		t.Fatal("external change was not reloaded")
+	}
+	if _, err := s.Get(ctx, "w1"); err != nil {
+		t.Fatalf("journaled change lost on reload: %v", err)
+	}
+}
+
+func TestFileStore_WatchFileReloadsPriceHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	s, err := NewFileStoreWithOptions(path, Options{WatchFile: true, TrackPriceHistory: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	defer s.Close(ctx)
+	if err := s.Create(ctx, domain.Product{ID: "w1", Name: "Ours", Price: 100, Quantity: 1}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	// another process changes the price
+	other, err := NewFileStoreWithOptions(path, Options{TrackPriceHistory: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	if err := other.Update(ctx, "w1", domain.Product{Name: "Ours", Price: 120, Quantity: 1}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if !waitFor(t, func() bool { changes, _ := s.PriceHistory(ctx, "w1"); return len(changes) == 1 }) {
+		t.Fatal("external price change was not reloaded into the history")
+	}
+}
+
+// BenchmarkFileStore_Import10k imports 10k products into an empty file
+func BenchmarkFileStore_Import10k(b *testing.B) {
+	batch := make([]domain.Product, 10_000)
+	for i := range batch {
+		batch[i] = domain.Product{ID: "i-" + strconv.Itoa(i), Name: "Bench", Price: 1, Quantity: 1, Category: "Bulk"}
+	}
+	for i := 0; i < b.N; i++ {
+		s, err := NewFileStore(b.TempDir() + "/import.json")
+		if err != nil {
+			b.Fatalf("NewFileStore failed: %v", err)
+		}
+		if err := s.BulkImport(context.Background(), batch); err != nil {
+			b.Fatalf("import failed: %v", err)
+		}
+	}
+}
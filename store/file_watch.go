@@ -0,0 +1,143 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce is how long the file must stay quiet after a change before
+// it is reloaded, so an editor's partial writes are read once, as a whole.
+const reloadDebounce = 50 * time.Millisecond
+
+// startWatch reloads the file, and under Options.TrackPriceHistory the price
+// history file, whenever another process changes them. The directory is
+// watched rather than the files because saves, ours included, replace a file
+// by renaming over it.
+func (s *FileStore) startWatch() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, s.opts.dirMode()); err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return err
+	}
+	s.onDisk, _ = os.ReadFile(s.path)
+	s.fsw = w
+	s.watchDone = make(chan struct{})
+	go s.watchLoop(w, s.watchDone)
+	return nil
+}
+
+func (s *FileStore) watchLoop(w *fsnotify.Watcher, done chan struct{}) {
+	defer close(done)
+	target, history := filepath.Clean(s.path), filepath.Clean(s.historyPath())
+	var debounce *time.Timer
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				if debounce != nil {
+					debounce.Stop()
+				}
+				return
+			}
+			name := filepath.Clean(ev.Name)
+			watched := name == target || (name == history && s.opts.TrackPriceHistory)
+			if !watched || !ev.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			if debounce == nil {
+				debounce = time.AfterFunc(reloadDebounce, s.reload)
+			} else {
+				debounce.Reset(reloadDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("watching store file failed", "path", s.path, "error", err)
+		}
+	}
+}
+
+// stopWatch ends the watch started by startWatch. Callers must not hold s.mu,
+// which a reload in progress may be waiting for.
+func (s *FileStore) stopWatch() {
+	s.mu.Lock()
+	w, done := s.fsw, s.watchDone
+	s.fsw, s.watchDone = nil, nil
+	s.mu.Unlock()
+	if w == nil {
+		return
+	}
+	w.Close()
+	<-done
+}
+
+// reload replaces the products, and the price history when it is tracked,
+// with the files' contents after an external change, then replays the
+// journal on top, as loading does, so changes not yet compacted into the
+// files survive. Our own saves are recognised by their bytes and skipped. If
+// local changes are still waiting for a SaveDelay flush, the two versions
+// conflict: the local state is kept, with a warning, and the next flush
+// overwrites the file. Files or a journal that do not parse are left for the
+// next change to fix.
+func (s *FileStore) reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fsw == nil {
+		return
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var history priceHistory
+	var historyRaw []byte
+	if s.history != nil {
+		if history, historyRaw, err = readPriceHistory(s.historyPath(), s.aead); err != nil {
+			slog.Warn("ignoring unreadable change to price history", "path", s.historyPath(), "error", err)
+			return
+		}
+	}
+	if bytes.Equal(b, s.onDisk) && bytes.Equal(historyRaw, s.historyOnDisk) {
+		return
+	}
+	if s.dirty {
+		slog.Warn("store file changed on disk while local changes are unsaved; keeping local changes", "path", s.path)
+		return
+	}
+	list, _, err := s.decodeProducts(b)
+	if err != nil {
+		slog.Warn("ignoring unreadable change to store file", "path", s.path, "error", err)
+		return
+	}
+	products := make(map[string]domain.Product, len(list))
+	for _, p := range list {
+		products[p.ID] = p
+	}
+	prev, prevHistory, journaled := s.products, s.history, s.journaled
+	s.products = products
+	if history != nil {
+		s.history = history
+	}
+	if err := s.replayJournal(); err != nil {
+		s.products, s.history, s.journaled = prev, prevHistory, journaled
+		slog.Warn("ignoring change to store file: journal replay failed", "path", s.path, "error", err)
+		return
+	}
+	s.onDisk, s.historyOnDisk = b, historyRaw
+	slog.Info("store reloaded from disk", "path", s.path, "products", len(s.products))
+}
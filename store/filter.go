@@ -0,0 +1,314 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"cmp"
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// ctxCheckEvery is how many products a List scan visits between checks of
+// its context
+const ctxCheckEvery = 1024
+
+// listMatcher evaluates a ListFilter against products. Set-valued criteria
+// are indexed once up front so matching stays cheap inside List loops.
+type listMatcher struct {
+	filter     domain.ListFilter
+	categories map[string]struct{}
+	tagsAny    map[string]struct{}
+}
+
+// newListMatcher prepares filter for repeated matching
+func newListMatcher(filter domain.ListFilter) listMatcher {
+	m := listMatcher{filter: filter}
+	if len(filter.Categories) > 0 {
+		m.categories = make(map[string]struct{}, len(filter.Categories)+1)
+		for _, c := range filter.Categories {
+			m.categories[c] = struct{}{}
+		}
+		if filter.Category != "" {
+			m.categories[filter.Category] = struct{}{}
+		}
+	}
+	if len(filter.TagsAny) > 0 {
+		m.tagsAny = make(map[string]struct{}, len(filter.TagsAny))
+		for _, t := range filter.TagsAny {
+			m.tagsAny[t] = struct{}{}
+		}
+	}
+	return m
+}
+
+// categoryList returns the categories the filter is limited to, or nil when
+// it accepts any category
+func (m listMatcher) categoryList() []string {
+	if m.categories != nil {
+		out := make([]string, 0, len(m.categories))
+		for c := range m.categories {
+			out = append(out, c)
+		}
+		return out
+	}
+	if m.filter.Category != "" {
+		return []string{m.filter.Category}
+	}
+	return nil
+}
+
+// match reports whether p satisfies every criterion set on the filter
+func (m listMatcher) match(p domain.Product) bool {
+	filter := m.filter
+	if p.IsDeleted() && !filter.IncludeDeleted {
+		return false
+	}
+	if m.categories != nil {
+		if _, ok := m.categories[p.Category]; !ok {
+			return false
+		}
+	} else if filter.Category != "" && p.Category != filter.Category {
+		return false
+	}
+	if filter.MinPrice != nil && p.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
+		return false
+	}
+	if filter.Currency != "" && p.EffectiveCurrency() != filter.Currency {
+		return false
+	}
+	if filter.MinQuantity != nil && p.Quantity < *filter.MinQuantity {
+		return false
+	}
+	if filter.MaxQuantity != nil && p.Quantity > *filter.MaxQuantity {
+		return false
+	}
+	if filter.InStock != nil && (p.Quantity > 0) != *filter.InStock {
+		return false
+	}
+	if filter.UpdatedAfter != nil && (p.UpdatedAt == nil || p.UpdatedAt.Before(*filter.UpdatedAfter)) {
+		return false
+	}
+	if m.tagsAny != nil && !hasAnyTag(p.Tags, m.tagsAny) {
+		return false
+	}
+	for _, t := range filter.TagsAll {
+		if !hasTag(p.Tags, t) {
+			return false
+		}
+	}
+	for k, v := range filter.AttributeEquals {
+		if got, ok := p.Attributes[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyTag reports whether any of tags is in want
+func hasAnyTag(tags []string, want map[string]struct{}) bool {
+	for _, t := range tags {
+		if _, ok := want[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sortProducts orders out in place according to filter.SortBy and filter.Order.
+// SortBy may list several comma-separated keys (e.g. "price,name"); later keys
+// break ties on earlier ones, and the id breaks any that remain. Unknown keys
+// are ignored.
+func sortProducts(out []domain.Product, filter domain.ListFilter) {
+	less := lessFunc(filter)
+	if less == nil {
+		return
+	}
+	sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+}
+
+// lessFunc returns the ordering sortProducts applies for filter, or nil when
+// the filter names no known sort key and asks for no page. Products equal on
+// every key are ordered by id, so the heap and the full sort in pageProducts
+// agree; a page without sort keys is ordered by id alone, so successive pages
+// neither overlap nor skip products.
+func lessFunc(filter domain.ListFilter) func(a, b domain.Product) bool {
+	var keys []string
+	for _, k := range strings.Split(filter.SortBy, ",") {
+		switch k = strings.TrimSpace(k); k {
+		case "name", "price", "quantity", "value":
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 && filter.Limit <= 0 && filter.Offset <= 0 {
+		return nil
+	}
+	desc := filter.Order == "desc"
+	return func(a, b domain.Product) bool {
+		c := 0
+		for _, k := range keys {
+			if c = compareBy(k, a, b, filter.CaseInsensitive); c != 0 {
+				break
+			}
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+}
+
+// pageProducts orders out and cuts the page selected by filter.Offset and
+// filter.Limit. When the page ends well inside out, only its first
+// Offset+Limit products are selected, with a heap, instead of sorting all.
+func pageProducts(out []domain.Product, filter domain.ListFilter) []domain.Product {
+	offset := max(filter.Offset, 0)
+	k := 0
+	if filter.Limit > 0 {
+		k = offset + filter.Limit
+	}
+	if less := lessFunc(filter); less != nil {
+		if k > 0 && k <= len(out)/topKRatio {
+			out = topK(out, k, less)
+		} else {
+			sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+		}
+	}
+	if offset >= len(out) {
+		return out[:0]
+	}
+	out = out[offset:]
+	if filter.Limit > 0 && filter.Limit < len(out) {
+		out = out[:filter.Limit]
+	}
+	return out
+}
+
+// clonePage cuts the page of matches selected by filter and clones only the
+// products in it, so the caller owns the result. A result is never nil.
+func clonePage(matches []domain.Product, filter domain.ListFilter) []domain.Product {
+	page := pageProducts(matches, filter)
+	out := make([]domain.Product, len(page))
+	for i, p := range page {
+		out[i] = p.Clone()
+	}
+	return out
+}
+
+// topKRatio is how many times larger than the page a result set must be for
+// pageProducts to select with a heap rather than sort everything
+const topKRatio = 8
+
+// topK returns the k first products of out under less, in order, in
+// O(n log k). It reuses out's backing array.
+func topK(out []domain.Product, k int, less func(a, b domain.Product) bool) []domain.Product {
+	h := &productHeap{items: make([]domain.Product, 0, k), less: less}
+	for _, p := range out {
+		switch {
+		case h.Len() < k:
+			heap.Push(h, p)
+		case less(p, h.items[0]):
+			h.items[0] = p
+			heap.Fix(h, 0)
+		}
+	}
+	kept := out[:h.Len()]
+	for i := len(kept) - 1; i >= 0; i-- {
+		kept[i] = heap.Pop(h).(domain.Product)
+	}
+	return kept
+}
+
+// productHeap is a max-heap under less: its root is the last product kept
+type productHeap struct {
+	items []domain.Product
+	less  func(a, b domain.Product) bool
+}
+
+func (h *productHeap) Len() int           { return len(h.items) }
+func (h *productHeap) Less(i, j int) bool { return h.less(h.items[j], h.items[i]) }
+func (h *productHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *productHeap) Push(x any)         { h.items = append(h.items, x.(domain.Product)) }
+func (h *productHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// compareBy compares a and b on a single sort key, returning -1, 0 or +1
+func compareBy(key string, a, b domain.Product, caseInsensitive bool) int {
+	switch key {
+	case "name":
+		if caseInsensitive {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		return cmp.Compare(a.Name, b.Name)
+	case "price":
+		return cmp.Compare(a.Price, b.Price)
+	case "quantity":
+		return cmp.Compare(a.Quantity, b.Quantity)
+	case "value":
+		return cmp.Compare(a.Value(), b.Value())
+	}
+	return 0
+}
+
+// countCategories counts the live products per category
+func countCategories(products map[string]domain.Product) map[string]int {
+	out := make(map[string]int)
+	for _, p := range products {
+		if !p.IsDeleted() {
+			out[p.Category]++
+		}
+	}
+	return out
+}
+
+// findDuplicates groups the live products by normalised name and category
+// and keeps the groups with more than one id. Each group is named after its
+// lowest id; ids and groups are sorted so reports are stable.
+func findDuplicates(products map[string]domain.Product) []domain.DuplicateGroup {
+	type key struct{ name, category string }
+	groups := make(map[key][]domain.Product)
+	for _, p := range products {
+		if p.IsDeleted() {
+			continue
+		}
+		k := key{strings.ToLower(strings.TrimSpace(p.Name)), strings.ToLower(strings.TrimSpace(p.Category))}
+		groups[k] = append(groups[k], p)
+	}
+	var out []domain.DuplicateGroup
+	for _, ps := range groups {
+		if len(ps) < 2 {
+			continue
+		}
+		sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+		g := domain.DuplicateGroup{Name: ps[0].Name, Category: ps[0].Category, IDs: make([]string, len(ps))}
+		for i, p := range ps {
+			g.IDs[i] = p.ID
+		}
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// FileFormatVersion is the version of the file layout FileStore writes.
+// Version 0 is the original bare JSON array, which is still read.
+const FileFormatVersion = 1
+
+// fileEnvelope is the layout of a versioned store file
+type fileEnvelope struct {
+	Version  int              `json:"version"`
+	Products []domain.Product `json:"products"`
+}
+
+// parseFileContent decodes plain (decrypted and decompressed) file content
+// in any known layout and returns its products and format version. An empty
+// file holds no products.
+func parseFileContent(b []byte) ([]domain.Product, int, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, 0, nil
+	}
+	if b[0] == '[' {
+		var list []domain.Product
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, 0, err
+		}
+		return list, 0, nil
+	}
+	var env fileEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case env.Version < 1:
+		return nil, 0, errors.New("store file has no format version")
+	case env.Version > FileFormatVersion:
+		return nil, 0, fmt.Errorf("store file format version %d is newer than this program supports (%d)", env.Version, FileFormatVersion)
+	}
+	return env.Products, env.Version, nil
+}
+
+// ReadFile returns the products in the store file at path exactly as
+// stored, so an id that appears twice is returned twice. A journal beside
+// the file is not applied. opts supplies the EncryptionKey of an encrypted
+// file.
+func ReadFile(path string, opts Options) ([]domain.Product, error) {
+	aead, err := newAEAD(opts.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list, _, err := decodeFile(aead, b)
+	return list, err
+}
+
+// MigrateFile rewrites the store file at path in FileFormatVersion and
+// returns the version it was in; a current file is left alone. opts must
+// carry the EncryptionKey of an encrypted file, and a .gz path stays
+// compressed. A journal beside the file is folded into it.
+func MigrateFile(path string, opts Options) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	opts.WatchFile = false
+	opts.TrackPriceHistory = false
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := s.version
+	if from == FileFormatVersion && s.journaled == 0 {
+		return from, nil
+	}
+	return from, s.saveToFile()
+}
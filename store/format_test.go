@@ -0,0 +1,76 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateFile_UpgradesLegacyArray(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	legacy := `[{"id":"m1","name":"Old","price":1.5,"quantity":2}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	from, err := MigrateFile(path, Options{})
+	if err != nil || from != 0 {
+		t.Fatalf("expected a migration from version 0, got %d (%v)", from, err)
+	}
+	b, _ := os.ReadFile(path)
+	var env fileEnvelope
+	if err := json.Unmarshal(b, &env); err != nil || env.Version != FileFormatVersion || len(env.Products) != 1 || env.Products[0].Price != 150 {
+		t.Fatalf("unexpected migrated file %q (%v)", b, err)
+	}
+	if from, err := MigrateFile(path, Options{}); err != nil || from != FileFormatVersion {
+		t.Fatalf("expected a current file to be left alone, got %d (%v)", from, err)
+	}
+	if _, err := MigrateFile(t.TempDir()+"/missing.json", Options{}); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing file to fail, got %v", err)
+	}
+}
+
+func TestMigrateFile_KeepsEncoding(t *testing.T) {
+	path := t.TempDir() + "/products.json.gz"
+	opts := Options{EncryptionKey: []byte("0123456789abcdef")}
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	_ = s.Create(context.Background(), domain.Product{ID: "m1", Name: "Secret", Price: 1, Quantity: 1})
+
+	if _, err := MigrateFile(path, Options{}); err == nil {
+		t.Fatal("expected migrating an encrypted file without its key to fail")
+	}
+	if _, err := MigrateFile(path, opts); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	reopened, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if _, err := reopened.Get(context.Background(), "m1"); err != nil {
+		t.Fatalf("expected the product to survive, got %v", err)
+	}
+}
+
+func TestFileStore_RejectsNewerFormat(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	future := `{"version": 99, "products": []}`
+	if err := os.WriteFile(path, []byte(future), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewFileStoreWithOptions(path, Options{RecoverCorrupt: true})
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("expected a newer format to be rejected, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != future {
+		t.Fatal("expected a newer file to be left alone")
+	}
+	if _, err := os.Stat(path + ".corrupt"); !os.IsNotExist(err) {
+		t.Fatal("expected a newer file not to be treated as corrupt")
+	}
+}
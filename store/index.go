@@ -0,0 +1,72 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"slices"
+	"sort"
+)
+
+// categoryIndex maps each category to the ids of the products in it,
+// soft-deleted ones included, so category filters need not scan every product
+type categoryIndex map[string]map[string]struct{}
+
+func (ix categoryIndex) add(category, id string) {
+	ids, ok := ix[category]
+	if !ok {
+		ids = make(map[string]struct{})
+		ix[category] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (ix categoryIndex) remove(category, id string) {
+	ids := ix[category]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(ix, category)
+	}
+}
+
+// priceEntry is one product's position in a priceIndex
+type priceEntry struct {
+	price domain.Money
+	id    string
+}
+
+// priceIndex holds every product ordered by price, then id, so price bounds
+// can be binary-searched. Keeping it sorted makes each write O(n).
+type priceIndex []priceEntry
+
+// search returns the position of (price, id), or where it would be inserted
+func (ix priceIndex) search(price domain.Money, id string) int {
+	return sort.Search(len(ix), func(i int) bool {
+		e := ix[i]
+		return e.price > price || (e.price == price && e.id >= id)
+	})
+}
+
+func (ix *priceIndex) add(price domain.Money, id string) {
+	i := ix.search(price, id)
+	*ix = slices.Insert(*ix, i, priceEntry{price: price, id: id})
+}
+
+func (ix *priceIndex) remove(price domain.Money, id string) {
+	if i := ix.search(price, id); i < len(*ix) && (*ix)[i].id == id {
+		*ix = slices.Delete(*ix, i, i+1)
+	}
+}
+
+// between returns the entries priced within the bounds; a nil bound is open
+func (ix priceIndex) between(min, max *domain.Money) []priceEntry {
+	lo, hi := 0, len(ix)
+	if min != nil {
+		lo = sort.Search(len(ix), func(i int) bool { return ix[i].price >= *min })
+	}
+	if max != nil {
+		hi = sort.Search(len(ix), func(i int) bool { return ix[i].price > *max })
+	}
+	if lo >= hi {
+		return nil
+	}
+	return ix[lo:hi]
+}
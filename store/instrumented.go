@@ -0,0 +1,189 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"time"
+)
+
+// InstrumentedStore wraps any domain.ProductStore and records the count,
+// result and latency of every call in a MetricsRegistry.
+type InstrumentedStore struct {
+	inner   domain.ProductStore
+	metrics *MetricsRegistry
+}
+
+// compile-time assertion
+var (
+	_ domain.ProductStore = (*InstrumentedStore)(nil)
+	_ domain.StoreCloser  = (*InstrumentedStore)(nil)
+)
+
+// NewInstrumentedStore wraps inner, recording into DefaultMetrics
+func NewInstrumentedStore(inner domain.ProductStore) *InstrumentedStore {
+	return NewInstrumentedStoreWithRegistry(inner, DefaultMetrics)
+}
+
+// NewInstrumentedStoreWithRegistry wraps inner, recording into r
+func NewInstrumentedStoreWithRegistry(inner domain.ProductStore, r *MetricsRegistry) *InstrumentedStore {
+	return &InstrumentedStore{inner: inner, metrics: r}
+}
+
+// record observes one call of op started at start
+func (s *InstrumentedStore) record(op string, start time.Time, err error) {
+	s.metrics.observe(op, time.Since(start), err)
+}
+
+func (s *InstrumentedStore) Create(ctx context.Context, product domain.Product) error {
+	start := time.Now()
+	err := s.inner.Create(ctx, product)
+	s.record("create", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	start := time.Now()
+	p, err := s.inner.Get(ctx, id)
+	s.record("get", start, err)
+	return p, err
+}
+
+func (s *InstrumentedStore) Update(ctx context.Context, id string, product domain.Product) error {
+	start := time.Now()
+	err := s.inner.Update(ctx, id, product)
+	s.record("update", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	start := time.Now()
+	out, err := s.inner.UpdateWhere(ctx, filter, patch)
+	s.record("update_where", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) Delete(ctx context.Context, id string) error {
+	start := time.Now()
+	err := s.inner.Delete(ctx, id)
+	s.record("delete", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) BatchDelete(ctx context.Context, ids []string) error {
+	start := time.Now()
+	err := s.inner.BatchDelete(ctx, ids)
+	s.record("batch_delete", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Restore(ctx context.Context, id string) error {
+	start := time.Now()
+	err := s.inner.Restore(ctx, id)
+	s.record("restore", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Purge(ctx context.Context) (int, error) {
+	start := time.Now()
+	n, err := s.inner.Purge(ctx)
+	s.record("purge", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) Clear(ctx context.Context) (int, error) {
+	start := time.Now()
+	n, err := s.inner.Clear(ctx)
+	s.record("clear", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+	start := time.Now()
+	out, err := s.inner.List(ctx, filter)
+	s.record("list", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	start := time.Now()
+	err := s.inner.BulkImport(ctx, products)
+	s.record("bulk_import", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	start := time.Now()
+	err := s.inner.BulkImportWithOptions(ctx, products, opts)
+	s.record("bulk_import", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	start := time.Now()
+	n, err := s.inner.RenameCategory(ctx, from, to)
+	s.record("rename_category", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	start := time.Now()
+	n, err := s.inner.AdjustPriceWhere(ctx, filter, pct)
+	s.record("adjust_price_where", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) Categories(ctx context.Context) (map[string]int, error) {
+	start := time.Now()
+	out, err := s.inner.Categories(ctx)
+	s.record("categories", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	start := time.Now()
+	out, err := s.inner.PriceHistory(ctx, id)
+	s.record("price_history", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	start := time.Now()
+	out, err := s.inner.Stats(ctx, filter)
+	s.record("stats", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	start := time.Now()
+	out, err := s.inner.FindDuplicates(ctx)
+	s.record("find_duplicates", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	start := time.Now()
+	out, err := s.inner.NeedsReorder(ctx)
+	s.record("needs_reorder", start, err)
+	return out, err
+}
+
+func (s *InstrumentedStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	start := time.Now()
+	ch, err := s.inner.Watch(ctx)
+	s.record("watch", start, err)
+	return ch, err
+}
+
+func (s *InstrumentedStore) Flush(ctx context.Context) error {
+	start := time.Now()
+	err := domain.FlushStore(ctx, s.inner)
+	s.record("flush", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Close(ctx context.Context) error {
+	start := time.Now()
+	err := domain.CloseStore(ctx, s.inner)
+	s.record("close", start, err)
+	return err
+}
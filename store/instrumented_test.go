@@ -0,0 +1,38 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentedStoreRecordsMetrics(t *testing.T) {
+	reg := NewMetricsRegistry()
+	s := NewInstrumentedStoreWithRegistry(NewInMemoryStore(), reg)
+	ctx := context.Background()
+
+	_ = s.Create(ctx, domain.Product{ID: "m1", Name: "A", Price: 1, Quantity: 1})
+	_, _ = s.Get(ctx, "m1")
+	_, _ = s.Get(ctx, "missing")
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`inventory_store_operations_total{op="create",result="ok"} 1`,
+		`inventory_store_operations_total{op="get",result="ok"} 1`,
+		`inventory_store_operations_total{op="get",result="error"} 1`,
+		`inventory_store_operation_duration_seconds_count{op="get"} 2`,
+		`inventory_store_operation_duration_seconds_bucket{op="get",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in metrics output:\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+}
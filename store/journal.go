@@ -0,0 +1,141 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// DefaultCompactEvery is how many journal records FileStore appends before
+// it folds them into a full save, when Options.CompactEvery is zero
+const DefaultCompactEvery = 1000
+
+// journalRecord is one line of the journal: the new state of one product,
+// its removal, or a price change for the price history
+type journalRecord struct {
+	Op      string              `json:"op"` // "put", "delete" or "price"
+	ID      string              `json:"id,omitempty"`
+	Product *domain.Product     `json:"product,omitempty"`
+	Change  *domain.PriceChange `json:"change,omitempty"`
+}
+
+func (o Options) compactEvery() int {
+	if o.CompactEvery <= 0 {
+		return DefaultCompactEvery
+	}
+	return o.CompactEvery
+}
+
+func (s *FileStore) journalPath() string {
+	return s.path + ".journal"
+}
+
+// appendJournal records the current state of ids. Callers hold s.mu.
+func (s *FileStore) appendJournal(ids []string) error {
+	recs := make([]journalRecord, len(ids))
+	for i, id := range ids {
+		recs[i] = journalRecord{Op: "delete", ID: id}
+		if p, ok := s.products[id]; ok {
+			recs[i] = journalRecord{Op: "put", Product: &p}
+		}
+	}
+	return s.writeJournal(recs)
+}
+
+// writeJournal appends recs to the journal, then compacts once it holds
+// Options.CompactEvery records. A failed append is truncated away so the
+// journal never ends in a partial record of our own. Callers hold s.mu.
+func (s *FileStore) writeJournal(recs []journalRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.journalPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, s.opts.fileMode())
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil && s.opts.Durable {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Truncate(fi.Size())
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if s.opts.Durable && fi.Size() == 0 {
+		// the journal was just created; make its directory entry durable too
+		if err := syncDir(filepath.Dir(s.path)); err != nil {
+			return err
+		}
+	}
+
+	s.journaled += len(recs)
+	if s.journaled >= s.opts.compactEvery() {
+		// the records are already safe, so a failed compaction only delays it
+		if err := s.saveToFile(); err != nil {
+			slog.Warn("compacting store journal failed", "path", s.journalPath(), "error", err)
+		}
+	}
+	return nil
+}
+
+// replayJournal applies the journal left next to the file, if any, to the
+// loaded products and, under Options.TrackPriceHistory, to the price history.
+// Product records are whole states and price changes are not added twice, so
+// replaying records that a compaction already saved is harmless. A final
+// line cut short by a crash is skipped. Callers hold s.mu.
+func (s *FileStore) replayJournal() error {
+	b, err := os.ReadFile(s.journalPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	n := 0
+	for line := 1; sc.Scan(); line++ {
+		var rec journalRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			if !bytes.HasSuffix(b, []byte("\n")) && line == bytes.Count(b, []byte("\n"))+1 {
+				slog.Warn("ignoring incomplete last journal record", "path", s.journalPath())
+				break
+			}
+			return fmt.Errorf("%s line %d: %w", s.journalPath(), line, err)
+		}
+		switch {
+		case rec.Op == "put" && rec.Product != nil:
+			s.products[rec.Product.ID] = *rec.Product
+		case rec.Op == "delete":
+			delete(s.products, rec.ID)
+		case rec.Op == "price" && rec.Change != nil:
+			if s.history != nil {
+				s.history.replay(rec.ID, *rec.Change)
+			}
+		default:
+			return fmt.Errorf("%s line %d: unknown journal record %q", s.journalPath(), line, rec.Op)
+		}
+		n++
+	}
+	s.journaled = n
+	return sc.Err()
+}
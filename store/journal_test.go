@@ -0,0 +1,162 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileStore_JournalReplaysOnLoad(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	opts := Options{Journal: true, SoftDelete: true}
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "j1", Name: "A", Price: 1, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "j2", Name: "B", Price: 1, Quantity: 1})
+	_ = s.Update(ctx, "j1", domain.Product{Name: "A2", Price: 2, Quantity: 5})
+	_ = s.Delete(ctx, "j2")
+	_, _ = s.Purge(ctx)
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected only the journal to be written, stat err %v", err)
+	}
+	b, _ := os.ReadFile(path + ".journal")
+	if n := strings.Count(string(b), "\n"); n != 5 {
+		t.Fatalf("expected 5 journal records, got %d:\n%s", n, b)
+	}
+
+	reopened, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	p, err := reopened.Get(ctx, "j1")
+	if err != nil || p.Name != "A2" || p.Quantity != 5 {
+		t.Fatalf("expected replayed update, got %+v (%v)", p, err)
+	}
+	if all, _ := reopened.List(ctx, domain.ListFilter{IncludeDeleted: true}); len(all) != 1 {
+		t.Fatalf("expected the purged product gone, got %v", all)
+	}
+}
+
+func TestFileStore_JournalCompacts(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	s, err := NewFileStoreWithOptions(path, Options{Journal: true, CompactEvery: 3})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_ = s.Create(ctx, domain.Product{ID: "c" + strconv.Itoa(i), Name: "C", Price: 1, Quantity: 1})
+	}
+
+	list, _, err := s.readProducts(path)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("expected the compacted file to hold 3 products, got %d (%v)", len(list), err)
+	}
+	b, _ := os.ReadFile(path + ".journal")
+	if n := strings.Count(string(b), "\n"); n != 1 {
+		t.Fatalf("expected 1 record after compaction, got %d", n)
+	}
+	reopened, _ := NewFileStoreWithOptions(path, Options{})
+	if all, _ := reopened.List(ctx, domain.ListFilter{}); len(all) != 4 {
+		t.Fatalf("expected 4 products from file plus journal, got %d", len(all))
+	}
+}
+
+func TestFileStore_JournalRecordsPriceHistory(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	opts := Options{Journal: true, TrackPriceHistory: true, CompactEvery: 5}
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "h1", Name: "A", Price: 100, Quantity: 1})
+	_ = s.Update(ctx, "h1", domain.Product{Name: "A", Price: 150, Quantity: 1})
+
+	// the change is appended to the journal, not written to its own file
+	if _, err := os.Stat(s.historyPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected no history file before compaction, stat err %v", err)
+	}
+	reopened, _ := NewFileStoreWithOptions(path, opts)
+	if changes, _ := reopened.PriceHistory(ctx, "h1"); len(changes) != 1 || changes[0].NewPrice != 150 {
+		t.Fatalf("expected the journaled change after reopening, got %+v", changes)
+	}
+
+	// compaction folds the changes into the history file
+	journal, _ := os.ReadFile(s.journalPath())
+	_ = s.Update(ctx, "h1", domain.Product{Name: "A", Price: 175, Quantity: 1})
+	if _, err := os.Stat(s.journalPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected the journal compacted away, stat err %v", err)
+	}
+	h, _, err := readPriceHistory(s.historyPath(), nil)
+	if err != nil || len(h["h1"]) != 2 {
+		t.Fatalf("expected 2 changes in the history file, got %+v (%v)", h, err)
+	}
+
+	// a journal left behind by a crash during compaction is not counted twice
+	_ = os.WriteFile(s.journalPath(), journal, 0o644)
+	reopened, _ = NewFileStoreWithOptions(path, opts)
+	if changes, _ := reopened.PriceHistory(ctx, "h1"); len(changes) != 2 {
+		t.Fatalf("expected 2 changes after replaying a saved journal, got %+v", changes)
+	}
+}
+
+func TestFileStore_JournalSkipsTornRecord(t *testing.T) {
+	path := t.TempDir() + "/products.json"
+	journal := `{"op":"put","product":{"id":"t1","name":"A","price":1,"quantity":1}}` + "\n" + `{"op":"put","prod`
+	if err := os.WriteFile(path+".journal", []byte(journal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileStoreWithOptions(path, Options{Journal: true})
+	if err != nil {
+		t.Fatalf("expected a torn last record to be skipped, got %v", err)
+	}
+	if _, err := s.Get(context.Background(), "t1"); err != nil {
+		t.Fatalf("expected t1 from the journal, got %v", err)
+	}
+
+	bad := `{"op":"put","prod` + "\n" + journal
+	if err := os.WriteFile(path+".journal", []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStoreWithOptions(path, Options{Journal: true}); err == nil {
+		t.Fatal("expected a corrupt record before the end to fail the load")
+	}
+}
+
+// benchmarkFileStoreUpdate measures one Update against a 50k-product file.
+// The journal case includes its share of the periodic compactions.
+func benchmarkFileStoreUpdate(b *testing.B, opts Options) {
+	path := b.TempDir() + "/bench.json"
+	s, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		b.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	seed := make([]domain.Product, 50_000)
+	for i := range seed {
+		seed[i] = domain.Product{ID: "b-" + strconv.Itoa(i), Name: "Bench", Price: 1, Quantity: 1}
+	}
+	if err := s.BulkImport(ctx, seed); err != nil {
+		b.Fatalf("seed failed: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Update(ctx, "b-"+strconv.Itoa(i%len(seed)), domain.Product{Name: "Bench", Price: 1, Quantity: i})
+	}
+}
+
+func BenchmarkFileStore_Update50kFullSave(b *testing.B) {
+	benchmarkFileStoreUpdate(b, Options{})
+}
+
+func BenchmarkFileStore_Update50kJournal(b *testing.B) {
+	benchmarkFileStoreUpdate(b, Options{Journal: true})
+}
@@ -3,27 +3,130 @@ package store
 
 import (
 	"aexp_assesment/domain"
+	"cmp"
 	"context"
 	"fmt"
-	"sort"
+	"slices"
 	"sync"
 )
 
 // InMemoryStore is a thread-safe in-memory for domain.ProductStore
 type InMemoryStore struct {
-	mu       sync.RWMutex
-	products map[string]domain.Product
+	mu         sync.RWMutex
+	products   map[string]domain.Product
+	byCategory categoryIndex
+	byPrice    *priceIndex  // nil unless Options.PriceIndex
+	history    priceHistory // nil unless Options.TrackPriceHistory
+	watchers   watchers
+	opts       Options
 }
 
 // NewInMemoryStore constructs a new InMemoryStore
 func NewInMemoryStore() *InMemoryStore {
-	return &InMemoryStore{
-		products: make(map[string]domain.Product),
+	return NewInMemoryStoreWithOptions(Options{})
+}
+
+// NewInMemoryStoreWithOptions constructs a new InMemoryStore using opts
+func NewInMemoryStoreWithOptions(opts Options) *InMemoryStore {
+	s := &InMemoryStore{
+		products:   make(map[string]domain.Product),
+		byCategory: make(categoryIndex),
+		opts:       opts,
+	}
+	if opts.PriceIndex {
+		s.byPrice = &priceIndex{}
+	}
+	if opts.TrackPriceHistory {
+		s.history = make(priceHistory)
+	}
+	return s
+}
+
+// live returns the product stored under id unless it is missing or
+// soft-deleted. Callers hold s.mu.
+func (s *InMemoryStore) live(id string) (domain.Product, bool) {
+	p, ok := s.products[id]
+	return p, ok && !p.IsDeleted()
+}
+
+// put stores p and keeps the indexes in step. Callers hold s.mu.
+func (s *InMemoryStore) put(p domain.Product) {
+	if old, ok := s.products[p.ID]; ok {
+		s.unindex(old)
+	}
+	s.products[p.ID] = p
+	s.byCategory.add(p.Category, p.ID)
+	if s.byPrice != nil {
+		s.byPrice.add(p.Price, p.ID)
+	}
+}
+
+// drop removes the product stored under id and its index entries. Callers
+// hold s.mu.
+func (s *InMemoryStore) drop(id string) {
+	if old, ok := s.products[id]; ok {
+		s.unindex(old)
+		delete(s.products, id)
+	}
+}
+
+// unindex removes p from the indexes. Callers hold s.mu.
+func (s *InMemoryStore) unindex(p domain.Product) {
+	s.byCategory.remove(p.Category, p.ID)
+	if s.byPrice != nil {
+		s.byPrice.remove(p.Price, p.ID)
+	}
+}
+
+// each calls fn with every product matching m until fn returns false. When
+// the filter names categories only their indexed products are visited;
+// otherwise price bounds use the price index if there is one. ctx is checked
+// every ctxCheckEvery products so a long scan stops soon after cancellation,
+// returning ctx.Err(). Callers hold s.mu.
+func (s *InMemoryStore) each(ctx context.Context, m listMatcher, fn func(domain.Product) bool) error {
+	visited := 0
+	// visit reports whether to go on after p
+	visit := func(p domain.Product) (bool, error) {
+		if visited++; visited%ctxCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+		}
+		return !m.match(p) || fn(p), nil
+	}
+
+	cats := m.categoryList()
+	if cats == nil && s.byPrice != nil && (m.filter.MinPrice != nil || m.filter.MaxPrice != nil) {
+		for _, e := range s.byPrice.between(m.filter.MinPrice, m.filter.MaxPrice) {
+			if ok, err := visit(s.products[e.id]); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+	if cats == nil {
+		for _, p := range s.products {
+			if ok, err := visit(p); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, c := range cats {
+		for id := range s.byCategory[c] {
+			if ok, err := visit(s.products[id]); !ok {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 // compile-time assertion that InMemoryStore implements domain.ProductStore
-var _ domain.ProductStore = (*InMemoryStore)(nil)
+var (
+	_ domain.ProductStore = (*InMemoryStore)(nil)
+	_ domain.StoreCloser  = (*InMemoryStore)(nil)
+)
 
 func (s *InMemoryStore) Create(ctx context.Context, product domain.Product) error {
 	select {
@@ -32,19 +135,12 @@ func (s *InMemoryStore) Create(ctx context.Context, product domain.Product) erro
 	default:
 	}
 
-	//validations for empty product ID, name, negative price or quantity
-	if product.ID == "" {
-		return domain.NewInvalidProductError("id", "cannot be empty", product.ID)
-	}
-	if product.Name == "" {
-		return domain.NewInvalidProductError("name", "cannot be empty", product.Name)
-	}
-	if product.Price < 0 {
-		return domain.NewInvalidProductError("price", "must be non-negative", product.Price)
-	}
-	if product.Quantity < 0 {
-		return domain.NewInvalidProductError("quantity", "must be non-negative", product.Quantity)
+	//validations for empty product ID, then the shared field rules
+	if err := validateNew(product); err != nil {
+		return err
 	}
+	product.Currency = product.EffectiveCurrency()
+	product = markUpdated(product)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -52,7 +148,8 @@ func (s *InMemoryStore) Create(ctx context.Context, product domain.Product) erro
 	if _, exists := s.products[product.ID]; exists {
 		return domain.NewDuplicateProductError(product.ID)
 	}
-	s.products[product.ID] = product
+	s.put(product.Clone())
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeCreated, ID: product.ID, Product: product})
 	return nil
 }
 
@@ -66,11 +163,11 @@ func (s *InMemoryStore) Get(ctx context.Context, id string) (domain.Product, err
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	p, ok := s.products[id]
+	p, ok := s.live(id)
 	if !ok {
 		return domain.Product{}, domain.NewProductNotFoundError(id)
 	}
-	return p, nil
+	return p.Clone(), nil
 }
 
 func (s *InMemoryStore) Update(ctx context.Context, id string, product domain.Product) error {
@@ -80,27 +177,68 @@ func (s *InMemoryStore) Update(ctx context.Context, id string, product domain.Pr
 	default:
 	}
 
-	if product.Name == "" {
-		return domain.NewInvalidProductError("name", "cannot be empty", product.Name)
-	}
-	if product.Price < 0 {
-		return domain.NewInvalidProductError("price", "must be non-negative", product.Price)
-	}
-	if product.Quantity < 0 {
-		return domain.NewInvalidProductError("quantity", "must be non-negative", product.Quantity)
+	if err := domain.ValidateProduct(product); err != nil {
+		return err
 	}
+	product.Currency = product.EffectiveCurrency()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.products[id]; !ok {
+	old, ok := s.live(id)
+	if !ok {
 		return domain.NewProductNotFoundError(id)
 	}
 	product.ID = id
-	s.products[id] = product
+	product = markUpdated(product)
+	if s.history != nil {
+		s.history.record(old, product)
+	}
+	s.put(product.Clone())
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeUpdated, ID: id, Product: product})
 	return nil
 }
 
+// UpdateWhere patches every product matching filter under one lock. Every
+// result is validated before any is stored.
+func (s *InMemoryStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated []domain.Product
+	var invalid error
+	err := s.each(ctx, newListMatcher(filter), func(p domain.Product) bool {
+		np := markUpdated(patch.Apply(p))
+		if err := domain.ValidateProduct(np); err != nil {
+			invalid = fmt.Errorf("id=%s: %w", p.ID, err)
+			return false
+		}
+		updated = append(updated, np)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if invalid != nil {
+		return nil, invalid
+	}
+	for _, p := range updated {
+		if s.history != nil {
+			s.history.record(s.products[p.ID], p)
+		}
+		s.put(p.Clone())
+		s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeUpdated, ID: p.ID, Product: p})
+	}
+	sortProducts(updated, filter)
+	return updated, nil
+}
+
 func (s *InMemoryStore) Delete(ctx context.Context, id string) error {
 	select {
 	case <-ctx.Done():
@@ -111,147 +249,379 @@ func (s *InMemoryStore) Delete(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.products[id]; !ok {
+	p, ok := s.live(id)
+	if !ok {
 		return domain.NewProductNotFoundError(id)
 	}
-	delete(s.products, id)
+	s.remove(p)
 	return nil
 }
 
-func (s *InMemoryStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+// remove deletes p, or marks it deleted when soft delete is enabled, and
+// publishes the event. Callers hold s.mu.
+func (s *InMemoryStore) remove(p domain.Product) {
+	if s.opts.SoftDelete {
+		p = markDeleted(p)
+		s.put(p)
+	} else {
+		s.drop(p.ID)
+	}
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeDeleted, ID: p.ID, Product: p})
+}
+
+// BatchDelete removes all ids under a single lock. If any id is unknown
+// nothing is deleted and a ProductNotFoundError is returned.
+func (s *InMemoryStore) BatchDelete(ctx context.Context, ids []string) error {
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.live(id); !ok {
+			return domain.NewProductNotFoundError(id)
+		}
+	}
+	for _, id := range ids {
+		p, ok := s.live(id)
+		if !ok {
+			continue // listed twice
+		}
+		s.remove(p)
+	}
+	return nil
+}
+
+// Restore clears the deletion mark of a soft-deleted product
+func (s *InMemoryStore) Restore(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok {
+		return domain.NewProductNotFoundError(id)
+	}
+	if !p.IsDeleted() {
+		return nil
+	}
+	p.DeletedAt = nil
+	p = markUpdated(p)
+	s.put(p)
+	s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeRestored, ID: id, Product: p})
+	return nil
+}
+
+// Purge removes every soft-deleted product for good and publishes a
+// ChangeDeleted event for each
+func (s *InMemoryStore) Purge(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged []domain.Product
+	for id, p := range s.products {
+		if p.IsDeleted() {
+			s.drop(id)
+			purged = append(purged, p)
+		}
+	}
+	s.watchers.publishDeleted(purged)
+	return len(purged), nil
+}
+
+// Clear removes every product, including soft-deleted ones, and publishes
+// a ChangeDeleted event for each
+func (s *InMemoryStore) Clear(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
 	default:
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make([]domain.Product, 0, len(s.products))
+	for _, p := range s.products {
+		removed = append(removed, p)
+	}
+	s.products = make(map[string]domain.Product)
+	s.byCategory = make(categoryIndex)
+	if s.byPrice != nil {
+		s.byPrice = &priceIndex{}
+	}
+	if s.history != nil {
+		s.history = make(priceHistory)
+	}
+	s.watchers.publishDeleted(removed)
+	return len(removed), nil
+}
+
+// Snapshot returns a deep copy of every stored product, soft-deleted ones
+// included, sorted by id. Pass it to RestoreSnapshot to go back to this
+// state later.
+func (s *InMemoryStore) Snapshot() []domain.Product {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	out := make([]domain.Product, 0, len(s.products))
 	for _, p := range s.products {
-		if filter.Category != "" && p.Category != filter.Category {
-			continue
-		}
-		if filter.MinPrice != nil && p.Price < *filter.MinPrice {
-			continue
-		}
-		if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
-			continue
-		}
+		out = append(out, p.Clone())
+	}
+	slices.SortFunc(out, func(a, b domain.Product) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+// RestoreSnapshot replaces the whole store content with deep copies of
+// products, as taken by Snapshot. Like Clear it drops the price history and
+// publishes no change events. The products are not validated; a repeated id
+// keeps its last product.
+func (s *InMemoryStore) RestoreSnapshot(products []domain.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products = make(map[string]domain.Product, len(products))
+	s.byCategory = make(categoryIndex)
+	if s.byPrice != nil {
+		s.byPrice = &priceIndex{}
+	}
+	if s.history != nil {
+		s.history = make(priceHistory)
+	}
+	for _, p := range products {
+		s.put(p.Clone())
+	}
+}
+
+// Clone returns an independent store with the same options holding deep
+// copies of the products and price history. Changes to either store never
+// show in the other, and the clone starts without watchers.
+func (s *InMemoryStore) Clone() *InMemoryStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := NewInMemoryStoreWithOptions(s.opts)
+	for _, p := range s.products {
+		c.put(p.Clone())
+	}
+	for id, changes := range s.history {
+		c.history[id] = slices.Clone(changes)
+	}
+	return c
+}
+
+func (s *InMemoryStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []domain.Product
+	err := s.each(ctx, newListMatcher(filter), func(p domain.Product) bool {
 		out = append(out, p)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
+	return clonePage(out, filter), nil
+}
 
-	switch filter.SortBy {
-	case "name":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Name > out[j].Name
-			}
-			return out[i].Name < out[j].Name
-		})
-	case "price":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Price > out[j].Price
-			}
-			return out[i].Price < out[j].Price
-		})
-	case "quantity":
-		sort.Slice(out, func(i, j int) bool {
-			if filter.Order == "desc" {
-				return out[i].Quantity > out[j].Quantity
-			}
-			return out[i].Quantity < out[j].Quantity
-		})
+// RenameCategory moves every live product in category from to category to
+// through UpdateWhere, so it is validated and saved as one step
+func (s *InMemoryStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	if err := checkRename(from, to); err != nil {
+		return 0, err
 	}
+	updated, err := s.UpdateWhere(ctx, domain.ListFilter{Category: from}, domain.ProductPatch{Category: &to})
+	return len(updated), err
+}
 
-	return out, nil
+// AdjustPriceWhere changes the price of every live product matching filter
+// by pct percent through UpdateWhere, so it is validated and saved as one
+// step
+func (s *InMemoryStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	if err := checkPercent(pct); err != nil {
+		return 0, err
+	}
+	updated, err := s.UpdateWhere(ctx, filter, domain.ProductPatch{PricePercent: &pct})
+	return len(updated), err
 }
 
-func (s *InMemoryStore) BulkImport(ctx context.Context, products []domain.Product) error {
+// Categories counts live products per category
+func (s *InMemoryStore) Categories(ctx context.Context) (map[string]int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return countCategories(s.products), nil
+}
+
+// Stats totals the products matching filter, using the same indexes as List
+func (s *InMemoryStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	var st domain.InventoryStats
 	if err := ctx.Err(); err != nil {
-		return err
+		return st, err
 	}
 
-	const maxWorkers = 10
-	if len(products) == 0 {
-		return nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.each(ctx, newListMatcher(filter), func(p domain.Product) bool {
+		st.Add(p)
+		return true
+	})
+	if err != nil {
+		return domain.InventoryStats{}, err
 	}
+	return st, nil
+}
 
-	type result struct {
-		id  string
-		err error
+// FindDuplicates reports live products sharing a name and category
+func (s *InMemoryStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
 
-	jobs := make(chan domain.Product)
-	results := make(chan result, len(products))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return findDuplicates(s.products), nil
+}
 
-	var wg sync.WaitGroup
+// PriceHistory returns the price changes recorded for id. Changes are kept
+// after the product is deleted.
+func (s *InMemoryStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
 
-	worker := func() {
-		defer wg.Done()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case p, ok := <-jobs:
-				if !ok {
-					return
-				}
-				if err := s.Create(ctx, p); err != nil {
-					results <- result{id: p.ID, err: fmt.Errorf("id=%s: %w", p.ID, err)}
-				} else {
-					results <- result{id: p.ID, err: nil}
-				}
-			}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.products[id]; !ok && len(s.history[id]) == 0 {
+		return nil, domain.NewProductNotFoundError(id)
+	}
+	return s.history.of(id), nil
+}
+
+// NeedsReorder returns products whose stock has fallen below their own
+// ReorderLevel, lowest quantity first.
+func (s *InMemoryStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]domain.Product, 0)
+	for _, p := range s.products {
+		if !p.IsDeleted() && p.Quantity < p.ReorderLevel {
+			out = append(out, p.Clone())
 		}
 	}
+	sortProducts(out, domain.ListFilter{SortBy: "quantity"})
+	return out, nil
+}
+
+// BulkImport is BulkImportWithOptions with domain.DefaultBulkImportOptions
+func (s *InMemoryStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	return s.BulkImportWithOptions(ctx, products, domain.DefaultBulkImportOptions)
+}
 
-	nWorkers := maxWorkers
-	if len(products) < nWorkers {
-		nWorkers = len(products)
+// BulkImportWithOptions stores products and reports failures as a
+// *domain.BulkImportError. An atomic import checks the whole batch first and
+// stores nothing if any product fails; a best-effort import creates products
+// concurrently through importConcurrently and keeps every one that succeeded.
+func (s *InMemoryStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	wg.Add(nWorkers)
-	for i := 0; i < nWorkers; i++ {
-		go worker()
+	if len(products) == 0 {
+		return nil
+	}
+	if opts.Atomic {
+		return s.bulkImportAtomic(ctx, products)
 	}
+	return importConcurrently(ctx, products, opts, s.Create)
+}
 
-	// feed jobs
-	go func() {
-		defer close(jobs)
-		for _, p := range products {
-			select {
-			case <-ctx.Done():
-				return
-			case jobs <- p:
-			}
+// bulkImportAtomic validates the whole batch, then stores it under a single
+// lock so either every product is created or none is.
+func (s *InMemoryStore) bulkImportAtomic(ctx context.Context, products []domain.Product) error {
+	tick := progressTicker(ctx, len(products))
+	var collected domain.MultiError
+	seen := make(map[string]bool, len(products))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range products {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-	}()
-
-	// collect results
-	var collected error
-	received := 0
-	for received < len(products) {
-		select {
-		case <-ctx.Done():
-			// wait for workers to stop then return context error
-			wg.Wait()
-			return ctx.Err()
-		case res := <-results:
-			received++
-			if res.err != nil {
-				if collected == nil {
-					collected = res.err
-				} else {
-					collected = fmt.Errorf("%v; %w", collected, res.err)
-				}
-			}
+		tick()
+		if err := validateNew(p); err != nil {
+			collected.Append(fmt.Errorf("id=%s: %w", p.ID, err))
+			continue
+		}
+		if _, exists := s.products[p.ID]; exists || seen[p.ID] {
+			collected.Append(fmt.Errorf("id=%s: %w", p.ID, domain.NewDuplicateProductError(p.ID)))
+			continue
 		}
+		seen[p.ID] = true
 	}
+	if err := domain.NewBulkImportError(nil, &collected); err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.Currency = p.EffectiveCurrency()
+		p = markUpdated(p)
+		s.put(p.Clone())
+		s.watchers.publish(domain.ChangeEvent{Op: domain.ChangeCreated, ID: p.ID, Product: p})
+	}
+	return nil
+}
+
+// Watch subscribes to change events. Events are published while the store
+// lock is held so subscribers observe mutations in commit order.
+func (s *InMemoryStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	return s.watchers.subscribe(ctx)
+}
 
-	// all results received; wait for workers
-	wg.Wait()
-	return collected
+// Flush does nothing: every write is applied immediately
+func (s *InMemoryStore) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing; the products live as long as the store value
+func (s *InMemoryStore) Close(ctx context.Context) error {
+	return nil
 }
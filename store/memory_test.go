@@ -3,7 +3,10 @@ package store
 import (
 	"aexp_assesment/domain"
 	"context"
+	"errors"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -22,6 +25,9 @@ func TestCreateValidation_TableDriven(t *testing.T) {
 		{"empty name", domain.Product{ID: "x1", Name: "", Price: 1, Quantity: 1}, true},
 		{"negative price", domain.Product{ID: "x2", Name: "A", Price: -1, Quantity: 1}, true},
 		{"negative quantity", domain.Product{ID: "x3", Name: "A", Price: 1, Quantity: -5}, true},
+		{"name too long", domain.Product{ID: "x6", Name: strings.Repeat("n", 201), Price: 1, Quantity: 1}, true},
+		{"name with control char", domain.Product{ID: "x7", Name: "bad\tname", Price: 1, Quantity: 1}, true},
+		{"negative reorder level", domain.Product{ID: "x5", Name: "A", Price: 1, Quantity: 1, ReorderLevel: -1}, true},
 		{"valid", domain.Product{ID: "x4", Name: "A", Price: 1, Quantity: 0}, false},
 	}
 
@@ -149,20 +155,40 @@ func TestInMemoryStore_ConcurrentAccess(t *testing.T) {
 	}
 }
 
-func TestBulkImport_Timeout(t *testing.T) {
+func TestBulkImport_CancelMidImport(t *testing.T) {
 	s := NewInMemoryStore()
-	// large number to ensure work takes some time
+	// large number to ensure work is still going when it is cancelled
 	n := 1000
 	products := make([]domain.Product, 0, n)
 	for i := 0; i < n; i++ {
 		products = append(products, domain.Product{ID: "t-" + strconv.Itoa(i), Name: "X", Price: 1.0, Quantity: 1, Category: "C"})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	// best effort goes through the concurrent workers; cancel from the
+	// progress callback so the import is always cut off part way
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	err := s.BulkImport(ctx, products)
-	if err == nil {
-		t.Fatalf("expected timeout or cancellation error, got nil")
+	ctx = domain.WithProgress(ctx, func(done, total int) {
+		if done == 10 {
+			cancel()
+		}
+	})
+	err := s.BulkImportWithOptions(ctx, products, domain.BulkImportOptions{Atomic: false})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var bie *domain.BulkImportError
+	if !errors.As(err, &bie) {
+		t.Fatalf("expected a *domain.BulkImportError, got %T", err)
+	}
+	stored, _ := s.List(context.Background(), domain.ListFilter{})
+	ids := make([]string, len(stored))
+	for i, p := range stored {
+		ids[i] = p.ID
+	}
+	slices.Sort(ids)
+	if !slices.Equal(bie.Applied, ids) {
+		t.Fatalf("Applied %v does not match the stored ids %v", bie.Applied, ids)
 	}
 }
 
@@ -185,3 +211,652 @@ func BenchmarkInMemoryStore_Get(b *testing.B) {
 		_, _ = s.Get(context.Background(), id)
 	}
 }
+
+func TestListCategoryIndexFollowsMutations(t *testing.T) {
+	s := NewInMemoryStoreWithOptions(Options{SoftDelete: true})
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "i1", Name: "A", Price: 1, Quantity: 1, Category: "Office"})
+	_ = s.Create(ctx, domain.Product{ID: "i2", Name: "B", Price: 1, Quantity: 1, Category: "Office"})
+	_ = s.Create(ctx, domain.Product{ID: "i3", Name: "C", Price: 1, Quantity: 1, Category: "Books"})
+
+	_ = s.Update(ctx, "i1", domain.Product{Name: "A", Price: 1, Quantity: 1, Category: "Books"})
+	_ = s.Delete(ctx, "i3")
+	_, _ = s.RenameCategory(ctx, "Office", "Garden")
+
+	ids := func(filter domain.ListFilter) string {
+		filter.SortBy = "name"
+		out, err := s.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		var got []string
+		for _, p := range out {
+			got = append(got, p.ID)
+		}
+		return strings.Join(got, ",")
+	}
+	if got := ids(domain.ListFilter{Category: "Books"}); got != "i1" {
+		t.Fatalf("Books: expected i1, got %q", got)
+	}
+	if got := ids(domain.ListFilter{Categories: []string{"Office", "Garden"}}); got != "i2" {
+		t.Fatalf("Office/Garden: expected i2, got %q", got)
+	}
+	if got := ids(domain.ListFilter{Category: "Books", IncludeDeleted: true}); got != "i1,i3" {
+		t.Fatalf("Books with deleted: expected i1,i3, got %q", got)
+	}
+
+	_ = s.Restore(ctx, "i3")
+	_, _ = s.Purge(ctx)
+	if got := ids(domain.ListFilter{Category: "Books"}); got != "i1,i3" {
+		t.Fatalf("Books after restore: expected i1,i3, got %q", got)
+	}
+	_, _ = s.Clear(ctx)
+	if got := ids(domain.ListFilter{Category: "Books"}); got != "" {
+		t.Fatalf("expected nothing after Clear, got %q", got)
+	}
+}
+
+// BenchmarkInMemoryStore_ListCategory filters 100k products down to one
+// small category through the index, and by scanning every product as List
+// did before the index existed.
+func BenchmarkInMemoryStore_ListCategory(b *testing.B) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	for i := 0; i < 100_000; i++ {
+		category := "bulk-" + strconv.Itoa(i%100)
+		if i%1000 == 0 {
+			category = "rare"
+		}
+		_ = s.Create(ctx, domain.Product{ID: "b-" + strconv.Itoa(i), Name: "X", Price: 1, Quantity: 1, Category: category})
+	}
+	filter := domain.ListFilter{Category: "rare"}
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = s.List(ctx, filter)
+		}
+	})
+	b.Run("full-scan", func(b *testing.B) {
+		m := newListMatcher(filter)
+		for i := 0; i < b.N; i++ {
+			s.mu.RLock()
+			out := make([]domain.Product, 0)
+			for _, p := range s.products {
+				if m.match(p) {
+					out = append(out, p.Clone())
+				}
+			}
+			s.mu.RUnlock()
+		}
+	})
+}
+
+func TestListPriceIndexMatchesScan(t *testing.T) {
+	plain := NewInMemoryStoreWithOptions(Options{SoftDelete: true})
+	indexed := NewInMemoryStoreWithOptions(Options{SoftDelete: true, PriceIndex: true})
+	ctx := context.Background()
+	for _, s := range []*InMemoryStore{plain, indexed} {
+		for i := 0; i < 50; i++ {
+			_ = s.Create(ctx, domain.Product{ID: "p" + strconv.Itoa(i), Name: "P" + strconv.Itoa(i), Price: domain.Money(i % 10 * 100), Quantity: 1})
+		}
+		_ = s.Update(ctx, "p3", domain.Product{Name: "P3", Price: 450, Quantity: 1})
+		_ = s.Delete(ctx, "p4")
+		_ = s.BatchDelete(ctx, []string{"p14", "p24"})
+		_, _ = s.Purge(ctx)
+		free, cheap := domain.Money(0), domain.Money(50)
+		_, _ = s.UpdateWhere(ctx, domain.ListFilter{MinPrice: &free, MaxPrice: &free}, domain.ProductPatch{Price: &cheap})
+	}
+
+	bound := func(v domain.Money) *domain.Money { return &v }
+	for _, f := range []domain.ListFilter{
+		{MinPrice: bound(300), MaxPrice: bound(500)},
+		{MinPrice: bound(450)},
+		{MaxPrice: bound(50)},
+		{MinPrice: bound(901)},
+		{MinPrice: bound(500), MaxPrice: bound(100)},
+	} {
+		f.SortBy = "price,name"
+		want, _ := plain.List(ctx, f)
+		got, _ := indexed.List(ctx, f)
+		if !slices.EqualFunc(want, got, func(a, b domain.Product) bool { return a.ID == b.ID }) {
+			t.Fatalf("min=%v max=%v: indexed list %v differs from scan %v", f.MinPrice, f.MaxPrice, got, want)
+		}
+	}
+}
+
+// BenchmarkInMemoryStore_ListPriceRange selects about 0.1% of 100k products
+// by price, with and without Options.PriceIndex.
+func BenchmarkInMemoryStore_ListPriceRange(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		opts Options
+	}{{"indexed", Options{PriceIndex: true}}, {"full-scan", Options{}}} {
+		s := NewInMemoryStoreWithOptions(tc.opts)
+		ctx := context.Background()
+		for i := 0; i < 100_000; i++ {
+			_ = s.Create(ctx, domain.Product{ID: "b-" + strconv.Itoa(i), Name: "X", Price: domain.Money(i), Quantity: 1})
+		}
+		lo, hi := domain.Money(50_000), domain.Money(50_099)
+		filter := domain.ListFilter{MinPrice: &lo, MaxPrice: &hi}
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = s.List(ctx, filter)
+			}
+		})
+	}
+}
+
+func TestInMemoryStore_Watch(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	_ = s.Create(ctx, domain.Product{ID: "w1", Name: "W", Price: 1, Quantity: 1})
+	_ = s.Update(ctx, "w1", domain.Product{Name: "W2", Price: 2, Quantity: 1})
+	_ = s.Delete(ctx, "w1")
+
+	want := []domain.ChangeOp{domain.ChangeCreated, domain.ChangeUpdated, domain.ChangeDeleted}
+	for _, op := range want {
+		ev := <-events
+		if ev.Op != op || ev.ID != "w1" {
+			t.Fatalf("expected %s for w1, got %+v", op, ev)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatalf("expected channel to close after cancel")
+	}
+}
+
+func TestListMaxQuantity(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "a", Name: "A", Price: 1, Quantity: 5})
+	_ = s.Create(ctx, domain.Product{ID: "b", Name: "B", Price: 1, Quantity: 2})
+	_ = s.Create(ctx, domain.Product{ID: "c", Name: "C", Price: 1, Quantity: 0})
+
+	threshold := 2
+	out, err := s.List(ctx, domain.ListFilter{MaxQuantity: &threshold, SortBy: "quantity"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(out) != 2 || out[0].ID != "c" || out[1].ID != "b" {
+		t.Fatalf("unexpected low-stock result: %+v", out)
+	}
+}
+
+func TestListQuantityRange(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	for i, q := range []int{0, 1, 3, 5, 9} {
+		_ = s.Create(ctx, domain.Product{ID: "q" + strconv.Itoa(i), Name: "Q", Price: 1, Quantity: q})
+	}
+
+	lo, hi := 1, 5
+	out, err := s.List(ctx, domain.ListFilter{MinQuantity: &lo, MaxQuantity: &hi, SortBy: "quantity"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(out) != 3 || out[0].Quantity != 1 || out[2].Quantity != 5 {
+		t.Fatalf("unexpected quantity range result: %+v", out)
+	}
+}
+
+// BenchmarkListTopK takes the 10 cheapest of 100k products with the heap
+// path and with a full sort of every match.
+func BenchmarkListTopK(b *testing.B) {
+	all := make([]domain.Product, 100_000)
+	for i := range all {
+		all[i] = domain.Product{ID: strconv.Itoa(i), Price: domain.Money((i * 7919) % 100_000)}
+	}
+	filter := domain.ListFilter{SortBy: "price", Limit: 10}
+	work := make([]domain.Product, len(all))
+
+	b.Run("top-k", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(work, all)
+			_ = pageProducts(work, filter)
+		}
+	})
+	b.Run("full-sort", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(work, all)
+			sortProducts(work, filter)
+			_ = work[:filter.Limit]
+		}
+	})
+}
+
+func TestListInStock(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "a", Name: "A", Price: 1, Quantity: 5})
+	_ = s.Create(ctx, domain.Product{ID: "b", Name: "B", Price: 1, Quantity: 0})
+
+	for _, want := range []bool{true, false} {
+		out, err := s.List(ctx, domain.ListFilter{InStock: &want})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		if len(out) != 1 || (out[0].Quantity > 0) != want {
+			t.Fatalf("InStock=%v: unexpected result %+v", want, out)
+		}
+	}
+}
+
+func TestNeedsReorder(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "r1", Name: "A", Price: 1, Quantity: 2, ReorderLevel: 5})
+	_ = s.Create(ctx, domain.Product{ID: "r2", Name: "B", Price: 1, Quantity: 5, ReorderLevel: 5})
+	_ = s.Create(ctx, domain.Product{ID: "r3", Name: "C", Price: 1, Quantity: 0})
+
+	out, err := s.NeedsReorder(ctx)
+	if err != nil {
+		t.Fatalf("needs reorder failed: %v", err)
+	}
+	if len(out) != 1 || out[0].ID != "r1" {
+		t.Fatalf("unexpected reorder result: %+v", out)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "t1", Name: "A", Price: 1, Quantity: 1, Tags: []string{"sale", "new"}})
+	_ = s.Create(ctx, domain.Product{ID: "t2", Name: "B", Price: 1, Quantity: 1, Tags: []string{"sale"}})
+	_ = s.Create(ctx, domain.Product{ID: "t3", Name: "C", Price: 1, Quantity: 1})
+
+	cases := []struct {
+		name   string
+		filter domain.ListFilter
+		want   int
+	}{
+		{"any single", domain.ListFilter{TagsAny: []string{"new"}}, 1},
+		{"any multiple", domain.ListFilter{TagsAny: []string{"new", "sale"}}, 2},
+		{"all", domain.ListFilter{TagsAll: []string{"new", "sale"}}, 1},
+		{"all missing", domain.ListFilter{TagsAll: []string{"clearance"}}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := s.List(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if len(out) != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, len(out))
+			}
+		})
+	}
+}
+
+func TestListCaseInsensitiveNameSortDesc(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "z", Name: "Zebra", Price: 1, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "a", Name: "apple", Price: 1, Quantity: 1})
+
+	out, _ := s.List(ctx, domain.ListFilter{SortBy: "name", Order: "desc", CaseInsensitive: true})
+	if out[0].Name != "Zebra" || out[1].Name != "apple" {
+		t.Fatalf("unexpected order: %+v", out)
+	}
+}
+
+func TestListMultiKeySort(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "1", Name: "Delta", Price: 5, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "2", Name: "Alpha", Price: 5, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "3", Name: "Charlie", Price: 1, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "4", Name: "Bravo", Price: 5, Quantity: 1})
+
+	cases := []struct {
+		name   string
+		filter domain.ListFilter
+		want   []string
+	}{
+		{"price then name", domain.ListFilter{SortBy: "price,name"}, []string{"Charlie", "Alpha", "Bravo", "Delta"}},
+		{"price then name desc", domain.ListFilter{SortBy: "price, name", Order: "desc"}, []string{"Delta", "Bravo", "Alpha", "Charlie"}},
+		{"single key still works", domain.ListFilter{SortBy: "name"}, []string{"Alpha", "Bravo", "Charlie", "Delta"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := s.List(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			for i, name := range tc.want {
+				if out[i].Name != name {
+					t.Fatalf("position %d: expected %s, got %s", i, name, out[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestListSortByValue(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "1", Name: "Cheap bulk", Price: 100, Quantity: 50}) // 5000
+	_ = s.Create(ctx, domain.Product{ID: "2", Name: "Pricey", Price: 9000, Quantity: 1})     // 9000
+	_ = s.Create(ctx, domain.Product{ID: "3", Name: "Empty", Price: 99999, Quantity: 0})     // 0
+	_ = s.Create(ctx, domain.Product{ID: "4", Name: "Also 5000", Price: 2500, Quantity: 2})  // 5000
+
+	out, err := s.List(ctx, domain.ListFilter{SortBy: "value,name", Order: "desc"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	want := []string{"2", "1", "4", "3"}
+	for i, id := range want {
+		if out[i].ID != id {
+			t.Fatalf("position %d: expected %s, got %s (%+v)", i, id, out[i].ID, out)
+		}
+	}
+}
+
+func TestCreateDefaultsCurrencyAndFilters(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 100, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "c2", Name: "B", Price: 100, Quantity: 1, Currency: "EUR"})
+
+	p, err := s.Get(ctx, "c1")
+	if err != nil || p.Currency != domain.DefaultCurrency {
+		t.Fatalf("expected default currency %s, got %q (%v)", domain.DefaultCurrency, p.Currency, err)
+	}
+	out, _ := s.List(ctx, domain.ListFilter{Currency: "EUR"})
+	if len(out) != 1 || out[0].ID != "c2" {
+		t.Fatalf("unexpected currency filter result: %+v", out)
+	}
+	if err := s.Create(ctx, domain.Product{ID: "c3", Name: "C", Currency: "XYZ"}); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected invalid currency to be rejected, got %v", err)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	for _, id := range []string{"b1", "b2", "b3"} {
+		_ = s.Create(ctx, domain.Product{ID: id, Name: id, Price: 1, Quantity: 1})
+	}
+
+	if err := s.BatchDelete(ctx, []string{"b1", "missing"}); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected ProductNotFoundError, got %v", err)
+	}
+	if _, err := s.Get(ctx, "b1"); err != nil {
+		t.Fatalf("failed batch must not delete anything: %v", err)
+	}
+
+	if err := s.BatchDelete(ctx, []string{"b1", "b2", "b1"}); err != nil {
+		t.Fatalf("BatchDelete failed: %v", err)
+	}
+	out, _ := s.List(ctx, domain.ListFilter{})
+	if len(out) != 1 || out[0].ID != "b3" {
+		t.Fatalf("unexpected remaining products: %+v", out)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "u1", Name: "A", Price: 100, Quantity: 1, Category: "Phones"})
+	_ = s.Create(ctx, domain.Product{ID: "u2", Name: "B", Price: 200, Quantity: 2, Category: "Phones"})
+	_ = s.Create(ctx, domain.Product{ID: "u3", Name: "C", Price: 300, Quantity: 3, Category: "Tablets"})
+
+	mobile := "Mobile"
+	updated, err := s.UpdateWhere(ctx, domain.ListFilter{Category: "Phones"}, domain.ProductPatch{Category: &mobile})
+	if err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if len(updated) != 2 {
+		t.Fatalf("expected 2 updated products, got %d", len(updated))
+	}
+	out, _ := s.List(ctx, domain.ListFilter{Category: "Mobile"})
+	if len(out) != 2 || out[0].Price == 0 {
+		t.Fatalf("unexpected products after update: %+v", out)
+	}
+
+	negative := -1
+	if _, err := s.UpdateWhere(ctx, domain.ListFilter{}, domain.ProductPatch{Quantity: &negative}); !domain.IsInvalidProductError(err) {
+		t.Fatalf("expected InvalidProductError, got %v", err)
+	}
+	if p, _ := s.Get(ctx, "u3"); p.Quantity != 3 {
+		t.Fatalf("failed UpdateWhere must not change anything, got quantity %d", p.Quantity)
+	}
+}
+
+func TestBulkImport_ErrorsAreInspectable(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "e1", Name: "A", Price: 1, Quantity: 1})
+
+	err := s.BulkImport(ctx, []domain.Product{
+		{ID: "e1", Name: "Dup", Price: 1, Quantity: 1},
+		{ID: "e2", Name: "", Price: 1, Quantity: 1},
+		{ID: "e3", Name: "Ok", Price: 1, Quantity: 1},
+	})
+	var me *domain.MultiError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected *domain.MultiError, got %T", err)
+	}
+	var dup, invalid int
+	for _, e := range me.Errors() {
+		switch {
+		case domain.IsDuplicateProductError(e):
+			dup++
+		case domain.IsInvalidProductError(e):
+			invalid++
+		}
+	}
+	if dup != 1 || invalid != 1 || len(me.Errors()) != 2 {
+		t.Fatalf("expected one duplicate and one invalid error, got %v", me.Errors())
+	}
+}
+
+func TestBulkImport_AtomicStoresNothingOnFailure(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "x1", Name: "A", Price: 1, Quantity: 1})
+
+	err := s.BulkImport(ctx, []domain.Product{
+		{ID: "x2", Name: "B", Price: 1, Quantity: 1},
+		{ID: "x1", Name: "Dup", Price: 1, Quantity: 1},
+	})
+	if !domain.IsDuplicateProductError(err) {
+		t.Fatalf("expected duplicate error, got %v", err)
+	}
+	if _, err := s.Get(ctx, "x2"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("atomic import must not store x2, got %v", err)
+	}
+}
+
+func TestReturnedProductsDoNotShareTags(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	tags := []string{"sale", "new"}
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1, Tags: tags})
+	tags[0] = "caller"
+
+	got, _ := s.Get(ctx, "c1")
+	got.Tags[0] = "mutated"
+	list, _ := s.List(ctx, domain.ListFilter{})
+	list[0].Tags[1] = "mutated"
+
+	p, _ := s.Get(ctx, "c1")
+	if p.Tags[0] != "sale" || p.Tags[1] != "new" {
+		t.Fatalf("store copy was changed through a returned product: %v", p.Tags)
+	}
+}
+
+func TestListAttributeEquals(t *testing.T) {
+	s := NewInMemoryStore()
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "a1", Name: "Red S", Price: 1, Quantity: 1, Attributes: map[string]string{"color": "red", "size": "S"}})
+	_ = s.Create(ctx, domain.Product{ID: "a2", Name: "Red M", Price: 1, Quantity: 1, Attributes: map[string]string{"color": "red", "size": "M"}})
+	_ = s.Create(ctx, domain.Product{ID: "a3", Name: "Plain", Price: 1, Quantity: 1})
+
+	out, _ := s.List(ctx, domain.ListFilter{AttributeEquals: map[string]string{"color": "red"}})
+	if len(out) != 2 {
+		t.Fatalf("expected 2 red products, got %+v", out)
+	}
+	out, _ = s.List(ctx, domain.ListFilter{AttributeEquals: map[string]string{"color": "red", "size": "M"}})
+	if len(out) != 1 || out[0].ID != "a2" {
+		t.Fatalf("expected only a2, got %+v", out)
+	}
+
+	out[0].Attributes["color"] = "mutated"
+	if p, _ := s.Get(ctx, "a2"); p.Attributes["color"] != "red" {
+		t.Fatalf("store copy was changed through a returned product: %v", p.Attributes)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	s := NewInMemoryStoreWithOptions(Options{SoftDelete: true})
+	ctx := context.Background()
+	_ = s.Create(ctx, domain.Product{ID: "s1", Name: "A", Price: 1, Quantity: 1})
+	_ = s.Create(ctx, domain.Product{ID: "s2", Name: "B", Price: 1, Quantity: 1})
+
+	if err := s.Delete(ctx, "s1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "s1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected soft-deleted product to be hidden, got %v", err)
+	}
+	if err := s.Delete(ctx, "s1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected second delete to report not found, got %v", err)
+	}
+	if out, _ := s.List(ctx, domain.ListFilter{}); len(out) != 1 {
+		t.Fatalf("expected 1 live product, got %+v", out)
+	}
+	out, _ := s.List(ctx, domain.ListFilter{IncludeDeleted: true})
+	if len(out) != 2 {
+		t.Fatalf("expected 2 products including deleted, got %+v", out)
+	}
+
+	if err := s.Restore(ctx, "s1"); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if p, err := s.Get(ctx, "s1"); err != nil || p.IsDeleted() {
+		t.Fatalf("expected restored product, got %+v, %v", p, err)
+	}
+
+	_ = s.BatchDelete(ctx, []string{"s1", "s2"})
+	n, err := s.Purge(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 purged, got %d, %v", n, err)
+	}
+	if err := s.Restore(ctx, "s1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected purged product to be gone, got %v", err)
+	}
+}
+
+func TestBulkImport_RateLimit(t *testing.T) {
+	s := NewInMemoryStore()
+	products := make([]domain.Product, 0, 6)
+	for i := 0; i < 6; i++ {
+		products = append(products, domain.Product{ID: "r-" + strconv.Itoa(i), Name: "X", Price: 1, Quantity: 1})
+	}
+	start := time.Now()
+	if err := s.BulkImportWithOptions(context.Background(), products, domain.BulkImportOptions{RateLimit: 50}); err != nil {
+		t.Fatalf("BulkImport failed: %v", err)
+	}
+	// the first create is free, the other five wait 20ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("6 creates at 50/s took %v, want about 100ms", elapsed)
+	}
+	if out, _ := s.List(context.Background(), domain.ListFilter{}); len(out) != 6 {
+		t.Fatalf("expected 6 products, got %d", len(out))
+	}
+}
+
+func TestInMemoryStore_SnapshotAndRestore(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryStoreWithOptions(Options{SoftDelete: true, PriceIndex: true})
+	_ = s.Create(ctx, domain.Product{ID: "s2", Name: "B", Price: 5, Category: "Office", Tags: []string{"x"}})
+	_ = s.Create(ctx, domain.Product{ID: "s1", Name: "A", Price: 1, Category: "Office"})
+	_ = s.Delete(ctx, "s1")
+
+	snap := s.Snapshot()
+	if len(snap) != 2 || snap[0].ID != "s1" || !snap[0].IsDeleted() {
+		t.Fatalf("expected both products sorted by id, got %+v", snap)
+	}
+	snap[1].Tags[0] = "changed"
+	if p, _ := s.Get(ctx, "s2"); p.Tags[0] != "x" {
+		t.Fatal("snapshot must not share memory with the store")
+	}
+	snap[1].Tags[0] = "x"
+
+	// what-if changes, then back to the snapshot
+	_ = s.Update(ctx, "s2", domain.Product{Name: "B", Price: 50, Category: "Toys"})
+	_ = s.Create(ctx, domain.Product{ID: "s3", Name: "C", Price: 1})
+	s.RestoreSnapshot(snap)
+
+	if got := s.Snapshot(); !slices.EqualFunc(got, snap, func(a, b domain.Product) bool { return a.ID == b.ID && a.Price == b.Price && a.Category == b.Category }) {
+		t.Fatalf("restore did not bring the snapshot back: %+v", got)
+	}
+	// the indexes follow the restored content
+	minPrice, maxPrice := domain.Money(4), domain.Money(6)
+	out, _ := s.List(ctx, domain.ListFilter{Categories: []string{"Office"}, MinPrice: &minPrice, MaxPrice: &maxPrice})
+	if len(out) != 1 || out[0].ID != "s2" {
+		t.Fatalf("expected s2 through the indexes, got %+v", out)
+	}
+	if out, _ := s.List(ctx, domain.ListFilter{Category: "Toys"}); len(out) != 0 {
+		t.Fatalf("expected the what-if category gone, got %+v", out)
+	}
+	if err := s.Restore(ctx, "s1"); err != nil {
+		t.Fatalf("expected the soft-deleted product restorable, got %v", err)
+	}
+}
+
+func TestInMemoryStore_CloneIsIndependent(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryStoreWithOptions(Options{TrackPriceHistory: true})
+	_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Attributes: map[string]string{"k": "v"}})
+	_ = s.Update(ctx, "c1", domain.Product{Name: "A", Price: 2, Attributes: map[string]string{"k": "v"}})
+
+	c := s.Clone()
+	_ = c.Update(ctx, "c1", domain.Product{Name: "A", Price: 3, Attributes: map[string]string{"k": "changed"}})
+	_ = c.Create(ctx, domain.Product{ID: "c2", Name: "B", Price: 1})
+
+	if p, _ := s.Get(ctx, "c1"); p.Price != 2 || p.Attributes["k"] != "v" {
+		t.Fatalf("clone changes leaked into the original: %+v", p)
+	}
+	if _, err := s.Get(ctx, "c2"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected c2 only in the clone, got %v", err)
+	}
+	if h, _ := s.PriceHistory(ctx, "c1"); len(h) != 1 {
+		t.Fatalf("expected the original to keep one price change, got %d", len(h))
+	}
+	if h, _ := c.PriceHistory(ctx, "c1"); len(h) != 2 {
+		t.Fatalf("expected the clone to have the copied change and its own, got %d", len(h))
+	}
+}
+
+func TestInMemoryStore_SnapshotConcurrentWithWrites(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryStore()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			_ = s.Create(ctx, domain.Product{ID: "w-" + strconv.Itoa(i), Name: "W", Price: 1})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_ = s.Snapshot()
+			_ = s.Clone()
+		}
+	}()
+	wg.Wait()
+	if n := len(s.Snapshot()); n != 200 {
+		t.Fatalf("expected 200 products, got %d", n)
+	}
+}
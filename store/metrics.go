@@ -0,0 +1,119 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the histogram upper bounds in seconds, matching the
+// Prometheus client defaults.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// opStats accumulates the counters and latency histogram of one operation
+type opStats struct {
+	ok, failed uint64
+	buckets    []uint64 // cumulative counts per latencyBuckets entry
+	sum        float64
+}
+
+// MetricsRegistry collects per-operation counts and latencies recorded by
+// InstrumentedStore and renders them in the Prometheus text exposition
+// format, so it can be scraped without the Prometheus client library.
+type MetricsRegistry struct {
+	mu  sync.Mutex
+	ops map[string]*opStats
+}
+
+// NewMetricsRegistry returns an empty registry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{ops: make(map[string]*opStats)}
+}
+
+// DefaultMetrics is the registry used by NewInstrumentedStore
+var DefaultMetrics = NewMetricsRegistry()
+
+// observe records one call of op that took d and failed when err != nil
+func (r *MetricsRegistry) observe(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.ops[op]
+	if !ok {
+		st = &opStats{buckets: make([]uint64, len(latencyBuckets))}
+		r.ops[op] = st
+	}
+	if err != nil {
+		st.failed++
+	} else {
+		st.ok++
+	}
+	secs := d.Seconds()
+	st.sum += secs
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			st.buckets[i]++
+		}
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text format
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make([]string, 0, len(r.ops))
+	for op := range r.ops {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	cw := &countingWriter{w: w}
+	fmt.Fprintln(cw, "# HELP inventory_store_operations_total Store operations by result.")
+	fmt.Fprintln(cw, "# TYPE inventory_store_operations_total counter")
+	for _, op := range ops {
+		st := r.ops[op]
+		fmt.Fprintf(cw, "inventory_store_operations_total{op=%q,result=\"ok\"} %d\n", op, st.ok)
+		fmt.Fprintf(cw, "inventory_store_operations_total{op=%q,result=\"error\"} %d\n", op, st.failed)
+	}
+	fmt.Fprintln(cw, "# HELP inventory_store_operation_duration_seconds Store operation latency.")
+	fmt.Fprintln(cw, "# TYPE inventory_store_operation_duration_seconds histogram")
+	for _, op := range ops {
+		st := r.ops[op]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(cw, "inventory_store_operation_duration_seconds_bucket{op=%q,le=\"%g\"} %d\n", op, le, st.buckets[i])
+		}
+		total := st.ok + st.failed
+		fmt.Fprintf(cw, "inventory_store_operation_duration_seconds_bucket{op=%q,le=\"+Inf\"} %d\n", op, total)
+		fmt.Fprintf(cw, "inventory_store_operation_duration_seconds_sum{op=%q} %g\n", op, st.sum)
+		fmt.Fprintf(cw, "inventory_store_operation_duration_seconds_count{op=%q} %d\n", op, total)
+	}
+	return cw.n, cw.err
+}
+
+// Handler serves the registry at a /metrics endpoint
+func (r *MetricsRegistry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// countingWriter tracks bytes written and the first error for WriteTo
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
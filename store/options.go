@@ -0,0 +1,78 @@
+package store
+
+import (
+	"os"
+	"time"
+)
+
+// Default permissions of the FileStore data file and of directories it creates
+const (
+	DefaultFileMode os.FileMode = 0o644
+	DefaultDirMode  os.FileMode = 0o755
+)
+
+// Options tune behaviour shared by the backends
+type Options struct {
+	// SoftDelete makes Delete and BatchDelete mark products as deleted
+	// instead of removing them. Marked products are hidden from Get, List
+	// and Update until Restore brings them back or Purge removes them.
+	SoftDelete bool
+	// SaveDelay, when positive, makes FileStore coalesce writes: a mutation
+	// only marks the file dirty and it is rewritten once SaveDelay after the
+	// first pending change, or on Close. Write errors then surface from Close
+	// rather than from the mutation. Zero saves on every mutation.
+	SaveDelay time.Duration
+	// RecoverCorrupt makes FileStore start from the temp file of an
+	// interrupted save, or empty, when its file cannot be parsed; the bad
+	// file is kept as path.corrupt. By default a corrupt file is an error.
+	RecoverCorrupt bool
+	// Durable makes FileStore fsync the new file before renaming it into
+	// place and the directory after, so a completed save survives a power
+	// loss. It costs two fsyncs per save.
+	Durable bool
+	// FileMode and DirMode are the permissions FileStore creates its data
+	// file and missing parent directories with, subject to the umask. Zero
+	// means DefaultFileMode and DefaultDirMode.
+	FileMode os.FileMode
+	DirMode  os.FileMode
+	// PriceIndex makes InMemoryStore keep its products sorted by price so
+	// MinPrice and MaxPrice filters binary-search their range instead of
+	// scanning every product. Each write then costs O(n) to keep the order.
+	PriceIndex bool
+	// Journal makes FileStore append each changed product to
+	// path.journal instead of rewriting the whole file on every mutation.
+	// After CompactEvery records (zero means DefaultCompactEvery) the file is
+	// rewritten and the journal removed; Clear always rewrites it. Loading
+	// replays any journal left behind. SaveDelay is ignored with a journal.
+	Journal      bool
+	CompactEvery int
+	// TrackPriceHistory makes the stores record every price change made
+	// by Update or UpdateWhere, for PriceHistory. FileStore keeps the
+	// history in path.prices.json; with Journal, changes are appended to
+	// the journal and written to that file when it is compacted.
+	TrackPriceHistory bool
+	// EncryptionKey, when set, makes FileStore encrypt its file and price
+	// history with AES-GCM under this 16, 24 or 32 byte key, with a fresh
+	// random nonce per write. A plaintext file is still read and is encrypted
+	// on the next save; an encrypted file fails to load with ErrWrongKey
+	// under any other key. It cannot be combined with Journal.
+	EncryptionKey []byte
+	// WatchFile makes FileStore reload its file when another process changes
+	// it, until Close. Local changes still waiting for a SaveDelay flush win
+	// over such a change, and a warning is logged.
+	WatchFile bool
+}
+
+func (o Options) fileMode() os.FileMode {
+	if o.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return o.FileMode
+}
+
+func (o Options) dirMode() os.FileMode {
+	if o.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return o.DirMode
+}
@@ -0,0 +1,513 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBulkImport_BackendParity feeds the same batch to both backends and
+// expects the same products stored and the same errors reported.
+func TestBulkImport_BackendParity(t *testing.T) {
+	path := "testdata/parity_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStore(), "file": fs}
+
+	batch := []domain.Product{
+		{ID: "p1", Name: "Ok", Price: 100, Quantity: 1},
+		{ID: "", Name: "NoID", Price: 100, Quantity: 1},
+		{ID: "p2", Name: "", Price: 100, Quantity: 1},
+		{ID: "p3", Name: "Neg", Price: -1, Quantity: 1},
+		{ID: "p4", Name: "Neg", Price: 1, Quantity: -1},
+		{ID: "p5", Name: "Bad", Price: 1, Quantity: 1, Currency: "XXX"},
+		{ID: "p6", Name: "Ok too", Price: 1, Quantity: 1, ReorderLevel: 2},
+		{ID: "p7", Name: "Existing", Price: 1, Quantity: 1},
+	}
+
+	type outcome struct {
+		ids  []string
+		errs []string
+	}
+	results := map[string]outcome{}
+	for name, s := range stores {
+		ctx := context.Background()
+		if err := s.Create(ctx, domain.Product{ID: "p7", Name: "Existing", Price: 1, Quantity: 1}); err != nil {
+			t.Fatalf("%s: seed failed: %v", name, err)
+		}
+		var me *domain.MultiError
+		if err := s.BulkImport(ctx, batch); !errors.As(err, &me) {
+			t.Fatalf("%s: expected *domain.MultiError, got %v", name, err)
+		}
+		var o outcome
+		for _, e := range me.Errors() {
+			o.errs = append(o.errs, e.Error())
+		}
+		slices.Sort(o.errs)
+		list, _ := s.List(ctx, domain.ListFilter{})
+		for _, p := range list {
+			o.ids = append(o.ids, p.ID)
+		}
+		slices.Sort(o.ids)
+		results[name] = o
+	}
+
+	mem, file := results["memory"], results["file"]
+	if !slices.Equal(mem.ids, file.ids) {
+		t.Fatalf("stored products differ: memory=%v file=%v", mem.ids, file.ids)
+	}
+	if !slices.Equal(mem.errs, file.errs) {
+		t.Fatalf("errors differ:\nmemory=%q\nfile=%q", mem.errs, file.errs)
+	}
+	if len(mem.errs) != 6 {
+		t.Fatalf("expected 6 errors, got %q", mem.errs)
+	}
+}
+
+func TestCategories_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/categories.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx := context.Background()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "c2", Name: "B", Price: 1, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "c3", Name: "C", Price: 1, Quantity: 1, Category: "Books"})
+		_ = s.Create(ctx, domain.Product{ID: "c4", Name: "D", Price: 1, Quantity: 1})
+		_ = s.Create(ctx, domain.Product{ID: "c5", Name: "E", Price: 1, Quantity: 1, Category: "Garden"})
+		_ = s.Delete(ctx, "c5")
+
+		got, err := s.Categories(ctx)
+		if err != nil {
+			t.Fatalf("%s: categories failed: %v", name, err)
+		}
+		want := map[string]int{"Office": 2, "Books": 1, "": 1}
+		if !maps.Equal(got, want) {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestRenameCategory_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/rename.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx := context.Background()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "r1", Name: "A", Price: 1, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "r2", Name: "B", Price: 1, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "r3", Name: "C", Price: 1, Quantity: 1, Category: "Books"})
+
+		n, err := s.RenameCategory(ctx, "Office", "Stationery")
+		if err != nil || n != 2 {
+			t.Fatalf("%s: expected 2 renamed, got %d (%v)", name, n, err)
+		}
+		got, _ := s.Categories(ctx)
+		want := map[string]int{"Stationery": 2, "Books": 1}
+		if !maps.Equal(got, want) {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+		if n, err := s.RenameCategory(ctx, "Garden", "Outdoor"); err != nil || n != 0 {
+			t.Fatalf("%s: expected 0 renamed for unknown category, got %d (%v)", name, n, err)
+		}
+		if _, err := s.RenameCategory(ctx, "", "Books"); err == nil {
+			t.Fatalf("%s: expected error for empty source category", name)
+		}
+
+		domain.Validation.AllowedCategories = []string{"Books", "Stationery"}
+		_, err = s.RenameCategory(ctx, "Books", "Comics")
+		domain.Validation.AllowedCategories = nil
+		if !domain.IsInvalidProductError(err) {
+			t.Fatalf("%s: expected invalid product error for category outside whitelist, got %v", name, err)
+		}
+	}
+}
+
+func TestAdjustPriceWhere_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/adjust.json", Options{TrackPriceHistory: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{TrackPriceHistory: true}), "file": fs}
+	ctx := context.Background()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "a1", Name: "A", Price: 1999, Quantity: 1, Category: "Sale"})
+		_ = s.Create(ctx, domain.Product{ID: "a2", Name: "B", Price: 125, Quantity: 1, Category: "Sale"})
+		_ = s.Create(ctx, domain.Product{ID: "a3", Name: "C", Price: 1000, Quantity: 1, Category: "Books"})
+
+		n, err := s.AdjustPriceWhere(ctx, domain.ListFilter{Category: "Sale"}, -10)
+		if err != nil || n != 2 {
+			t.Fatalf("%s: expected 2 adjusted, got %d (%v)", name, n, err)
+		}
+		for id, want := range map[string]domain.Money{"a1": 1799, "a2": 112, "a3": 1000} {
+			if p, _ := s.Get(ctx, id); p.Price != want {
+				t.Fatalf("%s: %s: expected price %d, got %d", name, id, want, p.Price)
+			}
+		}
+		if changes, _ := s.PriceHistory(ctx, "a1"); len(changes) != 1 || changes[0].OldPrice != 1999 {
+			t.Fatalf("%s: expected the change in the price history, got %+v", name, changes)
+		}
+
+		for _, pct := range []float64{-100.5, math.NaN(), math.Inf(1)} {
+			if _, err := s.AdjustPriceWhere(ctx, domain.ListFilter{}, pct); !domain.IsInvalidProductError(err) {
+				t.Fatalf("%s: expected %v%% to be rejected, got %v", name, pct, err)
+			}
+		}
+		if p, _ := s.Get(ctx, "a3"); p.Price != 1000 {
+			t.Fatalf("%s: expected a rejected adjustment to change nothing, got %d", name, p.Price)
+		}
+	}
+}
+
+func TestListLimitOffset_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/page.json", Options{})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	for _, s := range []domain.ProductStore{NewInMemoryStore(), fs} {
+		ctx := context.Background()
+		for i := 0; i < 100; i++ {
+			_ = s.Create(ctx, domain.Product{ID: "l" + strconv.Itoa(i), Name: "L", Price: domain.Money(1000 - i), Quantity: 1})
+		}
+
+		// a small page of a large result takes the heap path
+		out, err := s.List(ctx, domain.ListFilter{SortBy: "price", Offset: 3, Limit: 4})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		var ids []string
+		for _, p := range out {
+			ids = append(ids, p.ID)
+		}
+		if got := strings.Join(ids, ","); got != "l96,l95,l94,l93" {
+			t.Fatalf("unexpected page %q", got)
+		}
+
+		// a page covering most of the result sorts it all
+		if out, _ := s.List(ctx, domain.ListFilter{SortBy: "price", Order: "desc", Offset: 90, Limit: 50}); len(out) != 10 || out[0].ID != "l90" {
+			t.Fatalf("unexpected last page %v", out)
+		}
+
+		// without sort keys pages follow the ids, so they never overlap
+		seen := make(map[string]bool)
+		for offset := 0; offset < 100; offset += 7 {
+			page, _ := s.List(ctx, domain.ListFilter{Offset: offset, Limit: 7})
+			for i, p := range page {
+				if seen[p.ID] || (i > 0 && page[i-1].ID >= p.ID) {
+					t.Fatalf("page at offset %d is not the next run of ids: %v", offset, page)
+				}
+				seen[p.ID] = true
+			}
+		}
+		if len(seen) != 100 {
+			t.Fatalf("expected pages to cover all 100 products, got %d", len(seen))
+		}
+
+		// ties break on id on both the heap and the full-sort path
+		_, _ = s.UpdateWhere(ctx, domain.ListFilter{}, domain.ProductPatch{Price: moneyPtr(5)})
+		heapPage, _ := s.List(ctx, domain.ListFilter{SortBy: "price", Limit: 4})
+		sortPage, _ := s.List(ctx, domain.ListFilter{SortBy: "price", Limit: 50})
+		for i, want := range []string{"l0", "l1", "l10", "l11"} {
+			if heapPage[i].ID != want || sortPage[i].ID != want {
+				t.Fatalf("expected tied products ordered by id, got %v and %v", heapPage, sortPage[:4])
+			}
+		}
+		if out, _ := s.List(ctx, domain.ListFilter{Offset: 200}); out == nil || len(out) != 0 {
+			t.Fatalf("expected an empty page past the end, got %v", out)
+		}
+	}
+}
+
+// cancelAfterCtx reports cancellation once Err has been asked n times, so a
+// test can cancel in the middle of a scan
+type cancelAfterCtx struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestListStopsWhenCanceledMidScan_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/cancel.json", Options{})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	batch := make([]domain.Product, 20_000)
+	for i := range batch {
+		batch[i] = domain.Product{ID: "x" + strconv.Itoa(i), Name: "X", Price: 1, Quantity: 1}
+	}
+	for name, s := range map[string]domain.ProductStore{"memory": NewInMemoryStore(), "file": fs} {
+		if err := s.BulkImport(context.Background(), batch); err != nil {
+			t.Fatalf("%s: import failed: %v", name, err)
+		}
+		// three checks pass and the next cancels, long before the scan ends
+		ctx := &cancelAfterCtx{Context: context.Background(), n: 3}
+		out, err := s.List(ctx, domain.ListFilter{})
+		if !errors.Is(err, context.Canceled) || out != nil {
+			t.Fatalf("%s: expected cancellation, got %d products and %v", name, len(out), err)
+		}
+		if ctx.n >= 0 {
+			t.Fatalf("%s: expected the scan to reach the cancelling check", name)
+		}
+	}
+}
+
+func TestPriceHistory_BackendParity(t *testing.T) {
+	path := t.TempDir() + "/history.json"
+	opts := Options{TrackPriceHistory: true}
+	fs, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	for name, s := range map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(opts), "file": fs} {
+		_ = s.Create(ctx, domain.Product{ID: "h1", Name: "A", Price: 100, Quantity: 1, Category: "Office"})
+		_ = s.Update(ctx, "h1", domain.Product{Name: "A", Price: 150, Quantity: 1, Category: "Office"})
+		_ = s.Update(ctx, "h1", domain.Product{Name: "Renamed", Price: 150, Quantity: 1, Category: "Office"})
+		sale := domain.Money(120)
+		_, _ = s.UpdateWhere(ctx, domain.ListFilter{Category: "Office"}, domain.ProductPatch{Price: &sale})
+
+		got, err := s.PriceHistory(ctx, "h1")
+		if err != nil {
+			t.Fatalf("%s: price history failed: %v", name, err)
+		}
+		if len(got) != 2 || got[0].OldPrice != 100 || got[0].NewPrice != 150 || got[1].NewPrice != 120 || got[0].At.IsZero() {
+			t.Fatalf("%s: unexpected history %+v", name, got)
+		}
+		if _, err := s.PriceHistory(ctx, "nope"); !domain.IsProductNotFoundError(err) {
+			t.Fatalf("%s: expected not found, got %v", name, err)
+		}
+	}
+
+	reopened, err := NewFileStoreWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if got, _ := reopened.PriceHistory(ctx, "h1"); len(got) != 2 {
+		t.Fatalf("expected history to survive a reopen, got %+v", got)
+	}
+
+	untracked := NewInMemoryStore()
+	_ = untracked.Create(ctx, domain.Product{ID: "u1", Name: "A", Price: 1, Quantity: 1})
+	_ = untracked.Update(ctx, "u1", domain.Product{Name: "A", Price: 2, Quantity: 1})
+	if got, err := untracked.PriceHistory(ctx, "u1"); err != nil || len(got) != 0 {
+		t.Fatalf("expected no history without tracking, got %+v (%v)", got, err)
+	}
+}
+
+func TestStats_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/stats.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx := context.Background()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "s1", Name: "A", Price: 250, Quantity: 4, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "s2", Name: "B", Price: 1000, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "s3", Name: "C", Price: 500, Quantity: 3, Category: "Books"})
+		_ = s.Create(ctx, domain.Product{ID: "s4", Name: "D", Price: 9999, Quantity: 9, Category: "Office"})
+		_ = s.Delete(ctx, "s4")
+
+		all, err := s.Stats(ctx, domain.ListFilter{Limit: 1})
+		if err != nil {
+			t.Fatalf("%s: stats failed: %v", name, err)
+		}
+		if want := (domain.InventoryStats{Count: 3, Quantity: 8, Value: 3500}); all != want {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, all)
+		}
+		office, _ := s.Stats(ctx, domain.ListFilter{Category: "Office"})
+		if want := (domain.InventoryStats{Count: 2, Quantity: 5, Value: 2000}); office != want {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, office)
+		}
+	}
+}
+
+func TestFindDuplicates_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/dupes.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx := context.Background()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "d2", Name: "Stapler", Price: 1, Quantity: 1, Category: "Office"})
+		_ = s.Create(ctx, domain.Product{ID: "d1", Name: "stapler ", Price: 2, Quantity: 1, Category: "office"})
+		_ = s.Create(ctx, domain.Product{ID: "d3", Name: "Stapler", Price: 1, Quantity: 1, Category: "Garden"})
+		_ = s.Create(ctx, domain.Product{ID: "d4", Name: "Atlas", Price: 1, Quantity: 1})
+		_ = s.Create(ctx, domain.Product{ID: "d5", Name: "Atlas", Price: 1, Quantity: 1})
+		_ = s.Create(ctx, domain.Product{ID: "d6", Name: "Atlas", Price: 1, Quantity: 1})
+		_ = s.Delete(ctx, "d6")
+		_ = s.Create(ctx, domain.Product{ID: "d7", Name: "Lamp", Price: 1, Quantity: 1, Category: "Home"})
+		_ = s.Create(ctx, domain.Product{ID: "d8", Name: "Lamp", Price: 1, Quantity: 1, Category: "Home"})
+		_ = s.Delete(ctx, "d8")
+
+		got, err := s.FindDuplicates(ctx)
+		if err != nil {
+			t.Fatalf("%s: find duplicates failed: %v", name, err)
+		}
+		want := []domain.DuplicateGroup{
+			{Name: "Atlas", Category: "", IDs: []string{"d4", "d5"}},
+			{Name: "stapler ", Category: "office", IDs: []string{"d1", "d2"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+}
+
+func TestUpdatedAfter_BackendParity(t *testing.T) {
+	path := t.TempDir() + "/since.json"
+	fs, err := NewFileStoreWithOptions(path, Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx := context.Background()
+	ids := func(ps []domain.Product) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.ID)
+		}
+		slices.Sort(out)
+		return out
+	}
+	for name, s := range stores {
+		longAgo := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+		for id, category := range map[string]string{"a": "Old", "b": "Old", "c": "Old", "d": "Other"} {
+			// a caller's UpdatedAt is overwritten
+			_ = s.Create(ctx, domain.Product{ID: id, Name: id, Price: 1, Quantity: 1, Category: category, UpdatedAt: &longAgo})
+		}
+		time.Sleep(2 * time.Millisecond)
+		since := time.Now().UTC()
+
+		b, _ := s.Get(ctx, "b")
+		if b.UpdatedAt == nil || !b.UpdatedAt.Before(since) || b.UpdatedAt.Before(longAgo.AddDate(1, 0, 0)) {
+			t.Fatalf("%s: expected Create to stamp the current time, got %v", name, b.UpdatedAt)
+		}
+		b.Quantity = 2
+		_ = s.Update(ctx, "b", b)
+		_ = s.Delete(ctx, "c")
+		_, _ = s.RenameCategory(ctx, "Other", "New") // through UpdateWhere
+		_ = s.Create(ctx, domain.Product{ID: "e", Name: "e", Price: 1, Quantity: 1})
+
+		got, err := s.List(ctx, domain.ListFilter{UpdatedAfter: &since})
+		if err != nil {
+			t.Fatalf("%s: list failed: %v", name, err)
+		}
+		if want := []string{"b", "d", "e"}; !slices.Equal(ids(got), want) {
+			t.Fatalf("%s: expected %v, got %v", name, want, ids(got))
+		}
+		got, _ = s.List(ctx, domain.ListFilter{UpdatedAfter: &since, IncludeDeleted: true})
+		if want := []string{"b", "c", "d", "e"}; !slices.Equal(ids(got), want) {
+			t.Fatalf("%s: expected the soft-deleted product too, got %v", name, ids(got))
+		}
+	}
+
+	// the stamps survive a reload
+	reopened, err := NewFileStoreWithOptions(path, Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	since := time.Now().UTC().Add(-time.Hour)
+	got, _ := reopened.List(ctx, domain.ListFilter{UpdatedAfter: &since})
+	if len(got) != 4 {
+		t.Fatalf("expected every live product after reopening, got %v", ids(got))
+	}
+}
+
+// nextEvents reads n events from ch, failing t if they are slow to come
+func nextEvents(t *testing.T, ch <-chan domain.ChangeEvent, n int) []domain.ChangeEvent {
+	t.Helper()
+	out := make([]domain.ChangeEvent, 0, n)
+	for len(out) < n {
+		select {
+		case ev := <-ch:
+			out = append(out, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d of %d events: %+v", len(out), n, out)
+		}
+	}
+	return out
+}
+
+func TestClearPublishesDeleted_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/clear.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for name, s := range stores {
+		_ = s.Create(ctx, domain.Product{ID: "c2", Name: "B", Price: 1, Quantity: 1})
+		_ = s.Create(ctx, domain.Product{ID: "c1", Name: "A", Price: 1, Quantity: 1, Tags: []string{"x"}})
+		_ = s.Delete(ctx, "c2")
+		events, err := s.Watch(ctx)
+		if err != nil {
+			t.Fatalf("%s: watch failed: %v", name, err)
+		}
+
+		if n, err := s.Clear(ctx); err != nil || n != 2 {
+			t.Fatalf("%s: expected 2 cleared, got %d, %v", name, n, err)
+		}
+		got := nextEvents(t, events, 2)
+		if got[0].Op != domain.ChangeDeleted || got[0].ID != "c1" || got[1].Op != domain.ChangeDeleted || got[1].ID != "c2" {
+			t.Fatalf("%s: expected deleted events for c1 and c2, got %+v", name, got)
+		}
+		if got[0].Product.Name != "A" || len(got[0].Product.Tags) != 1 {
+			t.Fatalf("%s: expected the removed product in the event, got %+v", name, got[0].Product)
+		}
+	}
+}
+
+func TestPurgePublishesDeleted_BackendParity(t *testing.T) {
+	fs, err := NewFileStoreWithOptions(t.TempDir()+"/purge.json", Options{SoftDelete: true})
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	stores := map[string]domain.ProductStore{"memory": NewInMemoryStoreWithOptions(Options{SoftDelete: true}), "file": fs}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for name, s := range stores {
+		for _, id := range []string{"p1", "p2", "p3"} {
+			_ = s.Create(ctx, domain.Product{ID: id, Name: id, Price: 1, Quantity: 1})
+		}
+		_ = s.Delete(ctx, "p3")
+		_ = s.Delete(ctx, "p1")
+		events, err := s.Watch(ctx)
+		if err != nil {
+			t.Fatalf("%s: watch failed: %v", name, err)
+		}
+
+		if n, err := s.Purge(ctx); err != nil || n != 2 {
+			t.Fatalf("%s: expected 2 purged, got %d, %v", name, n, err)
+		}
+		got := nextEvents(t, events, 2)
+		if got[0].Op != domain.ChangeDeleted || got[0].ID != "p1" || got[1].Op != domain.ChangeDeleted || got[1].ID != "p3" {
+			t.Fatalf("%s: expected deleted events for p1 and p3, got %+v", name, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"crypto/cipher"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+)
+
+// priceHistory holds the recorded price changes per product id, oldest first
+type priceHistory map[string][]domain.PriceChange
+
+// record appends a change when updated is priced differently from old and
+// reports whether it did
+func (h priceHistory) record(old, updated domain.Product) bool {
+	if old.Price == updated.Price {
+		return false
+	}
+	h[updated.ID] = append(h[updated.ID], domain.PriceChange{
+		At:       time.Now().UTC(),
+		OldPrice: old.Price,
+		NewPrice: updated.Price,
+	})
+	return true
+}
+
+// of returns a copy of the changes recorded for id
+func (h priceHistory) of(id string) []domain.PriceChange {
+	return slices.Clone(h[id])
+}
+
+// replay appends a change read back from the journal unless id already has
+// it, as it does when a compaction saved the history but did not get to
+// remove the journal
+func (h priceHistory) replay(id string, c domain.PriceChange) {
+	for _, have := range h[id] {
+		if have.At.Equal(c.At) && have.OldPrice == c.OldPrice && have.NewPrice == c.NewPrice {
+			return
+		}
+	}
+	h[id] = append(h[id], c)
+}
+
+// readPriceHistory loads a history saved by FileStore.saveHistory, decrypting
+// it with aead if it was encrypted, and returns it with the file's content;
+// a missing file holds none
+func readPriceHistory(path string, aead cipher.AEAD) (priceHistory, []byte, error) {
+	h := make(priceHistory)
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := unseal(aead, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, nil, err
+	}
+	return h, raw, nil
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"sync"
+)
+
+// progressTicker returns a function to call once per processed item. It
+// forwards a monotonically increasing count to the ProgressFunc carried by
+// ctx, serialising calls so the callback need not be concurrency-safe.
+func progressTicker(ctx context.Context, total int) func() {
+	fn := domain.ProgressFromContext(ctx)
+	if fn == nil {
+		return func() {}
+	}
+	var mu sync.Mutex
+	done := 0
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		fn(done, total)
+	}
+}
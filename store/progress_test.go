@@ -0,0 +1,42 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestBulkImportReportsProgress(t *testing.T) {
+	path := "testdata/progress_test.json"
+	_ = os.Remove(path)
+	defer os.Remove(path)
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	products := make([]domain.Product, 50)
+	for i := range products {
+		products[i] = domain.Product{ID: fmt.Sprintf("p%d", i), Name: "P", Price: 1, Quantity: 1}
+	}
+	products[7].Name = "" // failures count as processed too
+
+	for name, s := range map[string]domain.ProductStore{"memory": NewInMemoryStore(), "file": fs} {
+		t.Run(name, func(t *testing.T) {
+			var calls, last int
+			ctx := domain.WithProgress(context.Background(), func(done, total int) {
+				calls++
+				if done != last+1 || total != len(products) {
+					t.Errorf("unexpected progress %d/%d after %d", done, total, last)
+				}
+				last = done
+			})
+			_ = s.BulkImport(ctx, products)
+			if calls != len(products) || last != len(products) {
+				t.Fatalf("expected %d progress calls ending at total, got %d ending at %d", len(products), calls, last)
+			}
+		})
+	}
+}
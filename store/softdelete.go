@@ -0,0 +1,23 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"time"
+)
+
+// markDeleted returns p stamped with the current time as its deletion and
+// update time
+func markDeleted(p domain.Product) domain.Product {
+	now := time.Now().UTC()
+	p.DeletedAt = &now
+	p.UpdatedAt = &now
+	return p
+}
+
+// markUpdated returns p stamped with the current time as its update time.
+// Every store write goes through it or markDeleted.
+func markUpdated(p domain.Product) domain.Product {
+	now := time.Now().UTC()
+	p.UpdatedAt = &now
+	return p
+}
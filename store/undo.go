@@ -0,0 +1,217 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNothingToUndo is returned by UndoStore.Undo when there is no single
+// create, update or delete to revert.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// UndoStore wraps any domain.ProductStore and remembers the most recent
+// Create, Update or Delete so Undo can revert it. Only that one operation is
+// kept, in memory: any later mutation replaces it, and bulk mutations
+// (UpdateWhere, BatchDelete, BulkImport, RenameCategory, AdjustPriceWhere),
+// Restore, Purge and Clear drop it.
+type UndoStore struct {
+	inner domain.ProductStore
+
+	mu   sync.Mutex
+	last *undoEntry
+}
+
+// undoEntry records one reversible operation and the product before it
+type undoEntry struct {
+	op     domain.ChangeOp
+	id     string
+	before domain.Product
+}
+
+// compile-time assertion
+var (
+	_ domain.ProductStore = (*UndoStore)(nil)
+	_ domain.StoreCloser  = (*UndoStore)(nil)
+)
+
+// NewUndoStore wraps inner with a one-step undo
+func NewUndoStore(inner domain.ProductStore) *UndoStore {
+	return &UndoStore{inner: inner}
+}
+
+func (s *UndoStore) remember(e *undoEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = e
+}
+
+func (s *UndoStore) Create(ctx context.Context, product domain.Product) error {
+	if err := s.inner.Create(ctx, product); err != nil {
+		return err
+	}
+	s.remember(&undoEntry{op: domain.ChangeCreated, id: product.ID})
+	return nil
+}
+
+func (s *UndoStore) Update(ctx context.Context, id string, product domain.Product) error {
+	before, err := s.inner.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.Update(ctx, id, product); err != nil {
+		return err
+	}
+	s.remember(&undoEntry{op: domain.ChangeUpdated, id: id, before: before})
+	return nil
+}
+
+func (s *UndoStore) Delete(ctx context.Context, id string) error {
+	before, err := s.inner.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.remember(&undoEntry{op: domain.ChangeDeleted, id: id, before: before})
+	return nil
+}
+
+func (s *UndoStore) UpdateWhere(ctx context.Context, filter domain.ListFilter, patch domain.ProductPatch) ([]domain.Product, error) {
+	out, err := s.inner.UpdateWhere(ctx, filter, patch)
+	s.remember(nil)
+	return out, err
+}
+
+func (s *UndoStore) BatchDelete(ctx context.Context, ids []string) error {
+	err := s.inner.BatchDelete(ctx, ids)
+	s.remember(nil)
+	return err
+}
+
+func (s *UndoStore) BulkImport(ctx context.Context, products []domain.Product) error {
+	err := s.inner.BulkImport(ctx, products)
+	s.remember(nil)
+	return err
+}
+
+func (s *UndoStore) BulkImportWithOptions(ctx context.Context, products []domain.Product, opts domain.BulkImportOptions) error {
+	err := s.inner.BulkImportWithOptions(ctx, products, opts)
+	s.remember(nil)
+	return err
+}
+
+func (s *UndoStore) Restore(ctx context.Context, id string) error {
+	err := s.inner.Restore(ctx, id)
+	s.remember(nil)
+	return err
+}
+
+func (s *UndoStore) Purge(ctx context.Context) (int, error) {
+	n, err := s.inner.Purge(ctx)
+	s.remember(nil)
+	return n, err
+}
+
+func (s *UndoStore) Clear(ctx context.Context) (int, error) {
+	n, err := s.inner.Clear(ctx)
+	s.remember(nil)
+	return n, err
+}
+
+func (s *UndoStore) Get(ctx context.Context, id string) (domain.Product, error) {
+	return s.inner.Get(ctx, id)
+}
+
+func (s *UndoStore) List(ctx context.Context, filter domain.ListFilter) ([]domain.Product, error) {
+	return s.inner.List(ctx, filter)
+}
+
+func (s *UndoStore) RenameCategory(ctx context.Context, from, to string) (int, error) {
+	n, err := s.inner.RenameCategory(ctx, from, to)
+	s.remember(nil)
+	return n, err
+}
+
+func (s *UndoStore) AdjustPriceWhere(ctx context.Context, filter domain.ListFilter, pct float64) (int, error) {
+	n, err := s.inner.AdjustPriceWhere(ctx, filter, pct)
+	s.remember(nil)
+	return n, err
+}
+
+func (s *UndoStore) Categories(ctx context.Context) (map[string]int, error) {
+	return s.inner.Categories(ctx)
+}
+
+func (s *UndoStore) PriceHistory(ctx context.Context, id string) ([]domain.PriceChange, error) {
+	return s.inner.PriceHistory(ctx, id)
+}
+
+func (s *UndoStore) Stats(ctx context.Context, filter domain.ListFilter) (domain.InventoryStats, error) {
+	return s.inner.Stats(ctx, filter)
+}
+
+func (s *UndoStore) FindDuplicates(ctx context.Context) ([]domain.DuplicateGroup, error) {
+	return s.inner.FindDuplicates(ctx)
+}
+
+func (s *UndoStore) NeedsReorder(ctx context.Context) ([]domain.Product, error) {
+	return s.inner.NeedsReorder(ctx)
+}
+
+func (s *UndoStore) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	return s.inner.Watch(ctx)
+}
+
+func (s *UndoStore) Flush(ctx context.Context) error {
+	return domain.FlushStore(ctx, s.inner)
+}
+
+func (s *UndoStore) Close(ctx context.Context) error {
+	return domain.CloseStore(ctx, s.inner)
+}
+
+// Undo reverts the remembered operation and describes what it did. A
+// created product is deleted, an updated one gets its previous fields back
+// and a deleted one is restored (soft delete) or created again.
+func (s *UndoStore) Undo(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.last
+	if e == nil {
+		return "", ErrNothingToUndo
+	}
+
+	var err error
+	switch e.op {
+	case domain.ChangeCreated:
+		err = s.inner.Delete(ctx, e.id)
+	case domain.ChangeUpdated:
+		err = s.inner.Update(ctx, e.id, e.before)
+	case domain.ChangeDeleted:
+		err = s.inner.Restore(ctx, e.id)
+		if domain.IsProductNotFoundError(err) {
+			err = s.inner.Create(ctx, e.before)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("undo %s of %s: %w", opVerb(e.op), e.id, err)
+	}
+	s.last = nil
+	return fmt.Sprintf("undid %s of %s", opVerb(e.op), e.id), nil
+}
+
+// opVerb names op the way Undo reports it
+func opVerb(op domain.ChangeOp) string {
+	switch op {
+	case domain.ChangeCreated:
+		return "create"
+	case domain.ChangeUpdated:
+		return "update"
+	default:
+		return "delete"
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUndoStore_RevertsLastOperation(t *testing.T) {
+	ctx := context.Background()
+	s := NewUndoStore(NewInMemoryStore())
+
+	if _, err := s.Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	_ = s.Create(ctx, domain.Product{ID: "u1", Name: "A", Price: 1, Quantity: 1})
+	if _, err := s.Undo(ctx); err != nil {
+		t.Fatalf("undo create failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "u1"); !domain.IsProductNotFoundError(err) {
+		t.Fatalf("expected undone create to remove the product, got %v", err)
+	}
+
+	_ = s.Create(ctx, domain.Product{ID: "u2", Name: "B", Price: 1, Quantity: 5})
+	_ = s.Update(ctx, "u2", domain.Product{Name: "B", Price: 1, Quantity: 0})
+	msg, err := s.Undo(ctx)
+	if err != nil || msg != "undid update of u2" {
+		t.Fatalf("unexpected undo result %q, %v", msg, err)
+	}
+	if p, _ := s.Get(ctx, "u2"); p.Quantity != 5 {
+		t.Fatalf("expected quantity 5 after undo, got %d", p.Quantity)
+	}
+
+	_ = s.Delete(ctx, "u2")
+	if _, err := s.Undo(ctx); err != nil {
+		t.Fatalf("undo delete failed: %v", err)
+	}
+	if p, err := s.Get(ctx, "u2"); err != nil || p.Name != "B" {
+		t.Fatalf("expected deleted product back, got %+v, %v", p, err)
+	}
+
+	if _, err := s.Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected only one step of undo, got %v", err)
+	}
+}
+
+func TestUndoStore_SoftDeleteAndBulkOps(t *testing.T) {
+	ctx := context.Background()
+	s := NewUndoStore(NewInMemoryStoreWithOptions(Options{SoftDelete: true}))
+	_ = s.Create(ctx, domain.Product{ID: "u1", Name: "A", Price: 1, Quantity: 1})
+
+	_ = s.Delete(ctx, "u1")
+	if _, err := s.Undo(ctx); err != nil {
+		t.Fatalf("undo soft delete failed: %v", err)
+	}
+	if _, err := s.Get(ctx, "u1"); err != nil {
+		t.Fatalf("expected soft-deleted product restored, got %v", err)
+	}
+
+	_ = s.Update(ctx, "u1", domain.Product{Name: "A2", Price: 1, Quantity: 1})
+	_ = s.BatchDelete(ctx, []string{"u1"})
+	if _, err := s.Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected a bulk mutation to clear undo, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"math"
+)
+
+// validateNew checks a product about to be inserted: a non-empty id plus
+// the shared domain rules. Every backend's Create and BulkImport use it so
+// they accept and reject exactly the same products.
+func validateNew(p domain.Product) error {
+	if p.ID == "" {
+		return domain.NewInvalidProductError("id", "cannot be empty", p.ID)
+	}
+	return domain.ValidateProduct(p)
+}
+
+// checkRename validates the arguments of RenameCategory. An empty from is
+// rejected because a filter on "" would match every product.
+func checkRename(from, to string) error {
+	if from == "" {
+		return domain.NewInvalidProductError("category", "category to rename cannot be empty", from)
+	}
+	return domain.ValidateCategory(to)
+}
+
+// checkPercent validates the argument of AdjustPriceWhere. Below -100%
+// every non-zero price would turn negative.
+func checkPercent(pct float64) error {
+	if math.IsNaN(pct) || math.IsInf(pct, 0) {
+		return domain.NewInvalidProductError("price", "price adjustment must be a finite percentage", pct)
+	}
+	if pct < -100 {
+		return domain.NewInvalidProductError("price", "price adjustment below -100% would make prices negative", pct)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"aexp_assesment/domain"
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// watchBuffer is the per-subscriber channel capacity. Events are dropped for
+// subscribers that fall further behind than this rather than blocking writers.
+const watchBuffer = 64
+
+// watchers fans change events out to Watch subscribers
+type watchers struct {
+	mu   sync.Mutex
+	subs map[chan domain.ChangeEvent]struct{}
+}
+
+// subscribe registers a new subscriber whose channel is closed once ctx is done
+func (w *watchers) subscribe(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ch := make(chan domain.ChangeEvent, watchBuffer)
+
+	w.mu.Lock()
+	if w.subs == nil {
+		w.subs = make(map[chan domain.ChangeEvent]struct{})
+	}
+	w.subs[ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		delete(w.subs, ch)
+		close(ch)
+		w.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+// publish delivers ev to every subscriber without blocking
+func (w *watchers) publish(ev domain.ChangeEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// publishDeleted sends a ChangeDeleted event with a copy of each removed
+// product, in id order, for mutations that remove many products at once
+func (w *watchers) publishDeleted(removed []domain.Product) {
+	slices.SortFunc(removed, func(a, b domain.Product) int { return cmp.Compare(a.ID, b.ID) })
+	for _, p := range removed {
+		w.publish(domain.ChangeEvent{Op: domain.ChangeDeleted, ID: p.ID, Product: p.Clone()})
+	}
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic starts every gzip stream; no JSON document can start with it
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsGzip reports whether b looks like gzip-compressed data
+func IsGzip(b []byte) bool {
+	return bytes.HasPrefix(b, gzipMagic)
+}
+
+// Gzip compresses b. The header carries no name or time, so equal input
+// gives equal output.
+func Gzip(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MaybeGunzip decompresses b if it is gzip data and returns it unchanged
+// otherwise
+func MaybeGunzip(b []byte) ([]byte, error) {
+	if !IsGzip(b) {
+		return b, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// MaybeGunzipReader returns a reader of r's content, decompressed as it is
+// read if it is gzip data. Closing it does not close r.
+func MaybeGunzipReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(gzipMagic)); !IsGzip(head) {
+		return io.NopCloser(br), nil
+	}
+	return gzip.NewReader(br)
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGzip_RoundTrip(t *testing.T) {
+	in := []byte(`[{"id":"p1","name":"Pen"}]`)
+	z, err := Gzip(in)
+	if err != nil {
+		t.Fatalf("gzip failed: %v", err)
+	}
+	if !IsGzip(z) || IsGzip(in) {
+		t.Fatal("IsGzip misdetected its input")
+	}
+	again, _ := Gzip(in)
+	if !bytes.Equal(z, again) {
+		t.Fatal("expected gzip output to be deterministic")
+	}
+	out, err := MaybeGunzip(z)
+	if err != nil || !bytes.Equal(out, in) {
+		t.Fatalf("round trip gave %q (%v)", out, err)
+	}
+	if out, err := MaybeGunzip(in); err != nil || !bytes.Equal(out, in) {
+		t.Fatalf("expected plain input unchanged, got %q (%v)", out, err)
+	}
+	if _, err := MaybeGunzip(z[:len(z)-4]); err == nil {
+		t.Fatal("expected truncated gzip to fail")
+	}
+}
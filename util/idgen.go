@@ -0,0 +1,118 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IDGenerator produces new product ids
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// UUIDv4 generates random v4 UUIDs
+type UUIDv4 struct{}
+
+func (UUIDv4) NewID() (string, error) { return GenerateUUIDErr() }
+
+// UUIDv7 generates time-ordered v7 UUIDs
+type UUIDv7 struct{}
+
+func (UUIDv7) NewID() (string, error) { return GenerateUUIDv7Err() }
+
+// Sequence generates readable ids made of Prefix and a counter zero-padded
+// to Width digits, e.g. P-0001. Its counter is a high-water mark: Save keeps
+// it in a file and LoadSequence continues after it, so a number stays used
+// after its product is gone. Observe raises it past ids stored elsewhere.
+type Sequence struct {
+	Prefix string
+	Width  int
+
+	mu   sync.Mutex
+	last int
+}
+
+// DefaultSequencePrefix and DefaultSequenceWidth give ids like P-0001
+const (
+	DefaultSequencePrefix = "P-"
+	DefaultSequenceWidth  = 4
+)
+
+// NewSequence returns a Sequence starting at 1
+func NewSequence(prefix string, width int) *Sequence {
+	return &Sequence{Prefix: prefix, Width: width}
+}
+
+// Observe raises the counter past id when id has the sequence's prefix
+// followed by a number; other ids are ignored.
+func (s *Sequence) Observe(id string) {
+	rest, ok := strings.CutPrefix(id, s.Prefix)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = max(s.last, n)
+}
+
+func (s *Sequence) NewID() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last++
+	return fmt.Sprintf("%s%0*d", s.Prefix, s.Width, s.last), nil
+}
+
+// LoadSequence returns a Sequence continuing after the counter saved at path
+// by Save. ok is false, with a Sequence starting at 1, when nothing has been
+// saved there yet.
+func LoadSequence(path, prefix string, width int) (s *Sequence, ok bool, err error) {
+	s = NewSequence(prefix, width)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || n < 0 {
+		return nil, false, fmt.Errorf("%s: invalid sequence counter %q", path, strings.TrimSpace(string(b)))
+	}
+	s.last = n
+	return s, true, nil
+}
+
+// Save writes the counter to path, replacing the file through a rename so a
+// crash leaves either the old counter or the new one
+func (s *Sequence) Save(path string, perm os.FileMode) error {
+	s.mu.Lock()
+	last := s.last
+	s.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(last)+"\n"), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewIDGenerator returns the generator for strategy: v4, v7 or sequence
+func NewIDGenerator(strategy string) (IDGenerator, error) {
+	switch strategy {
+	case "v4":
+		return UUIDv4{}, nil
+	case "v7":
+		return UUIDv7{}, nil
+	case "sequence":
+		return NewSequence(DefaultSequencePrefix, DefaultSequenceWidth), nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy %q: want v4, v7 or sequence", strategy)
+	}
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSequence_ContinuesAfterObservedIDs(t *testing.T) {
+	s := NewSequence("P-", 4)
+	for _, id := range []string{"P-0007", "P-0002", "P-x", "Q-0099", "5b0e"} {
+		s.Observe(id)
+	}
+	for _, want := range []string{"P-0008", "P-0009"} {
+		if got, _ := s.NewID(); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	for _, strategy := range []string{"v4", "v7", "sequence"} {
+		g, err := NewIDGenerator(strategy)
+		if err != nil {
+			t.Fatalf("%s: %v", strategy, err)
+		}
+		if id, err := g.NewID(); err != nil || id == "" {
+			t.Fatalf("%s: expected an id, got %q, %v", strategy, id, err)
+		}
+	}
+	if _, err := NewIDGenerator("snowflake"); err == nil {
+		t.Fatalf("expected an unknown strategy to be rejected")
+	}
+}
+
+func TestSequence_SaveAndLoad(t *testing.T) {
+	path := t.TempDir() + "/ids.seq"
+	s, ok, err := LoadSequence(path, "P-", 4)
+	if err != nil || ok {
+		t.Fatalf("expected no saved counter yet, got ok=%v, %v", ok, err)
+	}
+	s.Observe("P-0041")
+	_, _ = s.NewID()
+	if err := s.Save(path, 0o644); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	s, ok, err = LoadSequence(path, "P-", 4)
+	if err != nil || !ok {
+		t.Fatalf("expected the saved counter, got ok=%v, %v", ok, err)
+	}
+	if got, _ := s.NewID(); got != "P-0043" {
+		t.Fatalf("expected P-0043 after the saved P-0042, got %s", got)
+	}
+	_ = os.WriteFile(path, []byte("many"), 0o644)
+	if _, _, err := LoadSequence(path, "P-", 4); err == nil {
+		t.Fatalf("expected an unreadable counter to be rejected")
+	}
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces operations evenly at a fixed rate, without bursts. It covers
+// the part of golang.org/x/time/rate's Limiter this module needs. A nil
+// *Limiter allows every operation at once.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a Limiter allowing perSecond operations a second, or
+// nil when perSecond is not positive
+func NewLimiter(perSecond int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the next operation may start or ctx is done, in which
+// case it returns ctx's error. The first call never waits.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil || l == nil {
+		return err
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
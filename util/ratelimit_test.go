@@ -0,0 +1,40 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_SpacesCalls(t *testing.T) {
+	l := NewLimiter(50)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first call is free, the other five wait 20ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("6 calls at 50/s took %v, want about 100ms", elapsed)
+	}
+}
+
+func TestLimiter_NilAndCancelled(t *testing.T) {
+	if NewLimiter(0) != nil {
+		t.Fatal("expected no limiter for a zero rate")
+	}
+	var l *Limiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter should not block: %v", err)
+	}
+
+	l = NewLimiter(1)
+	_ = l.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to stop the wait, got %v", err)
+	}
+}
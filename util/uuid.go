@@ -3,19 +3,82 @@ package util
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"sync"
+	"time"
 )
 
-// GenerateUUID returns a RFC4122-compliant v4 UUID string.
+// randReader is the entropy source; tests replace it to simulate failure
+var randReader io.Reader = rand.Reader
+
+// GenerateUUID returns a RFC4122-compliant v4 UUID string, or "" if the
+// system random source fails. Use GenerateUUIDErr to see why.
 func GenerateUUID() string {
+	id, _ := GenerateUUIDErr()
+	return id
+}
+
+// GenerateUUIDErr returns a RFC4122-compliant v4 UUID string, or the error
+// from the system random source.
+func GenerateUUIDErr() (string, error) {
 	b := make([]byte, 16)
-	_, err := rand.Read(b)
-	if err != nil {
-		return ""
+	if _, err := io.ReadFull(randReader, b); err != nil {
+		return "", fmt.Errorf("generate uuid: %w", err)
 	}
 	// Set version (4) and variant bits per RFC
 	b[6] = (b[6] & 0x0f) | 0x40
 	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b), nil
+}
+
+// v7 state: the last timestamp handed out and the counter within it
+var (
+	v7mu  sync.Mutex
+	v7ms  uint64
+	v7seq uint16
+)
+
+// GenerateUUIDv7 returns an RFC 9562 v7 UUID string, or "" if the system
+// random source fails. Use GenerateUUIDv7Err to see why.
+func GenerateUUIDv7() string {
+	id, _ := GenerateUUIDv7Err()
+	return id
+}
+
+// GenerateUUIDv7Err returns an RFC 9562 v7 UUID string: a 48-bit Unix
+// millisecond timestamp followed by random bits, so ids sort by creation
+// time. Within one millisecond the 12-bit rand_a field is a counter, making
+// successive ids from this process strictly increasing.
+func GenerateUUIDv7Err() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(randReader, b); err != nil {
+		return "", fmt.Errorf("generate uuid: %w", err)
+	}
+
+	v7mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms > v7ms {
+		// start each millisecond at a random counter with room to grow
+		v7ms, v7seq = ms, binary.BigEndian.Uint16(b[6:8])&0x7ff
+	} else if v7seq++; v7seq > 0xfff {
+		// counter exhausted, or the clock went back: borrow the next millisecond
+		v7ms, v7seq = v7ms+1, 0
+	}
+	ms, seq := v7ms, v7seq
+	v7mu.Unlock()
+
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = 0x70 | byte(seq>>8)
+	b[7] = byte(seq)
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b), nil
+}
+
+// formatUUID renders 16 bytes in the canonical 8-4-4-4-12 form
+func formatUUID(b []byte) string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
 		uint32(b[0])<<24|uint32(b[1])<<16|uint32(b[2])<<8|uint32(b[3]),
 		uint16(b[4])<<8|uint16(b[5]),
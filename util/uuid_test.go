@@ -1,7 +1,9 @@
 package util
 
 import (
+	"errors"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -16,3 +18,38 @@ func TestGenerateUUID_Format(t *testing.T) {
 		t.Fatalf("UUID %s does not match v4 format", u)
 	}
 }
+
+func TestGenerateUUIDv7_FormatAndOrder(t *testing.T) {
+	r := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	prev := GenerateUUIDv7()
+	for i := 0; i < 10000; i++ {
+		u := GenerateUUIDv7()
+		if !r.MatchString(u) {
+			t.Fatalf("UUID %s does not match v7 format", u)
+		}
+		if u <= prev {
+			t.Fatalf("expected %s to sort after %s", u, prev)
+		}
+		prev = u
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("no entropy") }
+
+func TestGenerateUUIDErr_EntropyFailure(t *testing.T) {
+	old := randReader
+	randReader = failingReader{}
+	defer func() { randReader = old }()
+
+	if _, err := GenerateUUIDErr(); err == nil || !strings.Contains(err.Error(), "no entropy") {
+		t.Fatalf("expected the entropy error, got %v", err)
+	}
+	if _, err := GenerateUUIDv7Err(); err == nil {
+		t.Fatalf("expected the entropy error from v7")
+	}
+	if id := GenerateUUID(); id != "" {
+		t.Fatalf("expected empty id on failure, got %q", id)
+	}
+}